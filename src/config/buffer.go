@@ -1,18 +1,24 @@
 package config
 
 import (
+	"bytes"
+	"time"
+
 	"github.com/deanrtaylor1/go-editor/constants"
+	"github.com/deanrtaylor1/go-editor/piecetable"
+	"github.com/deanrtaylor1/go-editor/utils"
 )
 
 type Buffer struct {
 	Idx                int
 	Name               string
-	Rows               []Row
+	Text               TextStore
+	Rows               RowList
 	NumRows            int
 	SearchState        *SearchState
 	BufferSyntax       *BufferSyntax
-	UndoStack          []EditorAction
-	RedoStack          []EditorAction
+	Options            *BufferOptions
+	UndoTree           *UndoTree
 	NeedsFullHighlight bool
 	SliceIndex         int
 	Dirty              int
@@ -22,6 +28,36 @@ type Buffer struct {
 	StoredOffsetX      int
 	SelectionStart     Point
 	SelectionEnd       Point
+	SelectionLinewise  bool
+	SwapPath           string
+	SwapDirty          int
+	SwapWrittenAt      time.Time
+	DiskStamp          FileStamp
+	LastSeenStamp      FileStamp
+	// UndoGroup collects undo steps into one while it isn't nil
+	UndoGroup *EditorAction
+	// SavedSeq is the undo state the text on disk is in, or -1 when no state
+	// holds it
+	SavedSeq int
+	// Marks are the places set with m, along with '< and '> for the last
+	// selection a command line was opened on
+	Marks map[rune]Point
+}
+
+// FileStamp identifies a version of a file on disk. The zero value stands for
+// a file that does not exist.
+type FileStamp struct {
+	ModTime time.Time
+	Size    int64
+	Inode   uint64
+}
+
+func (s FileStamp) Equal(other FileStamp) bool {
+	return s.ModTime.Equal(other.ModTime) && s.Size == other.Size && s.Inode == other.Inode
+}
+
+func (s FileStamp) IsZero() bool {
+	return s.Equal(FileStamp{})
 }
 
 type BufferSyntax struct {
@@ -34,104 +70,260 @@ type BufferSyntax struct {
 	Syntaxes               []constants.SyntaxHighlighting
 }
 
-func (b *Buffer) ReplaceRowAtIndex(index int, newRow Row) {
-	if index < 0 || index >= len(b.Rows) {
-		return
+// TextStore is the storage behind a Buffer. Every row of the buffer is kept
+// newline terminated inside the store, so the store holds exactly what gets
+// written to disk.
+type TextStore interface {
+	Insert(offset int, text []byte)
+	Delete(offset, length int)
+	Line(n int) []byte
+	LineCount() int
+	Len() int
+	Bytes() []byte
+	Slice(start, end int) []byte
+	PointToOffset(line, col int) int
+	OffsetToPoint(offset int) (int, int)
+}
+
+// SetText replaces the whole contents of the buffer and rebuilds the rows.
+func (b *Buffer) SetText(content []byte) {
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		content = append(content, '\n')
 	}
+	b.Text = piecetable.New(content)
+	rows := make([]Row, 0, b.Text.LineCount())
+	for len(content) > 0 {
+		end := bytes.IndexByte(content, '\n')
+		rows = append(rows, *newRowFromChars(content[:end]))
+		content = content[end+1:]
+	}
+	b.Rows = NewRowList(rows)
+	b.NumRows = b.Rows.Len()
 
-	b.Rows[index] = newRow
+	// Undo states from before don't fit the new text
+	b.UndoTree = NewUndoTree()
+	b.UndoGroup = nil
+	b.SavedSeq = 0
 }
 
-func (b *Buffer) RemoveRowsFromIndex(index int, count int) {
-	if index < 0 || index >= len(b.Rows) {
-		return
-	}
+// Row returns row n, which stays valid until rows are added or removed.
+func (b *Buffer) Row(n int) *Row {
+	return b.Rows.At(n)
+}
 
-	if index+count > len(b.Rows) {
-		count = len(b.Rows) - index
-	}
+func (b *Buffer) Line(n int) []byte {
+	return b.Text.Line(n)
+}
 
-	beforeRows := b.Rows[:index]
-	afterRows := b.Rows[index+count:]
+func (b *Buffer) LineCount() int {
+	return b.Text.LineCount()
+}
 
-	b.Rows = append(beforeRows, afterRows...)
-	b.NumRows -= count
+func (b *Buffer) PointToOffset(p Point) int {
+	return b.Text.PointToOffset(p.Row, p.Col)
 }
 
-func (b *Buffer) RemoveRowAtIndex(index int) {
-	if index < 0 || index >= len(b.Rows) {
-		return
-	}
+func (b *Buffer) OffsetToPoint(offset int) Point {
+	row, col := b.Text.OffsetToPoint(offset)
+	return Point{Row: row, Col: col}
+}
 
-	beforeRows := b.Rows[:index]
+// TrimTrailingWhitespace removes spaces and tabs from the end of every row and
+// reports whether anything changed.
+func (b *Buffer) TrimTrailingWhitespace() bool {
+	changed := false
+	for i := 0; i < b.NumRows; i++ {
+		chars := b.Row(i).Chars
+		trimmed := len(bytes.TrimRight(chars, " \t"))
+		if trimmed < len(chars) {
+			b.DeleteText(Point{Row: i, Col: trimmed}, Point{Row: i, Col: len(chars)})
+			changed = true
+		}
+	}
+	return changed
+}
 
-	afterRows := b.Rows[index+1:]
+// InsertText inserts text at p and returns the point just after it. Inserting
+// on the line past the end of the buffer creates that line.
+func (b *Buffer) InsertText(p Point, text []byte) Point {
+	if len(text) == 0 {
+		return p
+	}
+	if p.Row >= b.LineCount() {
+		p = Point{Row: b.LineCount(), Col: 0}
+		if text[len(text)-1] != '\n' {
+			text = append(text[:len(text):len(text)], '\n')
+		}
+	}
+	return b.ReplaceText(p, p, text)
+}
 
-	b.Rows = append(beforeRows, afterRows...)
-	b.NumRows--
+// DeleteText removes the text between start and end (exclusive) and returns
+// it.
+func (b *Buffer) DeleteText(start, end Point) []byte {
+	startOffset := b.PointToOffset(start)
+	endOffset := b.PointToOffset(end)
+	if endOffset <= startOffset {
+		return []byte{}
+	}
+	removed := b.Text.Slice(startOffset, endOffset)
+	b.ReplaceText(start, end, nil)
+	return removed
 }
 
-func (b *Buffer) InsertRowAtIndex(index int, newRow Row) {
-	beforeRows := b.Rows[:index]
+// ReplaceText puts text in place of the text between start and end and
+// returns the point just after it. Every change to the text of the buffer goes
+// through here and is recorded for undo as what was removed and inserted.
+func (b *Buffer) ReplaceText(start, end Point, text []byte) Point {
+	startOffset := b.PointToOffset(start)
+	endOffset := utils.Max(b.PointToOffset(end), startOffset)
+	edit := Edit{
+		Offset:   startOffset,
+		Removed:  b.Text.Slice(startOffset, endOffset),
+		Inserted: append([]byte{}, text...),
+	}
 
-	newRows := []Row{newRow}
+	// Removing the final newline would leave the last row unterminated
+	if endOffset == b.Text.Len() {
+		last := edit.Inserted
+		if len(last) == 0 && startOffset > 0 {
+			last = b.Text.Slice(startOffset-1, startOffset)
+		}
+		if len(last) > 0 && last[len(last)-1] != '\n' {
+			edit.Inserted = append(edit.Inserted, '\n')
+		}
+	}
+	if len(edit.Removed) == 0 && len(edit.Inserted) == 0 {
+		return start
+	}
+
+	b.applyEdit(edit.Offset, edit.Removed, edit.Inserted)
+	b.BeginUndoGroup()
+	b.UndoGroup.Edits = append(b.UndoGroup.Edits, edit)
+	return b.OffsetToPoint(edit.Offset + len(edit.Inserted))
+}
 
-	afterRows := b.Rows[index:]
+// applyEdit swaps removed at offset for inserted and brings the rows up to
+// date, without recording anything.
+func (b *Buffer) applyEdit(offset int, removed, inserted []byte) {
+	from := b.OffsetToPoint(offset)
+	to := b.OffsetToPoint(offset + len(removed))
+	firstRow, lastRow := from.Row, to.Row
+	if len(removed) > 0 {
+		b.Text.Delete(offset, len(removed))
+	}
+	if len(inserted) > 0 {
+		b.Text.Insert(offset, inserted)
+	}
 
-	b.Rows = append(beforeRows, append(newRows, afterRows...)...)
+	// The rows from the first to the last one touched are replaced, while the
+	// first one keeps its state when it's still there
+	oldRows := utils.Min(lastRow, b.NumRows-1) - firstRow + 1
+	newRows := utils.Min(firstRow+bytes.Count(inserted, []byte{'\n'}), b.LineCount()-1) - firstRow + 1
+	oldRows, newRows = utils.Max(oldRows, 0), utils.Max(newRows, 0)
+	if oldRows > 0 && newRows > 0 {
+		b.spliceRows(firstRow+1, oldRows-1, newRows-1)
+	} else {
+		b.spliceRows(firstRow, oldRows, newRows)
+	}
+	b.reloadRows(firstRow, firstRow+newRows-1)
+	b.shiftMarks(from, to, b.OffsetToPoint(offset+len(inserted)))
 }
 
-func (b *Buffer) PopUndo() (EditorAction, bool) {
-	if len(b.UndoStack) == 0 {
-		return EditorAction{}, false
+// shiftMarks keeps the marks on the text they were set on after the text
+// from from to to was replaced by text ending at newEnd. Marks inside the
+// replaced text go to its start.
+func (b *Buffer) shiftMarks(from, to, newEnd Point) {
+	for name, p := range b.Marks {
+		switch {
+		case p.Row > to.Row:
+			p.Row += newEnd.Row - to.Row
+		case p.Row == to.Row && p.Col >= to.Col:
+			p = Point{Row: newEnd.Row, Col: newEnd.Col + p.Col - to.Col}
+		case p.Row > from.Row || (p.Row == from.Row && p.Col > from.Col):
+			p = from
+		default:
+			continue
+		}
+		b.Marks[name] = p
 	}
-	lastAction := b.UndoStack[len(b.UndoStack)-1]
-	b.UndoStack = b.UndoStack[:len(b.UndoStack)-1]
-	return lastAction, true
 }
 
-func (b *Buffer) PopRedo() (EditorAction, bool) {
-	if len(b.RedoStack) == 0 {
-		return EditorAction{}, false
+// spliceRows removes and then inserts empty rows at index at.
+func (b *Buffer) spliceRows(at int, remove int, insert int) {
+	b.Rows.Splice(at, remove, insert)
+	b.NumRows = b.Rows.Len()
+}
+
+// reloadRows refreshes the cached characters of rows from..to from the text
+// store, keeping each row's indentation state. Highlighting is reset and has
+// to be recomputed by the caller.
+func (b *Buffer) reloadRows(from, to int) {
+	for i := utils.Max(from, 0); i <= to && i < b.NumRows; i++ {
+		row := b.Row(i)
+		row.Chars = b.Text.Line(i)
+		row.Length = len(row.Chars)
+		row.Highlighting = make([]byte, row.Length)
+		row.Tabs = make([]byte, row.Length)
 	}
-	lastAction := b.RedoStack[len(b.RedoStack)-1]
-	b.RedoStack = b.RedoStack[:len(b.RedoStack)-1]
-	return lastAction, true
 }
 
-func (b *Buffer) AppendRedo(action EditorAction, maxUndoHistory int) {
-	if len(b.RedoStack) >= maxUndoHistory {
-		b.RedoStack = b.RedoStack[1:]
+func (b *Buffer) ReplaceRowAtIndex(index int, newRow Row) {
+	if index < 0 || index >= b.NumRows {
+		return
 	}
-	b.RedoStack = append(b.RedoStack, action)
+
+	b.DeleteText(Point{Row: index, Col: 0}, Point{Row: index, Col: len(b.Row(index).Chars)})
+	b.InsertText(Point{Row: index, Col: 0}, newRow.Chars)
+	newRow.Chars = b.Row(index).Chars
+	newRow.Length = len(newRow.Chars)
+	*b.Row(index) = newRow
 }
 
-func (b *Buffer) AppendUndo(action EditorAction, maxUndoHistory int) {
-	if len(b.UndoStack) >= maxUndoHistory {
-		b.UndoStack = b.UndoStack[1:]
+func (b *Buffer) RemoveRowsFromIndex(index int, count int) {
+	if index < 0 || index >= b.NumRows || count <= 0 {
+		return
 	}
-	b.UndoStack = append(b.UndoStack, action)
+
+	if index+count > b.NumRows {
+		count = b.NumRows - index
+	}
+
+	b.DeleteText(Point{Row: index, Col: 0}, Point{Row: index + count, Col: 0})
 }
 
-func (b *Buffer) NewEditorAction(row Row, rowIndex int, actionType int, prevRowLength int, cx int, prevRow interface{}, redoFunction func()) *EditorAction {
-	return &EditorAction{
-		Row:          row,
-		Index:        rowIndex,
-		ActionType:   actionType,
-		PrevRow:      prevRow,
-		Cx:           cx,
-		RedoFunction: redoFunction,
+func (b *Buffer) RemoveRowAtIndex(index int) {
+	b.RemoveRowsFromIndex(index, 1)
+}
+
+func (b *Buffer) InsertRowAtIndex(index int, newRow Row) {
+	if index < 0 || index > b.NumRows {
+		index = b.NumRows
+	}
+
+	text := append(append([]byte{}, newRow.Chars...), '\n')
+	b.InsertText(Point{Row: index, Col: 0}, text)
+	newRow.Chars = b.Row(index).Chars
+	newRow.Length = len(newRow.Chars)
+	if len(newRow.Highlighting) != newRow.Length {
+		newRow.Highlighting = make([]byte, newRow.Length)
+	}
+	if len(newRow.Tabs) != newRow.Length {
+		newRow.Tabs = make([]byte, newRow.Length)
 	}
+	*b.Row(index) = newRow
 }
 
 func NewBuffer() *Buffer {
 	return &Buffer{
-		Rows:               []Row{},
+		Text:               piecetable.New(nil),
+		Rows:               NewRowList(nil),
 		NumRows:            0,
 		SearchState:        NewSearchState(),
 		BufferSyntax:       NewBufferSyntax(),
-		UndoStack:          []EditorAction{},
-		RedoStack:          []EditorAction{},
+		Options:            NewBufferOptions(),
+		UndoTree:           NewUndoTree(),
+		Marks:              map[rune]Point{},
 		NeedsFullHighlight: false,
 		SliceIndex:         0,
 		Dirty:              0,
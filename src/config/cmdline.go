@@ -0,0 +1,156 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/deanrtaylor1/go-editor/utils"
+)
+
+// commandHistorySize is how many command lines are remembered.
+const commandHistorySize = 100
+
+// CommandLine is the line typed after : along with the lines run before it.
+type CommandLine struct {
+	Input []byte
+	// Cursor is a byte index into Input
+	Cursor int
+	// History holds the lines run so far, oldest first. HistoryIndex is the
+	// one shown while going through them, len(History) for the line being
+	// typed, which is kept in typed meanwhile.
+	History      []string
+	HistoryIndex int
+	typed        string
+	// Completions are what Tab cycles through in place of the text from
+	// CompletionStart to the cursor, the text that was typed coming last
+	Completions     []string
+	CompletionIndex int
+	CompletionStart int
+}
+
+// Start opens a fresh line holding text, with the cursor at its end.
+func (c *CommandLine) Start(text string) {
+	c.Input = []byte(text)
+	c.Cursor = len(c.Input)
+	c.HistoryIndex = len(c.History)
+	c.typed = ""
+	c.ResetCompletion()
+}
+
+func (c *CommandLine) String() string {
+	return string(c.Input)
+}
+
+// Insert puts text in at the cursor and moves the cursor past it.
+func (c *CommandLine) Insert(text string) {
+	c.Input = append(c.Input[:c.Cursor], append([]byte(text), c.Input[c.Cursor:]...)...)
+	c.Cursor += len(text)
+}
+
+// DeleteBack removes the character before the cursor.
+func (c *CommandLine) DeleteBack() {
+	start := utils.PrevGrapheme(c.Input, c.Cursor)
+	c.Input = append(c.Input[:start], c.Input[c.Cursor:]...)
+	c.Cursor = start
+}
+
+// DeleteForward removes the character under the cursor.
+func (c *CommandLine) DeleteForward() {
+	if c.Cursor >= len(c.Input) {
+		return
+	}
+	end := utils.NextGrapheme(c.Input, c.Cursor)
+	c.Input = append(c.Input[:c.Cursor], c.Input[end:]...)
+}
+
+// DeleteWord removes the word before the cursor and the spaces after it.
+func (c *CommandLine) DeleteWord() {
+	start := c.Cursor
+	for start > 0 && c.Input[start-1] == ' ' {
+		start--
+	}
+	for start > 0 && c.Input[start-1] != ' ' {
+		start--
+	}
+	c.Input = append(c.Input[:start], c.Input[c.Cursor:]...)
+	c.Cursor = start
+}
+
+// DeleteToStart removes everything before the cursor.
+func (c *CommandLine) DeleteToStart() {
+	c.Input = append([]byte{}, c.Input[c.Cursor:]...)
+	c.Cursor = 0
+}
+
+func (c *CommandLine) MoveLeft() {
+	c.Cursor = utils.PrevGrapheme(c.Input, c.Cursor)
+}
+
+func (c *CommandLine) MoveRight() {
+	if c.Cursor < len(c.Input) {
+		c.Cursor = utils.NextGrapheme(c.Input, c.Cursor)
+	}
+}
+
+// HistoryStep shows the line step places back in the history, or forward
+// for a positive step, skipping those that don't start with what was typed
+// before going through them.
+func (c *CommandLine) HistoryStep(step int) bool {
+	if c.HistoryIndex == len(c.History) {
+		c.typed = c.String()
+	}
+	for i := c.HistoryIndex + step; i >= 0 && i <= len(c.History); i += step {
+		line := c.typed
+		if i < len(c.History) {
+			line = c.History[i]
+			if !strings.HasPrefix(line, c.typed) {
+				continue
+			}
+		}
+		c.HistoryIndex = i
+		c.Input = []byte(line)
+		c.Cursor = len(c.Input)
+		return true
+	}
+	return false
+}
+
+// Remember adds line to the end of the history, dropping an earlier copy of
+// it and the oldest lines beyond commandHistorySize.
+func (c *CommandLine) Remember(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	for i, old := range c.History {
+		if old == line {
+			c.History = append(c.History[:i], c.History[i+1:]...)
+			break
+		}
+	}
+	c.History = append(c.History, line)
+	if len(c.History) > commandHistorySize {
+		c.History = c.History[len(c.History)-commandHistorySize:]
+	}
+	c.HistoryIndex = len(c.History)
+}
+
+// Complete puts the next of completions in place of the text from start to
+// the cursor, or the previous one for a negative step. The completions are
+// kept until ResetCompletion, so pressing Tab again goes on through them.
+func (c *CommandLine) Complete(start int, completions []string, step int) {
+	if c.Completions == nil {
+		c.CompletionStart = start
+		c.Completions = append(completions, string(c.Input[start:c.Cursor]))
+		c.CompletionIndex = len(c.Completions) - 1
+	}
+	n := len(c.Completions)
+	c.CompletionIndex = ((c.CompletionIndex+step)%n + n) % n
+	c.Input = append(c.Input[:c.CompletionStart], c.Input[c.Cursor:]...)
+	c.Cursor = c.CompletionStart
+	c.Insert(c.Completions[c.CompletionIndex])
+}
+
+func (c *CommandLine) ResetCompletion() {
+	c.Completions = nil
+	c.CompletionIndex = 0
+	c.CompletionStart = 0
+}
@@ -2,13 +2,13 @@ package config
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"time"
 
 	"golang.org/x/term"
 
 	"github.com/deanrtaylor1/go-editor/constants"
+	"github.com/deanrtaylor1/go-editor/utils"
 )
 
 const logging = true
@@ -19,9 +19,10 @@ func LogToFile(message string) {
 	}
 
 	// Open the log file in append mode, or create it if it doesn't exist
+	// Logging is best effort, a failure here must never take the editor down
 	file, err := os.OpenFile("logs.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		log.Fatal(err)
+		return
 	}
 	defer file.Close()
 
@@ -29,18 +30,21 @@ func LogToFile(message string) {
 	logEntry := fmt.Sprintf("%s: %s\n", time.Now().Format(time.RFC3339), message)
 
 	// Write the log entry to the file
-	if _, err := file.WriteString(logEntry); err != nil {
-		log.Fatal(err)
-	}
+	file.WriteString(logEntry)
+}
+
+// Edit is one change to the text of a buffer, Removed taken out at Offset and
+// Inserted put in its place. Undo and redo apply it in opposite directions.
+type Edit struct {
+	Offset   int
+	Removed  []byte
+	Inserted []byte
 }
 
+// EditorAction is one undo step, the edits a single command or insert session
+// made in the order they were made.
 type EditorAction struct {
-	ActionType   int
-	Row          Row
-	Index        int
-	PrevRow      interface{}
-	Cx           int
-	RedoFunction func()
+	Edits []Edit
 }
 
 type FileBrowserActionState struct {
@@ -59,7 +63,6 @@ type SearchState struct {
 type Row struct {
 	CharAdjustment   int
 	IndentationLevel int
-	Idx              int
 	Chars            []byte
 	Length           int
 	Highlighting     []byte
@@ -97,7 +100,6 @@ func NewSearchState() *SearchState {
 
 func NewRow() *Row {
 	return &Row{
-		Idx:              0,
 		CharAdjustment:   0,
 		IndentationLevel: 0,
 		Chars:            []byte{},
@@ -107,10 +109,18 @@ func NewRow() *Row {
 	}
 }
 
+func newRowFromChars(chars []byte) *Row {
+	row := NewRow()
+	row.Chars = append([]byte{}, chars...)
+	row.Length = len(row.Chars)
+	row.Highlighting = make([]byte, row.Length)
+	row.Tabs = make([]byte, row.Length)
+	return row
+}
+
 func (r *Row) DeepCopy() *Row {
 	// Create a new Row object and copy over the simple fields
 	newRow := &Row{
-		Idx:              r.Idx,
 		CharAdjustment:   r.CharAdjustment,
 		IndentationLevel: r.IndentationLevel,
 		Length:           r.Length,
@@ -129,6 +139,34 @@ func (r *Row) DeepCopy() *Row {
 	return newRow
 }
 
+// RenderColumn returns the screen column, relative to the start of the row,
+// at which the character starting at byte idx is drawn. Tabs are expanded to
+// tabStop.
+func (r *Row) RenderColumn(idx int, tabStop int) int {
+	col := 0
+	for i := 0; i < idx && i < len(r.Chars); {
+		next := utils.NextGrapheme(r.Chars, i)
+		col += utils.CellWidth(r.Chars[i:next], col, tabStop)
+		i = next
+	}
+	return col
+}
+
+// ByteIndexForColumn returns the byte index of the character drawn at col,
+// or the row length when col is past the end of the row.
+func (r *Row) ByteIndexForColumn(col int, tabStop int) int {
+	current := 0
+	for i := 0; i < len(r.Chars); {
+		next := utils.NextGrapheme(r.Chars, i)
+		current += utils.CellWidth(r.Chars[i:next], current, tabStop)
+		if current > col {
+			return i
+		}
+		i = next
+	}
+	return len(r.Chars)
+}
+
 func GetWindowSize(cfg *Editor) error {
 	width, height, err := term.GetSize(int(os.Stdin.Fd()))
 	if err != nil {
@@ -0,0 +1,62 @@
+package config
+
+import "testing"
+
+func TestRowColumns(t *testing.T) {
+	tests := []struct {
+		chars   string
+		tabStop int
+		// starts holds the byte index of each character and cols the column
+		// it is drawn at, with the end of the row last
+		starts []int
+		cols   []int
+	}{
+		{"", 4, []int{0}, []int{0}},
+		{"abc", 4, []int{0, 1, 2, 3}, []int{0, 1, 2, 3}},
+		{"\tx", 4, []int{0, 1, 2}, []int{0, 4, 5}},
+		{"\tx", 8, []int{0, 1, 2}, []int{0, 8, 9}},
+		{"\tx", 1, []int{0, 1, 2}, []int{0, 1, 2}},
+		// A tab goes to the next tab stop from wherever it starts
+		{"ab\tc\t\td", 4, []int{0, 1, 2, 3, 4, 5, 6, 7}, []int{0, 1, 2, 4, 5, 8, 12, 13}},
+		{"abcd\tx", 4, []int{0, 1, 2, 3, 4, 5, 6}, []int{0, 1, 2, 3, 4, 8, 9}},
+		{"ab\tc", 2, []int{0, 1, 2, 3, 4}, []int{0, 1, 2, 4, 5}},
+		// Wide characters take two columns and move the tab stops along
+		{"世界", 4, []int{0, 3, 6}, []int{0, 2, 4}},
+		{"\tb世\tx", 4, []int{0, 1, 2, 5, 6, 7}, []int{0, 4, 5, 7, 8, 9}},
+		{"\tb世\tx", 8, []int{0, 1, 2, 5, 6, 7}, []int{0, 8, 9, 11, 16, 17}},
+		{"\tb世\tx", 2, []int{0, 1, 2, 5, 6, 7}, []int{0, 2, 3, 5, 6, 7}},
+		{"世\t界\t", 4, []int{0, 3, 4, 7, 8}, []int{0, 2, 4, 6, 8}},
+		{"é世\t", 3, []int{0, 2, 5, 6}, []int{0, 1, 3, 6}},
+		// Combining marks are part of the character before them
+		{"e\u0301\tx", 4, []int{0, 3, 4, 5}, []int{0, 1, 4, 5}},
+	}
+	for _, tt := range tests {
+		r := newRowFromChars([]byte(tt.chars))
+		last := len(tt.starts) - 1
+		for i, idx := range tt.starts {
+			if got := r.RenderColumn(idx, tt.tabStop); got != tt.cols[i] {
+				t.Errorf("%q with tab stop %d: RenderColumn(%d) = %d, want %d", tt.chars, tt.tabStop, idx, got, tt.cols[i])
+			}
+			if i == last {
+				break
+			}
+			// Every column a character covers finds its start
+			for col := tt.cols[i]; col < tt.cols[i+1]; col++ {
+				if got := r.ByteIndexForColumn(col, tt.tabStop); got != idx {
+					t.Errorf("%q with tab stop %d: ByteIndexForColumn(%d) = %d, want %d", tt.chars, tt.tabStop, col, got, idx)
+				}
+			}
+		}
+
+		// Past the end is the end of the row
+		end := tt.cols[last]
+		for _, col := range []int{end, end + 5} {
+			if got := r.ByteIndexForColumn(col, tt.tabStop); got != len(tt.chars) {
+				t.Errorf("%q with tab stop %d: ByteIndexForColumn(%d) = %d, want %d", tt.chars, tt.tabStop, col, got, len(tt.chars))
+			}
+		}
+		if got := r.RenderColumn(len(tt.chars)+5, tt.tabStop); got != end {
+			t.Errorf("%q with tab stop %d: RenderColumn past the end = %d, want %d", tt.chars, tt.tabStop, got, end)
+		}
+	}
+}
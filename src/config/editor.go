@@ -1,8 +1,6 @@
 package config
 
 import (
-	"bufio"
-	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,6 +11,7 @@ import (
 
 	"github.com/deanrtaylor1/go-editor/constants"
 	"github.com/deanrtaylor1/go-editor/fuzzy"
+	"github.com/deanrtaylor1/go-editor/keys"
 	"github.com/deanrtaylor1/go-editor/utils"
 )
 
@@ -24,10 +23,15 @@ type Point struct {
 const (
 	MODAL_TYPE_FUZZY = iota
 	MODAL_TYPE_GENERIC
+	// MODAL_TYPE_VIEW only shows its lines, they can't be searched or opened
+	MODAL_TYPE_VIEW
+	// MODAL_TYPE_UNDO_TREE lists the undo states, newest first
+	MODAL_TYPE_UNDO_TREE
 )
 
 type Modal struct {
 	Type            int
+	Title           string
 	ModalInput      []byte
 	CursorPosition  int
 	Data            interface{}
@@ -55,9 +59,12 @@ type Editor struct {
 	StatusMsg              string
 	StatusMsgTime          time.Time
 	QuitTimes              int
-	Reader                 *bufio.Reader
+	Quitting               bool
+	QuitErr                error
+	Keys                   *keys.Decoder
+	Input                  chan InputEvent
+	Results                chan func(*Editor)
 	FirstRead              bool
-	UndoHistory            int
 	CurrentDirectory       string
 	RootDirectory          string
 	FileBrowserItems       []FileBrowserItem
@@ -65,9 +72,57 @@ type Editor struct {
 	FileBrowserIntroLength int
 	MotionBuffer           []rune
 	MotionMap              map[string]func()
-	Yank                   Yank
 	ModalOpen              bool
 	Modal                  Modal
+	Settings               *Settings
+	// MotionCount is the count typed before the sequence being run, 0 when
+	// there was none
+	MotionCount int
+	// LastChange holds the keys of the last complete change for . to replay,
+	// Change those of the change being typed
+	LastChange []keys.Key
+	Change     []keys.Key
+	Repeating  bool
+	// Registers hold yanked and deleted text and recorded macros, the macros
+	// as text in key notation
+	Registers Registers
+	// Recording is the register keys are being recorded into, 0 when not
+	// recording
+	Recording    rune
+	RecordedKeys []keys.Key
+	// PendingKeys are keys of a macro still to be played, read before any
+	// typed ones. MacroRuns counts the macros started by the one playing.
+	PendingKeys []keys.Key
+	MacroRuns   int
+	LastMacro   rune
+	// KeyErr is why the last key failed, which stops a playing macro
+	KeyErr error
+	// SelectedRegister is the register given with " for the next command, 0
+	// when none was
+	SelectedRegister rune
+	// InsertedText is what has been typed since insert mode started
+	InsertedText []byte
+	CommandLine  CommandLine
+}
+
+// InputEvent is a key read by the input goroutine, or the error that stopped
+// it.
+type InputEvent struct {
+	Key keys.Key
+	Err error
+}
+
+// Post hands the result of background work to the main loop. result runs on
+// the main goroutine, so it may touch the editor state freely.
+func (e *Editor) Post(result func(*Editor)) {
+	e.Results <- result
+}
+
+// Quit asks the main loop to stop. err is what the loop returns, nil for a
+// normal exit.
+func (e *Editor) Quit(err error) {
+	e.Quitting = true
+	e.QuitErr = err
 }
 
 func (e *Editor) ClearModalInput() {
@@ -90,13 +145,15 @@ func NewEditor() *Editor {
 		StatusMsg:        "",
 		StatusMsgTime:    time.Time{},
 		QuitTimes:        constants.QUIT_TIMES,
-		Reader:           bufio.NewReader(os.Stdin),
+		Input:            make(chan InputEvent),
+		Results:          make(chan func(*Editor), 64),
 		FirstRead:        true,
-		UndoHistory:      30,
 		FileBrowserItems: []FileBrowserItem{},
 		CurrentDirectory: "",
 		MotionBuffer:     []rune{},
 		ModalOpen:        false,
+		Settings:         NewSettings(),
+		Registers:        Registers{},
 	}
 }
 
@@ -187,95 +244,69 @@ func NewResults() fuzzy.Matches {
 	return fuzzy.Matches{}
 }
 
-func (e *Editor) DeleteSelection() {
-	startPoint, endPoint := e.GetNormalizedSelection()
-
-	// Case 1: Start and end points are on the same row
-	if startPoint.Row == endPoint.Row {
-		endPointCursor := endPoint.Col - e.LineNumberWidth + 1
-		row := &e.CurrentBuffer.Rows[startPoint.Row]
-		if endPointCursor > row.Length {
-			endPointCursor = row.Length
-		}
-		row.Chars = append(row.Chars[:startPoint.Col-e.LineNumberWidth], row.Chars[endPointCursor:]...)
-		row.Length = len(row.Chars)
-	} else {
-		// Case 2: Start and end points are on different rows
-
-		// Update the start row
-		startRow := &e.CurrentBuffer.Rows[startPoint.Row]
-		startRow.Chars = startRow.Chars[:startPoint.Col-e.LineNumberWidth]
-		startRow.Length = len(startRow.Chars)
-
-		// Update the end row
-		endRow := &e.CurrentBuffer.Rows[endPoint.Row]
-		endRow.Chars = endRow.Chars[endPoint.Col-e.LineNumberWidth+1:]
-		endRow.Length = len(endRow.Chars)
-
-		// Remove the rows between start and end
-		e.CurrentBuffer.RemoveRowsFromIndex(startPoint.Row+1, endPoint.Row-startPoint.Row-1)
-
-		// Merge the start and end rows
-		startRow.Chars = append(startRow.Chars, endRow.Chars...)
-		startRow.Length = len(startRow.Chars)
-
-		// Remove the end row (which is now at startPoint.Row + 1 after removing the middle rows)
-		e.CurrentBuffer.RemoveRowAtIndex(startPoint.Row + 1)
+// Selection points hold byte indexes into the rows, and the end point is
+// inclusive of the character it sits on.
+func (e *Editor) selectionEndIndex(end Point) int {
+	// There is no row to select in at the end of the buffer
+	if end.Row >= e.CurrentBuffer.NumRows {
+		return end.Col
 	}
-	e.Cx = e.LineNumberWidth
-	e.CurrentBuffer.SliceIndex = 0
+	return utils.NextGrapheme(e.CurrentBuffer.Row(end.Row).Chars, end.Col)
 }
 
-func (e *Editor) YankSelection() {
+// SelectionRange returns the selected text as its start and the point just
+// past it. A linewise selection covers its rows and their line breaks.
+func (e *Editor) SelectionRange() (Point, Point) {
 	startPoint, endPoint := e.GetNormalizedSelection()
-	partialBuffer := Buffer{
-		Rows:    []Row{},
-		NumRows: 0,
+	if e.CurrentBuffer.SelectionLinewise {
+		return Point{Row: startPoint.Row}, Point{Row: endPoint.Row + 1}
 	}
+	return startPoint, Point{Row: endPoint.Row, Col: e.selectionEndIndex(endPoint)}
+}
 
-	for i := startPoint.Row; i <= endPoint.Row; i++ {
-		row := e.CurrentBuffer.Rows[i]
-		newRow := Row{}
-		if i == startPoint.Row && i == endPoint.Row {
-			newRow.Chars = row.Chars[startPoint.Col-e.LineNumberWidth : endPoint.Col-e.LineNumberWidth+1]
-		} else if i == startPoint.Row {
-			newRow.Chars = row.Chars[startPoint.Col-e.LineNumberWidth:]
-		} else if i == endPoint.Row {
-			newRow.Chars = row.Chars[:endPoint.Col-e.LineNumberWidth]
-		} else {
-			newRow.Chars = row.Chars
-		}
-		newRow.Chars = bytes.Trim(newRow.Chars, "\x00")
-		partialBuffer.Rows = append(partialBuffer.Rows, newRow)
-		partialBuffer.NumRows++
-	}
+// DeleteSelection removes the selected text and returns where it started and
+// what was removed.
+func (e *Editor) DeleteSelection() (Point, []byte) {
+	startPoint, end := e.SelectionRange()
 
-	e.Yank.PartialBuffer = partialBuffer
-	if partialBuffer.NumRows == 1 {
-		e.Yank.Type = CharWise
-	} else {
-		e.Yank.Type = LineWise
+	removed := e.CurrentBuffer.DeleteText(startPoint, end)
+	e.Cy = startPoint.Row
+	e.Cx = e.LineNumberWidth
+	e.CurrentBuffer.SliceIndex = 0
+	return startPoint, removed
+}
+
+// SelectedText returns the selected text as it goes in a register.
+func (e *Editor) SelectedText() Register {
+	start, end := e.SelectionRange()
+	b := e.CurrentBuffer
+	text := b.Text.Slice(b.PointToOffset(start), b.PointToOffset(end))
+	if b.SelectionLinewise {
+		return Register{Text: strings.TrimSuffix(string(text), "\n"), Type: LineWise}
 	}
+	return Register{Text: string(text), Type: CharWise}
 }
 
-func (e *Editor) IsWithinSelection(fileRow, col int, startPoint, endPoint Point) bool {
+// YankSelection keeps the selected text in the register called name, see
+// Registers.Yank.
+func (e *Editor) YankSelection(name rune) error {
+	return e.Registers.Yank(name, e.SelectedText())
+}
+
+func (e *Editor) IsWithinSelection(fileRow, idx int, startPoint, endPoint Point) bool {
 	withinSelection := false
 	if fileRow == startPoint.Row && fileRow == endPoint.Row {
-		withinSelection = (e.ColOff+col >= startPoint.Col-e.LineNumberWidth && col <= endPoint.Col-e.LineNumberWidth)
+		withinSelection = idx >= startPoint.Col && idx <= endPoint.Col
 	} else if fileRow == startPoint.Row {
-		withinSelection = (e.ColOff+col >= startPoint.Col-e.LineNumberWidth)
+		withinSelection = idx >= startPoint.Col
 	} else if fileRow == endPoint.Row {
-		withinSelection = (e.ColOff+col <= endPoint.Col-e.LineNumberWidth)
+		withinSelection = idx <= endPoint.Col
 	} else if fileRow > startPoint.Row && fileRow < endPoint.Row {
 		withinSelection = true
 	}
 	return withinSelection
 }
 
-func (e *Editor) ClearYank() {
-	e.Yank = Yank{PartialBuffer: *NewBuffer(), Type: EMPTY_YANK}
-}
-
 // Get the normalized selection start and end points
 func (e *Editor) GetNormalizedSelection() (Point, Point) {
 	start := e.CurrentBuffer.SelectionStart
@@ -291,20 +322,26 @@ func (e *Editor) ClearSelection() {
 	neutralPoint := Point{Col: -1, Row: -1}
 	e.CurrentBuffer.SelectionStart = neutralPoint
 	e.CurrentBuffer.SelectionEnd = neutralPoint
+	e.CurrentBuffer.SelectionLinewise = false
 }
 
 func (e *Editor) MoveSelection() {
-	e.CurrentBuffer.SelectionEnd = Point{Col: e.Cx, Row: e.Cy}
+	e.CurrentBuffer.SelectionEnd = Point{Col: e.CurrentBuffer.SliceIndex, Row: e.Cy}
 }
 
 func (e *Editor) HighlightSelection() {
-	e.CurrentBuffer.SelectionStart = Point{Col: e.Cx, Row: e.Cy}
-	e.CurrentBuffer.SelectionEnd = Point{Col: e.Cx, Row: e.Cy}
+	e.CurrentBuffer.SelectionLinewise = false
+	e.CurrentBuffer.SelectionStart = Point{Col: e.CurrentBuffer.SliceIndex, Row: e.Cy}
+	e.CurrentBuffer.SelectionEnd = Point{Col: e.CurrentBuffer.SliceIndex, Row: e.Cy}
 }
 
 func (e *Editor) HighlightLine() {
-	e.CurrentBuffer.SelectionStart = Point{Col: e.LineNumberWidth, Row: e.Cy}
-	e.CurrentBuffer.SelectionEnd = Point{Col: e.GetCurrentRow().Length + e.LineNumberWidth, Row: e.Cy}
+	e.CurrentBuffer.SelectionLinewise = true
+	e.CurrentBuffer.SelectionStart = Point{Col: 0, Row: e.Cy}
+	e.CurrentBuffer.SelectionEnd = Point{Row: e.Cy}
+	if e.Cy < e.CurrentBuffer.NumRows {
+		e.CurrentBuffer.SelectionEnd.Col = e.GetCurrentRow().Length
+	}
 }
 
 func (e *Editor) YankChars() {
@@ -378,7 +415,7 @@ func (e *Editor) ReplaceBuffer() {
 // It returns true if a matching buffer is found, false otherwise.
 func (e *Editor) ReloadBuffer(path string) bool {
 	for _, bufferItem := range e.Buffers {
-		if e.RootDirectory+bufferItem.Name == path {
+		if filepath.Join(e.RootDirectory, bufferItem.Name) == filepath.Clean(path) {
 			// Load the old buffer into CurrentBuffer
 			e.CurrentBuffer = &bufferItem
 			e.Cx = bufferItem.StoredCx
@@ -411,18 +448,8 @@ func (e *Editor) RemoveBuffer(name string) {
 	}
 }
 
-func (c *Editor) ClearRedoStack() {
-	c.CurrentBuffer.RedoStack = []EditorAction{}
-}
-
-func (c *Editor) GetAdjustedCx() int {
-	adjustedCx := utils.Max(0, c.Cx-c.LineNumberWidth)
-	adjustedCx = utils.Min(adjustedCx, len(c.CurrentBuffer.Rows[c.Cy].Chars))
-	return adjustedCx
-}
-
 func (c *Editor) GetCurrentRow() *Row {
-	return &c.CurrentBuffer.Rows[c.Cy]
+	return c.CurrentBuffer.Row(c.Cy)
 }
 
 func (e *Editor) SpecialRefreshCase() bool {
@@ -438,17 +465,51 @@ func (e *Editor) SetMode(mode int) {
 }
 
 func (e *Editor) MoveCursorLeft() {
-	e.Cx--
-	if e.EditorMode != constants.EDITOR_MODE_FILE_BROWSER {
-		e.CurrentBuffer.SliceIndex--
+	if e.EditorMode == constants.EDITOR_MODE_FILE_BROWSER {
+		e.Cx--
+		return
 	}
+	if e.Cy < e.CurrentBuffer.NumRows {
+		e.CurrentBuffer.SliceIndex = utils.PrevGrapheme(e.GetCurrentRow().Chars, e.CurrentBuffer.SliceIndex)
+	}
+	e.SyncCx()
 }
 
 func (e *Editor) MoveCursorRight() {
-	if e.EditorMode != constants.EDITOR_MODE_FILE_BROWSER {
-		e.CurrentBuffer.SliceIndex++
+	if e.EditorMode == constants.EDITOR_MODE_FILE_BROWSER {
+		e.Cx++
+		return
+	}
+	if e.Cy < e.CurrentBuffer.NumRows {
+		e.CurrentBuffer.SliceIndex = utils.NextGrapheme(e.GetCurrentRow().Chars, e.CurrentBuffer.SliceIndex)
+	}
+	e.SyncCx()
+}
+
+// SyncCx moves Cx to the screen column of the character at SliceIndex.
+func (e *Editor) SyncCx() {
+	if e.Cy >= e.CurrentBuffer.NumRows {
+		e.Cx = e.LineNumberWidth
+		e.CurrentBuffer.SliceIndex = 0
+		return
+	}
+	row := e.GetCurrentRow()
+	if e.CurrentBuffer.SliceIndex > row.Length {
+		e.CurrentBuffer.SliceIndex = row.Length
+	}
+	e.Cx = row.RenderColumn(e.CurrentBuffer.SliceIndex, e.CurrentBuffer.Options.TabWidth) + e.LineNumberWidth
+}
+
+// SyncSliceIndex moves SliceIndex to the character drawn at Cx and snaps Cx
+// to the start of that character.
+func (e *Editor) SyncSliceIndex() {
+	if e.Cy >= e.CurrentBuffer.NumRows {
+		e.Cx = e.LineNumberWidth
+		e.CurrentBuffer.SliceIndex = 0
+		return
 	}
-	e.Cx++
+	e.CurrentBuffer.SliceIndex = e.GetCurrentRow().ByteIndexForColumn(e.Cx-e.LineNumberWidth, e.CurrentBuffer.Options.TabWidth)
+	e.SyncCx()
 }
 
 func (e *Editor) MoveCursorUp() {
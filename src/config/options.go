@@ -0,0 +1,162 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/deanrtaylor1/go-editor/constants"
+)
+
+// BufferOptions holds the formatting settings of a single buffer. They start
+// from the defaults of the buffer's file type and can be overridden by an
+// .editorconfig.
+type BufferOptions struct {
+	IndentStyle            string
+	IndentSize             int
+	TabWidth               int
+	EndOfLine              string
+	InsertFinalNewline     bool
+	TrimTrailingWhitespace bool
+}
+
+func NewBufferOptions() *BufferOptions {
+	return &BufferOptions{
+		IndentStyle:            constants.INDENT_STYLE_SPACE,
+		IndentSize:             constants.TAB_STOP,
+		TabWidth:               constants.TAB_STOP,
+		EndOfLine:              constants.END_OF_LINE_LF,
+		InsertFinalNewline:     true,
+		TrimTrailingWhitespace: false,
+	}
+}
+
+func (o *BufferOptions) ApplySyntax(syntax constants.SyntaxHighlighting) {
+	if syntax.IndentStyle != "" {
+		o.IndentStyle = syntax.IndentStyle
+	}
+	if syntax.IndentSize > 0 {
+		o.IndentSize = syntax.IndentSize
+		o.TabWidth = syntax.IndentSize
+	}
+}
+
+// ApplyEditorConfig overrides the options with the properties found in an
+// .editorconfig. Unknown properties and invalid values are ignored.
+func (o *BufferOptions) ApplyEditorConfig(properties map[string]string) {
+	switch properties["indent_style"] {
+	case constants.INDENT_STYLE_SPACE, constants.INDENT_STYLE_TAB:
+		o.IndentStyle = properties["indent_style"]
+	}
+
+	tabWidth, hasTabWidth := positiveInt(properties["tab_width"])
+	if hasTabWidth {
+		o.TabWidth = tabWidth
+	}
+	if size, ok := positiveInt(properties["indent_size"]); ok {
+		o.IndentSize = size
+		// tab_width defaults to indent_size when it is not given
+		if !hasTabWidth {
+			o.TabWidth = size
+		}
+	} else if properties["indent_size"] == "tab" || (o.IndentStyle == constants.INDENT_STYLE_TAB && hasTabWidth) {
+		o.IndentSize = o.TabWidth
+	}
+
+	switch properties["end_of_line"] {
+	case constants.END_OF_LINE_LF, constants.END_OF_LINE_CRLF, constants.END_OF_LINE_CR:
+		o.EndOfLine = properties["end_of_line"]
+	}
+	if value, ok := properties["insert_final_newline"]; ok && (value == "true" || value == "false") {
+		o.InsertFinalNewline = value == "true"
+	}
+	if value, ok := properties["trim_trailing_whitespace"]; ok && (value == "true" || value == "false") {
+		o.TrimTrailingWhitespace = value == "true"
+	}
+}
+
+// OptionNames lists every buffer option Set knows about, named as in an
+// .editorconfig.
+var OptionNames = []string{"indent_style", "indent_size", "tab_width", "end_of_line", "insert_final_newline", "trim_trailing_whitespace"}
+
+// Set changes a single option by name.
+func (o *BufferOptions) Set(name string, value string) error {
+	switch name {
+	case "indent_style":
+		if value != constants.INDENT_STYLE_SPACE && value != constants.INDENT_STYLE_TAB {
+			return fmt.Errorf("invalid value for %s: %q, expected space or tab", name, value)
+		}
+		o.IndentStyle = value
+	case "indent_size", "tab_width":
+		n, ok := positiveInt(value)
+		if !ok {
+			return fmt.Errorf("invalid value for %s: %q, expected a positive number", name, value)
+		}
+		if name == "indent_size" {
+			o.IndentSize = n
+		} else {
+			o.TabWidth = n
+		}
+	case "end_of_line":
+		switch value {
+		case constants.END_OF_LINE_LF, constants.END_OF_LINE_CRLF, constants.END_OF_LINE_CR:
+			o.EndOfLine = value
+		default:
+			return fmt.Errorf("invalid value for %s: %q, expected lf, crlf or cr", name, value)
+		}
+	case "insert_final_newline", "trim_trailing_whitespace":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %q", name, value)
+		}
+		if name == "insert_final_newline" {
+			o.InsertFinalNewline = b
+		} else {
+			o.TrimTrailingWhitespace = b
+		}
+	default:
+		return fmt.Errorf("unknown option: %s", name)
+	}
+	return nil
+}
+
+// Get returns the value of a single option by name, written the way Set
+// reads it.
+func (o *BufferOptions) Get(name string) (string, error) {
+	switch name {
+	case "indent_style":
+		return o.IndentStyle, nil
+	case "indent_size":
+		return strconv.Itoa(o.IndentSize), nil
+	case "tab_width":
+		return strconv.Itoa(o.TabWidth), nil
+	case "end_of_line":
+		return o.EndOfLine, nil
+	case "insert_final_newline":
+		return strconv.FormatBool(o.InsertFinalNewline), nil
+	case "trim_trailing_whitespace":
+		return strconv.FormatBool(o.TrimTrailingWhitespace), nil
+	}
+	return "", fmt.Errorf("unknown option: %s", name)
+}
+
+// IndentUnit returns the text that makes up one level of indentation.
+func (o *BufferOptions) IndentUnit() []byte {
+	if o.IndentStyle == constants.INDENT_STYLE_TAB {
+		return []byte{'\t'}
+	}
+	return bytes.Repeat([]byte{' '}, o.IndentSize)
+}
+
+// IndentText returns the text inserted for the given indentation level.
+func (o *BufferOptions) IndentText(level int) []byte {
+	return bytes.Repeat(o.IndentUnit(), level)
+}
+
+func positiveInt(s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n, true
+}
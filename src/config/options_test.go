@@ -0,0 +1,52 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/deanrtaylor1/go-editor/constants"
+)
+
+func TestApplyEditorConfig(t *testing.T) {
+	defaults := *NewBufferOptions()
+	tests := []struct {
+		properties map[string]string
+		want       func(o *BufferOptions)
+	}{
+		{map[string]string{}, func(o *BufferOptions) {}},
+		{map[string]string{"indent_style": "tab"}, func(o *BufferOptions) {
+			o.IndentStyle = constants.INDENT_STYLE_TAB
+		}},
+		{map[string]string{"indent_style": "both"}, func(o *BufferOptions) {}},
+		{map[string]string{"indent_size": "2"}, func(o *BufferOptions) {
+			o.IndentSize, o.TabWidth = 2, 2
+		}},
+		{map[string]string{"indent_size": "2", "tab_width": "8"}, func(o *BufferOptions) {
+			o.IndentSize, o.TabWidth = 2, 8
+		}},
+		{map[string]string{"indent_size": "tab", "tab_width": "8"}, func(o *BufferOptions) {
+			o.IndentSize, o.TabWidth = 8, 8
+		}},
+		{map[string]string{"indent_style": "tab", "tab_width": "8"}, func(o *BufferOptions) {
+			o.IndentStyle = constants.INDENT_STYLE_TAB
+			o.IndentSize, o.TabWidth = 8, 8
+		}},
+		{map[string]string{"indent_size": "0", "tab_width": "-1"}, func(o *BufferOptions) {}},
+		{map[string]string{"end_of_line": "crlf"}, func(o *BufferOptions) {
+			o.EndOfLine = constants.END_OF_LINE_CRLF
+		}},
+		{map[string]string{"end_of_line": "unix"}, func(o *BufferOptions) {}},
+		{map[string]string{"insert_final_newline": "false", "trim_trailing_whitespace": "true"}, func(o *BufferOptions) {
+			o.InsertFinalNewline, o.TrimTrailingWhitespace = false, true
+		}},
+		{map[string]string{"insert_final_newline": "unset"}, func(o *BufferOptions) {}},
+	}
+	for _, tt := range tests {
+		got := defaults
+		got.ApplyEditorConfig(tt.properties)
+		want := defaults
+		tt.want(&want)
+		if got != want {
+			t.Errorf("ApplyEditorConfig(%v) = %+v, want %+v", tt.properties, got, want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"unicode"
+)
+
+type YankType int
+
+const (
+	EMPTY_YANK YankType = iota
+	LineWise
+	CharWise
+	BlockWise
+)
+
+// The registers with a meaning of their own. Besides these, a to z are for the
+// user, with A to Z appending to them, 0 holds the last yank and 1 to 9 the
+// last deletes, the most recent in 1.
+const (
+	UnnamedRegister    = '"'
+	BlackHoleRegister  = '_'
+	LastInsertRegister = '.'
+	LastSearchRegister = '/'
+)
+
+// Register is text kept for pasting or for playing as a macro. LineWise text
+// stands for whole rows and has no line break at the end.
+type Register struct {
+	Text string
+	Type YankType
+}
+
+// Registers hold the register contents by name.
+type Registers map[rune]Register
+
+// IsRegister reports whether name can be given to a command, as in "ay.
+func IsRegister(name rune) bool {
+	switch {
+	case name >= 'a' && name <= 'z', name >= 'A' && name <= 'Z', name >= '0' && name <= '9':
+		return true
+	}
+	switch name {
+	case UnnamedRegister, BlackHoleRegister, LastInsertRegister, LastSearchRegister:
+		return true
+	}
+	return false
+}
+
+// Get returns the register called name, the unnamed one when name is 0.
+func (r Registers) Get(name rune) (Register, bool) {
+	if name == 0 {
+		name = UnnamedRegister
+	}
+	reg, ok := r[unicode.ToLower(name)]
+	return reg, ok
+}
+
+// Set puts reg in the register called name. An upper case name appends to the
+// lower case register. The registers the editor fills in itself can't be set.
+func (r Registers) Set(name rune, reg Register) error {
+	switch {
+	case name == BlackHoleRegister:
+		return nil
+	case name == LastInsertRegister || name == LastSearchRegister:
+		return fmt.Errorf("register %c is read only", name)
+	case !IsRegister(name):
+		return fmt.Errorf("invalid register: %c", name)
+	case unicode.IsUpper(name):
+		name = unicode.ToLower(name)
+		if old, ok := r[name]; ok {
+			reg = joinRegisters(old, reg)
+		}
+	}
+	r[name] = reg
+	return nil
+}
+
+func joinRegisters(a, b Register) Register {
+	if a.Type == LineWise || b.Type == LineWise {
+		return Register{Text: a.Text + "\n" + b.Text, Type: LineWise}
+	}
+	return Register{Text: a.Text + b.Text, Type: a.Type}
+}
+
+// Yank keeps yanked text in the register called name, or in 0 when none was
+// given. The unnamed register gets it too.
+func (r Registers) Yank(name rune, reg Register) error {
+	if name == 0 || name == UnnamedRegister {
+		name = '0'
+	}
+	return r.store(name, reg)
+}
+
+// Delete keeps deleted text in the register called name, or in 1 when none was
+// given, moving the earlier deletes up one register to 9. The unnamed register
+// gets it too.
+func (r Registers) Delete(name rune, reg Register) error {
+	if name == 0 || name == UnnamedRegister {
+		for i := '9'; i > '1'; i-- {
+			if prev, ok := r[i-1]; ok {
+				r[i] = prev
+			}
+		}
+		name = '1'
+	}
+	return r.store(name, reg)
+}
+
+func (r Registers) store(name rune, reg Register) error {
+	if name == BlackHoleRegister {
+		return nil
+	}
+	if err := r.Set(name, reg); err != nil {
+		return err
+	}
+	r[UnnamedRegister], _ = r.Get(name)
+	return nil
+}
+
+// Fill sets one of the registers the editor keeps itself, like the last
+// inserted text, which Set refuses.
+func (r Registers) Fill(name rune, text string) {
+	r[name] = Register{Text: text, Type: CharWise}
+}
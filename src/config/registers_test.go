@@ -0,0 +1,99 @@
+package config
+
+import "testing"
+
+func TestRegistersYankAndDelete(t *testing.T) {
+	r := Registers{}
+	chars := func(text string) Register { return Register{Text: text, Type: CharWise} }
+
+	// A yank with no register goes in 0 and the unnamed one
+	r.Yank(0, chars("y1"))
+	if r['0'] != chars("y1") || r[UnnamedRegister] != chars("y1") {
+		t.Errorf("after a yank 0 is %+v and \" is %+v, want y1 in both", r['0'], r[UnnamedRegister])
+	}
+
+	// Deletes go in 1, moving the earlier ones up and the oldest out past 9,
+	// and leave 0 alone
+	for _, text := range []string{"d1", "d2", "d3", "d4", "d5", "d6", "d7", "d8", "d9", "d10"} {
+		r.Delete(0, chars(text))
+	}
+	for i, want := range []string{"d10", "d9", "d8", "d7", "d6", "d5", "d4", "d3", "d2"} {
+		if got := r['1'+rune(i)].Text; got != want {
+			t.Errorf("register %c holds %q, want %q", '1'+rune(i), got, want)
+		}
+	}
+	if r['0'].Text != "y1" || r[UnnamedRegister].Text != "d10" {
+		t.Errorf("after the deletes 0 is %q and \" is %q, want y1 and d10", r['0'].Text, r[UnnamedRegister].Text)
+	}
+
+	// A named register takes the text instead of 0 or 1, and doesn't shift
+	// the deletes
+	r.Yank('a', chars("ya"))
+	r.Delete('b', chars("db"))
+	if r['0'].Text != "y1" || r['1'].Text != "d10" || r['2'].Text != "d9" {
+		t.Errorf("after named yanks 0, 1 and 2 are %q, %q and %q, want y1, d10 and d9", r['0'].Text, r['1'].Text, r['2'].Text)
+	}
+	if r['a'].Text != "ya" || r['b'].Text != "db" || r[UnnamedRegister].Text != "db" {
+		t.Errorf("a, b and \" are %q, %q and %q, want ya, db and db", r['a'].Text, r['b'].Text, r[UnnamedRegister].Text)
+	}
+
+	// The black hole keeps nothing, not even in the unnamed register
+	r.Delete(BlackHoleRegister, chars("gone"))
+	r.Yank(BlackHoleRegister, chars("gone"))
+	if _, ok := r[BlackHoleRegister]; ok || r['1'].Text != "d10" || r[UnnamedRegister].Text != "db" {
+		t.Errorf("_ kept its text: 1 is %q and \" is %q", r['1'].Text, r[UnnamedRegister].Text)
+	}
+}
+
+func TestRegistersSet(t *testing.T) {
+	tests := []struct {
+		name      rune
+		old, reg  Register
+		want      Register
+		wantError bool
+	}{
+		{'a', Register{}, Register{"two", CharWise}, Register{"two", CharWise}, false},
+		{'a', Register{"one", CharWise}, Register{"two", CharWise}, Register{"two", CharWise}, false},
+		// Upper case appends, whole rows going on a row of their own
+		{'A', Register{"one", CharWise}, Register{"two", CharWise}, Register{"onetwo", CharWise}, false},
+		{'A', Register{"one", LineWise}, Register{"two", LineWise}, Register{"one\ntwo", LineWise}, false},
+		{'A', Register{"one", CharWise}, Register{"two", LineWise}, Register{"one\ntwo", LineWise}, false},
+		{'A', Register{"one", LineWise}, Register{"two", CharWise}, Register{"one\ntwo", LineWise}, false},
+		{'A', Register{}, Register{"two", CharWise}, Register{"two", CharWise}, false},
+		{'_', Register{}, Register{"two", CharWise}, Register{}, false},
+		{'.', Register{}, Register{"two", CharWise}, Register{}, true},
+		{'/', Register{}, Register{"two", CharWise}, Register{}, true},
+		{'!', Register{}, Register{"two", CharWise}, Register{}, true},
+	}
+	for _, tt := range tests {
+		r := Registers{}
+		if tt.old.Text != "" {
+			r['a'] = tt.old
+		}
+		err := r.Set(tt.name, tt.reg)
+		if (err != nil) != tt.wantError {
+			t.Errorf("Set(%c, %+v) returned %v", tt.name, tt.reg, err)
+		}
+		got, _ := r.Get(tt.name)
+		if got != tt.want {
+			t.Errorf("Set(%c, %+v) on %+v left %+v, want %+v", tt.name, tt.reg, tt.old, got, tt.want)
+		}
+	}
+}
+
+func TestRegistersFill(t *testing.T) {
+	r := Registers{}
+	r.Fill(LastInsertRegister, "typed")
+	r.Fill(LastSearchRegister, "found")
+	if err := r.Set(LastInsertRegister, Register{Text: "other", Type: CharWise}); err == nil {
+		t.Error("Set of . after Fill returned no error")
+	}
+	for name, want := range map[rune]string{LastInsertRegister: "typed", LastSearchRegister: "found"} {
+		if got, ok := r.Get(name); !ok || got != (Register{Text: want, Type: CharWise}) {
+			t.Errorf("register %c holds %+v, want %q", name, got, want)
+		}
+	}
+	if _, ok := r.Get(0); ok {
+		t.Error("Fill put its text in the unnamed register")
+	}
+}
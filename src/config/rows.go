@@ -0,0 +1,115 @@
+package config
+
+import (
+	"sort"
+
+	"github.com/deanrtaylor1/go-editor/utils"
+)
+
+// rowBlockSize is how many rows a block of a RowList is filled with, which
+// bounds how many rows adding or removing one has to move.
+const rowBlockSize = 512
+
+// RowList holds the rows of a buffer in blocks, so adding or removing rows
+// moves the rows of the blocks touched and the start of each block after
+// them rather than every row after the change.
+type RowList struct {
+	blocks [][]Row
+	// starts holds the index of the first row of each block
+	starts []int
+	count  int
+}
+
+// NewRowList returns a list holding rows.
+func NewRowList(rows []Row) RowList {
+	l := RowList{}
+	for len(rows) > 0 {
+		n := utils.Min(len(rows), rowBlockSize)
+		l.blocks = append(l.blocks, rows[:n:n])
+		rows = rows[n:]
+	}
+	l.reindex(0)
+	return l
+}
+
+func (l *RowList) Len() int {
+	return l.count
+}
+
+// At returns the row at index i, which stays valid until rows are added or
+// removed.
+func (l *RowList) At(i int) *Row {
+	b := l.block(i)
+	return &l.blocks[b][i-l.starts[b]]
+}
+
+// block returns the block holding row i.
+func (l *RowList) block(i int) int {
+	return sort.Search(len(l.starts), func(b int) bool { return l.starts[b] > i }) - 1
+}
+
+// Splice removes remove rows at index at and then inserts insert empty ones
+// there.
+func (l *RowList) Splice(at, remove, insert int) {
+	for remove > 0 {
+		b := l.block(at)
+		block := l.blocks[b]
+		from := at - l.starts[b]
+		n := utils.Min(remove, len(block)-from)
+		if n == len(block) {
+			l.blocks = append(l.blocks[:b], l.blocks[b+1:]...)
+			l.starts = append(l.starts[:b], l.starts[b+1:]...)
+		} else {
+			l.blocks[b] = append(block[:from], block[from+n:]...)
+		}
+		l.reindex(b)
+		remove -= n
+	}
+	if insert <= 0 {
+		return
+	}
+
+	// Rows go into the block holding the row at, or at the end of the last
+	// one, which is split up again when it grows too long
+	b, from := len(l.blocks)-1, 0
+	if at < l.count {
+		b = l.block(at)
+		from = at - l.starts[b]
+	} else if b >= 0 {
+		from = len(l.blocks[b])
+	}
+	var block []Row
+	if b < 0 {
+		b = 0
+		l.blocks = [][]Row{nil}
+	} else {
+		block = l.blocks[b]
+	}
+	grown := make([]Row, 0, len(block)+insert)
+	grown = append(grown, block[:from]...)
+	grown = append(grown, make([]Row, insert)...)
+	grown = append(grown, block[from:]...)
+
+	var split [][]Row
+	for len(grown) > 2*rowBlockSize {
+		split = append(split, grown[:rowBlockSize:rowBlockSize])
+		grown = grown[rowBlockSize:]
+	}
+	split = append(split, grown)
+	l.blocks = append(l.blocks[:b], append(split, l.blocks[b+1:]...)...)
+	l.reindex(b)
+}
+
+// reindex brings the block starts from block b on up to date.
+func (l *RowList) reindex(b int) {
+	l.starts = l.starts[:utils.Min(b, len(l.starts))]
+	start := 0
+	if b > 0 {
+		start = l.starts[b-1] + len(l.blocks[b-1])
+	}
+	for ; b < len(l.blocks); b++ {
+		l.starts = append(l.starts, start)
+		start += len(l.blocks[b])
+	}
+	l.count = start
+}
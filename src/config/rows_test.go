@@ -0,0 +1,51 @@
+package config
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestRowListSplice splices a RowList and a plain slice of rows the same way,
+// numbering each row through CharAdjustment to tell them apart.
+func TestRowListSplice(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	next := 0
+	numbered := func(n int) []Row {
+		rows := make([]Row, n)
+		for i := range rows {
+			next++
+			rows[i].CharAdjustment = next
+		}
+		return rows
+	}
+
+	want := numbered(3 * rowBlockSize)
+	list := NewRowList(append([]Row{}, want...))
+	for step := 0; step < 500; step++ {
+		at := rng.Intn(len(want) + 1)
+		remove := rng.Intn(len(want)-at+1) / (1 + rng.Intn(50))
+		insert := rng.Intn(3 * rowBlockSize / (1 + rng.Intn(50)))
+
+		list.Splice(at, remove, insert)
+		inserted := numbered(insert)
+		for i := range inserted {
+			list.At(at + i).CharAdjustment = inserted[i].CharAdjustment
+		}
+		want = append(want[:at], append(inserted, want[at+remove:]...)...)
+
+		if list.Len() != len(want) {
+			t.Fatalf("step %d: Len() = %d, want %d", step, list.Len(), len(want))
+		}
+		for i := range want {
+			if got := list.At(i).CharAdjustment; got != want[i].CharAdjustment {
+				t.Fatalf("step %d: row %d is %d, want %d", step, i, got, want[i].CharAdjustment)
+			}
+		}
+	}
+
+	list.Splice(0, list.Len(), 0)
+	list.Splice(0, 0, 2)
+	if list.Len() != 2 || list.At(1).CharAdjustment != 0 {
+		t.Fatalf("after emptying and refilling: Len() = %d", list.Len())
+	}
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Settings are the editor wide options. They are read from the settings file
+// at startup as key = value lines.
+type Settings struct {
+	Backup bool
+	// EscapeTimeout is how long to wait after ESC for the rest of an escape
+	// sequence before taking it as the Escape key
+	EscapeTimeout time.Duration
+	// Mouse turns on clicking, scrolling and selecting with the mouse
+	Mouse bool
+	// UndoLevels is how many undo steps each buffer keeps
+	UndoLevels int
+	// UndoFile keeps the undo history of a file on save, for the next time
+	// it is opened
+	UndoFile bool
+}
+
+func NewSettings() *Settings {
+	return &Settings{
+		Backup:        false,
+		EscapeTimeout: 50 * time.Millisecond,
+		Mouse:         true,
+		UndoLevels:    1000,
+		UndoFile:      true,
+	}
+}
+
+// SettingsPath returns where the settings file lives, normally
+// ~/.config/go-editor/config.
+func SettingsPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "go-editor", "config")
+}
+
+// Load applies every setting found in the file at path. Lines starting with
+// '#' are comments.
+func (s *Settings) Load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected key = value", path, lineNumber)
+		}
+		if err := s.Set(strings.TrimSpace(key), strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// Set changes a single setting by name.
+func (s *Settings) Set(name string, value string) error {
+	switch name {
+	case "backup":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %q", name, value)
+		}
+		s.Backup = b
+	case "escape_timeout":
+		ms, err := strconv.Atoi(value)
+		if err != nil || ms < 0 {
+			return fmt.Errorf("invalid value for %s: %q, expected milliseconds", name, value)
+		}
+		s.EscapeTimeout = time.Duration(ms) * time.Millisecond
+	case "mouse":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %q", name, value)
+		}
+		s.Mouse = b
+	case "undo_levels":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid value for %s: %q, expected a positive number", name, value)
+		}
+		s.UndoLevels = n
+	case "undo_file":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %q", name, value)
+		}
+		s.UndoFile = b
+	default:
+		return fmt.Errorf("unknown setting: %s", name)
+	}
+	return nil
+}
+
+// SettingNames lists every setting Set knows about.
+var SettingNames = []string{"backup", "escape_timeout", "mouse", "undo_levels", "undo_file"}
+
+// Get returns the value of a single setting by name, written the way Set
+// reads it.
+func (s *Settings) Get(name string) (string, error) {
+	switch name {
+	case "backup":
+		return strconv.FormatBool(s.Backup), nil
+	case "escape_timeout":
+		return strconv.Itoa(int(s.EscapeTimeout / time.Millisecond)), nil
+	case "mouse":
+		return strconv.FormatBool(s.Mouse), nil
+	case "undo_levels":
+		return strconv.Itoa(s.UndoLevels), nil
+	case "undo_file":
+		return strconv.FormatBool(s.UndoFile), nil
+	}
+	return "", fmt.Errorf("unknown setting: %s", name)
+}
@@ -0,0 +1,360 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/deanrtaylor1/go-editor/piecetable"
+	"github.com/deanrtaylor1/go-editor/utils"
+)
+
+// UndoState is one state the text of a buffer has been in, reached from its
+// parent by the edits of Action.
+type UndoState struct {
+	// Seq numbers the states in the order they were made, the root is 0
+	Seq      int
+	Time     time.Time
+	Action   EditorAction
+	Parent   *UndoState
+	Children []*UndoState
+	// Next is the child redo goes to, the one made or undone last
+	Next *UndoState
+}
+
+// UndoTree keeps every state the text of a buffer went through. Undoing and
+// then making a change starts a new branch, so nothing is lost to redo.
+type UndoTree struct {
+	Root    *UndoState
+	Current *UndoState
+	// States hold the states in the order they were made
+	States []*UndoState
+	seq    int
+}
+
+func NewUndoTree() *UndoTree {
+	root := &UndoState{Time: time.Now()}
+	return &UndoTree{Root: root, Current: root, States: []*UndoState{root}}
+}
+
+// add makes action a new state after the current one, dropping the oldest
+// states beyond max.
+func (t *UndoTree) add(action EditorAction, max int) {
+	t.seq++
+	state := &UndoState{Seq: t.seq, Time: time.Now(), Action: action, Parent: t.Current}
+	t.Current.Children = append(t.Current.Children, state)
+	t.Current.Next = state
+	t.Current = state
+	t.States = append(t.States, state)
+	t.prune(max)
+}
+
+// prune moves the root towards the current state until no more than max
+// states are left besides it. The branches that don't lead to the current
+// state go with the old root.
+func (t *UndoTree) prune(max int) {
+	for len(t.States)-1 > max && t.Current != t.Root {
+		next := t.Current
+		for next.Parent != t.Root {
+			next = next.Parent
+		}
+		dropped := map[*UndoState]bool{}
+		stack := []*UndoState{t.Root}
+		for len(stack) > 0 {
+			s := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			dropped[s] = true
+			for _, child := range s.Children {
+				if child != next {
+					stack = append(stack, child)
+				}
+			}
+		}
+
+		next.Parent = nil
+		next.Action = EditorAction{}
+		t.Root = next
+		kept := make([]*UndoState, 0, len(t.States))
+		for _, s := range t.States {
+			if !dropped[s] {
+				kept = append(kept, s)
+			}
+		}
+		t.States = kept
+	}
+}
+
+// index is where the current state is in States.
+func (t *UndoTree) index() int {
+	for i, s := range t.States {
+		if s == t.Current {
+			return i
+		}
+	}
+	return 0
+}
+
+// BeginUndoGroup starts collecting the edits that follow into one undo step,
+// until EndUndoGroup. A group already open is kept.
+func (b *Buffer) BeginUndoGroup() {
+	if b.UndoGroup == nil {
+		b.UndoGroup = &EditorAction{}
+	}
+}
+
+// EndUndoGroup makes the edits collected since BeginUndoGroup a new undo
+// state, keeping at most maxUndoHistory of them.
+func (b *Buffer) EndUndoGroup(maxUndoHistory int) {
+	group := b.UndoGroup
+	b.UndoGroup = nil
+	if group != nil && len(group.Edits) > 0 {
+		b.UndoTree.add(*group, maxUndoHistory)
+	}
+}
+
+// MarkSaved records the current state as the text on disk, so undoing back to
+// it leaves the buffer unmodified.
+func (b *Buffer) MarkSaved(maxUndoHistory int) {
+	b.EndUndoGroup(maxUndoHistory)
+	b.SavedSeq = b.UndoTree.Current.Seq
+	b.Dirty = 0
+}
+
+// ForgetSaved records that no state holds the text on disk, after a change
+// undo doesn't know about.
+func (b *Buffer) ForgetSaved() {
+	b.SavedSeq = -1
+}
+
+// IsSaved reports whether the current state is the text on disk.
+func (b *Buffer) IsSaved() bool {
+	return b.UndoGroup == nil && b.UndoTree.Current.Seq == b.SavedSeq
+}
+
+// Undo goes back to the state before the current one. It returns where the
+// text changed from and where the cursor goes, with false when there was
+// nothing to undo.
+func (b *Buffer) Undo(maxUndoHistory int) (Point, Point, bool) {
+	b.EndUndoGroup(maxUndoHistory)
+	return b.UndoGoTo(b.UndoTree.Current.Parent)
+}
+
+// Redo goes forward to the state last undone, or last made, from the current
+// one. It returns the same as Undo.
+func (b *Buffer) Redo(maxUndoHistory int) (Point, Point, bool) {
+	b.EndUndoGroup(maxUndoHistory)
+	return b.UndoGoTo(b.UndoTree.Current.Next)
+}
+
+// UndoSteps goes count states later in the order they were made, or earlier
+// for a negative count, whatever branch they're on.
+func (b *Buffer) UndoSteps(count int, maxUndoHistory int) (Point, Point, bool) {
+	b.EndUndoGroup(maxUndoHistory)
+	t := b.UndoTree
+	i := utils.Max(utils.Min(t.index()+count, len(t.States)-1), 0)
+	return b.UndoGoTo(t.States[i])
+}
+
+// UndoTime goes to the last state made by d after the current one was, or
+// before it for a negative d. Going back further than the oldest state ends
+// up there.
+func (b *Buffer) UndoTime(d time.Duration, maxUndoHistory int) (Point, Point, bool) {
+	b.EndUndoGroup(maxUndoHistory)
+	t := b.UndoTree
+	at := t.Current.Time.Add(d)
+	target := t.Root
+	for _, s := range t.States {
+		if s.Time.After(at) {
+			break
+		}
+		target = s
+	}
+	return b.UndoGoTo(target)
+}
+
+// UndoGoTo changes the text to the state target, undoing up the tree to where
+// its branch starts and redoing down to it. It returns the same as Undo.
+func (b *Buffer) UndoGoTo(target *UndoState) (Point, Point, bool) {
+	t := b.UndoTree
+	if target == nil || target == t.Current {
+		return Point{}, Point{}, false
+	}
+	onPath := map[*UndoState]bool{}
+	for s := target; s != nil; s = s.Parent {
+		onPath[s] = true
+	}
+
+	first, cursor := b.Text.Len(), 0
+	for !onPath[t.Current] {
+		s := t.Current
+		for i := len(s.Action.Edits) - 1; i >= 0; i-- {
+			edit := s.Action.Edits[i]
+			b.applyEdit(edit.Offset, edit.Inserted, edit.Removed)
+			first = utils.Min(first, edit.Offset)
+		}
+		cursor = s.Action.Edits[0].Offset
+		s.Parent.Next = s
+		t.Current = s.Parent
+	}
+
+	var path []*UndoState
+	for s := target; s != t.Current; s = s.Parent {
+		path = append(path, s)
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		s := path[i]
+		for _, edit := range s.Action.Edits {
+			b.applyEdit(edit.Offset, edit.Removed, edit.Inserted)
+			first = utils.Min(first, edit.Offset)
+		}
+		cursor = s.Action.Edits[0].Offset
+		s.Parent.Next = s
+		t.Current = s
+	}
+	return b.OffsetToPoint(first), b.OffsetToPoint(cursor), true
+}
+
+// undoTreeJSON is how an UndoTree is written out, with the states referring
+// to each other by Seq.
+type undoTreeJSON struct {
+	Seq     int
+	Current int
+	States  []undoStateJSON
+}
+
+type undoStateJSON struct {
+	Seq int
+	// Parent and Next are -1 for none
+	Parent int
+	Next   int
+	Time   time.Time
+	Edits  []Edit
+}
+
+func (t *UndoTree) MarshalJSON() ([]byte, error) {
+	out := undoTreeJSON{Seq: t.seq, Current: t.Current.Seq}
+	for _, s := range t.States {
+		state := undoStateJSON{Seq: s.Seq, Parent: -1, Next: -1, Time: s.Time, Edits: s.Action.Edits}
+		if s.Parent != nil {
+			state.Parent = s.Parent.Seq
+		}
+		if s.Next != nil {
+			state.Next = s.Next.Seq
+		}
+		out.States = append(out.States, state)
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON reads a tree written by MarshalJSON. It fails unless the
+// states make up a tree, use Check to see whether their edits fit a text.
+func (t *UndoTree) UnmarshalJSON(data []byte) error {
+	var in undoTreeJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if len(in.States) == 0 || in.States[0].Parent != -1 {
+		return errors.New("undo tree has no root")
+	}
+
+	bySeq := map[int]*UndoState{}
+	tree := UndoTree{seq: in.Seq}
+	for i, s := range in.States {
+		if _, ok := bySeq[s.Seq]; ok || s.Seq > in.Seq {
+			return fmt.Errorf("undo state %d is out of place", s.Seq)
+		}
+		state := &UndoState{Seq: s.Seq, Time: s.Time, Action: EditorAction{Edits: s.Edits}}
+		if i > 0 {
+			parent, ok := bySeq[s.Parent]
+			if !ok || len(s.Edits) == 0 {
+				return fmt.Errorf("undo state %d is out of place", s.Seq)
+			}
+			state.Parent = parent
+			parent.Children = append(parent.Children, state)
+		}
+		bySeq[s.Seq] = state
+		tree.States = append(tree.States, state)
+	}
+	for _, s := range in.States {
+		if s.Next == -1 {
+			continue
+		}
+		next, ok := bySeq[s.Next]
+		if !ok || next.Parent != bySeq[s.Seq] {
+			return fmt.Errorf("undo state %d is out of place", s.Seq)
+		}
+		bySeq[s.Seq].Next = next
+	}
+
+	tree.Root = tree.States[0]
+	current, ok := bySeq[in.Current]
+	if !ok {
+		return errors.New("undo tree has no current state")
+	}
+	tree.Current = current
+	*t = tree
+	return nil
+}
+
+// Check makes sure the edits of every state fit, starting from text as the
+// text of the current state, so moving around the tree can't go wrong.
+func (t *UndoTree) Check(text []byte) error {
+	return checkState(piecetable.New(text), t.Current, nil)
+}
+
+// checkState checks the states around s, coming from the state from, with
+// text holding the text of s.
+func checkState(text *piecetable.Table, s, from *UndoState) error {
+	var next []*UndoState
+	if s.Parent != nil && s.Parent != from {
+		next = append(next, s.Parent)
+	}
+	for _, child := range s.Children {
+		if child != from {
+			next = append(next, child)
+		}
+	}
+
+	for _, n := range next {
+		// Going up undoes s, going down redoes the child
+		action, forward := n.Action, true
+		if n == s.Parent {
+			action, forward = s.Action, false
+		}
+		if err := checkEdits(text, action, forward); err != nil {
+			return fmt.Errorf("undo state %d: %w", n.Seq, err)
+		}
+		err := checkState(text, n, s)
+		checkEdits(text, action, !forward)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkEdits applies the edits of action to text, or reverts them when not
+// forward, as long as the text they expect to find is there.
+func checkEdits(text *piecetable.Table, action EditorAction, forward bool) error {
+	for i := range action.Edits {
+		edit := action.Edits[i]
+		removed, inserted := edit.Removed, edit.Inserted
+		if !forward {
+			edit = action.Edits[len(action.Edits)-1-i]
+			removed, inserted = edit.Inserted, edit.Removed
+		}
+		end := edit.Offset + len(removed)
+		if edit.Offset < 0 || end > text.Len() || !bytes.Equal(text.Slice(edit.Offset, end), removed) {
+			return errors.New("edit doesn't match the text")
+		}
+		if len(removed) > 0 {
+			text.Delete(edit.Offset, len(removed))
+		}
+		if len(inserted) > 0 {
+			text.Insert(edit.Offset, inserted)
+		}
+	}
+	return nil
+}
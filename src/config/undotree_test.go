@@ -0,0 +1,138 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// branchingBuffer returns a buffer whose undo tree has a branch: two edits,
+// then an undo and another edit.
+func branchingBuffer() *Buffer {
+	b := NewBuffer()
+	b.SetText([]byte("one\ntwo\n"))
+	b.InsertText(Point{Row: 0, Col: 3}, []byte(" more"))
+	b.EndUndoGroup(100)
+	b.DeleteText(Point{Row: 1, Col: 0}, Point{Row: 2, Col: 0})
+	b.InsertText(Point{Row: 0, Col: 0}, []byte("first\n"))
+	b.EndUndoGroup(100)
+	b.Undo(100)
+	b.ReplaceText(Point{Row: 1, Col: 0}, Point{Row: 1, Col: 3}, []byte("2"))
+	b.EndUndoGroup(100)
+	return b
+}
+
+func TestUndoTreeJSONRoundTrip(t *testing.T) {
+	b := branchingBuffer()
+	data, err := json.Marshal(b.UndoTree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tree UndoTree
+	if err := json.Unmarshal(data, &tree); err != nil {
+		t.Fatal(err)
+	}
+
+	if tree.seq != b.UndoTree.seq || len(tree.States) != len(b.UndoTree.States) {
+		t.Fatalf("read %d states up to %d, want %d up to %d", len(tree.States), tree.seq, len(b.UndoTree.States), b.UndoTree.seq)
+	}
+	if tree.Root != tree.States[0] || tree.Current.Seq != b.UndoTree.Current.Seq {
+		t.Errorf("root %d and current %d, want %d and %d", tree.Root.Seq, tree.Current.Seq, b.UndoTree.Root.Seq, b.UndoTree.Current.Seq)
+	}
+	seq := func(s *UndoState) int {
+		if s == nil {
+			return -1
+		}
+		return s.Seq
+	}
+	for i, want := range b.UndoTree.States {
+		got := tree.States[i]
+		if got.Seq != want.Seq || seq(got.Parent) != seq(want.Parent) || seq(got.Next) != seq(want.Next) || len(got.Children) != len(want.Children) {
+			t.Errorf("state %d read as seq %d, parent %d, next %d, %d children", want.Seq, got.Seq, seq(got.Parent), seq(got.Next), len(got.Children))
+		}
+		if !got.Time.Equal(want.Time) || !reflect.DeepEqual(got.Action.Edits, want.Action.Edits) {
+			t.Errorf("state %d read with other edits or time", want.Seq)
+		}
+	}
+	if err := tree.Check(b.Text.Bytes()); err != nil {
+		t.Errorf("Check of the text it was written with: %s", err)
+	}
+
+	// Going to every state of the tree that was read gives the same text as
+	// the tree it was written from
+	read := NewBuffer()
+	read.SetText(b.Text.Bytes())
+	read.UndoTree = &tree
+	for i := range b.UndoTree.States {
+		b.UndoGoTo(b.UndoTree.States[i])
+		read.UndoGoTo(tree.States[i])
+		if got, want := string(read.Text.Bytes()), string(b.Text.Bytes()); got != want {
+			t.Errorf("state %d has text %q, want %q", tree.States[i].Seq, got, want)
+		}
+	}
+}
+
+func TestUndoTreeUnmarshalErrors(t *testing.T) {
+	edits := `[{"Offset":0,"Removed":null,"Inserted":"eA=="}]`
+	state := func(seq, parent, next int, edits string) string {
+		return fmt.Sprintf(`{"Seq":%d,"Parent":%d,"Next":%d,"Time":"2024-01-01T00:00:00Z","Edits":%s}`, seq, parent, next, edits)
+	}
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"no states", `{"Seq":0,"Current":0,"States":[]}`},
+		{"root with a parent", `{"Seq":1,"Current":1,"States":[` + state(1, 0, -1, "null") + `]}`},
+		{"unknown parent", `{"Seq":2,"Current":0,"States":[` + state(0, -1, -1, "null") + `,` + state(1, 2, -1, edits) + `]}`},
+		{"repeated state", `{"Seq":1,"Current":0,"States":[` + state(0, -1, -1, "null") + `,` + state(0, 0, -1, edits) + `]}`},
+		{"state past seq", `{"Seq":1,"Current":0,"States":[` + state(0, -1, -1, "null") + `,` + state(2, 0, -1, edits) + `]}`},
+		{"state without edits", `{"Seq":1,"Current":0,"States":[` + state(0, -1, -1, "null") + `,` + state(1, 0, -1, "[]") + `]}`},
+		{"next that isn't a child", `{"Seq":2,"Current":0,"States":[` + state(0, -1, 2, "null") + `,` + state(1, 0, 2, edits) + `,` + state(2, 1, -1, edits) + `]}`},
+		{"unknown current", `{"Seq":1,"Current":5,"States":[` + state(0, -1, 1, "null") + `,` + state(1, 0, -1, edits) + `]}`},
+		{"not json", `{"Seq":`},
+	}
+	for _, tt := range tests {
+		var tree UndoTree
+		if err := json.Unmarshal([]byte(tt.json), &tree); err == nil {
+			t.Errorf("%s: read without an error", tt.name)
+		}
+	}
+}
+
+func TestUndoTreeCheck(t *testing.T) {
+	b := branchingBuffer()
+	text := b.Text.Bytes()
+	tests := []struct {
+		name string
+		text string
+		edit func(tree *UndoTree)
+		ok   bool
+	}{
+		{"matching text", string(text), func(*UndoTree) {}, true},
+		{"other text", "one\n2\n", func(*UndoTree) {}, false},
+		{"text a redo doesn't fit", string(text), func(tree *UndoTree) {
+			tree.States[2].Action.Edits[0].Removed = []byte("nothing like it")
+		}, false},
+		{"text an undo doesn't fit", string(text), func(tree *UndoTree) {
+			tree.Current.Action.Edits[0].Inserted = []byte("3")
+		}, false},
+		{"edit past the end", string(text), func(tree *UndoTree) {
+			tree.States[1].Action.Edits[0].Offset = 1000
+		}, false},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(b.UndoTree)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tree UndoTree
+		if err := json.Unmarshal(data, &tree); err != nil {
+			t.Fatal(err)
+		}
+		tt.edit(&tree)
+		if err := tree.Check([]byte(tt.text)); (err == nil) != tt.ok {
+			t.Errorf("%s: Check returned %v", tt.name, err)
+		}
+	}
+}
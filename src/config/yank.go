@@ -1,15 +0,0 @@
-package config
-
-type YankType int
-
-const (
-	EMPTY_YANK YankType = iota
-	LineWise
-	CharWise
-	BlockWise
-)
-
-type Yank struct {
-	PartialBuffer Buffer
-	Type          YankType
-}
@@ -1,5 +1,7 @@
 package constants
 
+import "time"
+
 /** CONSTS **/
 const VERSION = "0.0.1"
 
@@ -19,14 +21,7 @@ const (
 	EDITOR_MODE_INSERT
 	EDITOR_MODE_VISUAL
 	EDITOR_MODE_FILE_BROWSER
-)
-
-const (
-	ACTION_DELETE_ROW = iota
-	ACTION_UPDATE_ROW
-	ACTION_INSERT_ROW
-	ACTION_APPEND_ROW_TO_PREVIOUS
-	ACTION_INSERT_CHAR_AT_EOF
+	EDITOR_MODE_COMMAND
 )
 
 const (
@@ -38,27 +33,22 @@ const (
 	STATE_NUMBER
 )
 
+// Refresh hints returned by the key handlers, telling EditorRefreshScreen how
+// much of the screen to redraw
 const (
-	ARROW_LEFT      rune = 1000
-	ARROW_RIGHT     rune = 1001
-	ARROW_UP        rune = 1002
-	ARROW_DOWN      rune = 1003
-	PAGE_UP         rune = 1004
-	PAGE_DOWN       rune = 1005
-	HOME_KEY        rune = 1006
-	END_KEY         rune = 1007
-	DEL_KEY         rune = 1008
-	TAB_KEY         rune = 1009
 	INITIAL_REFRESH rune = 2000
+	RESIZE_REFRESH  rune = 2001
+	PARTIAL_REFRESH rune = 2002
+	LINE_REFRESH    rune = 2003
 	NO_OP           rune = 3000
-	BACKSPACE       rune = 127
-	QUIT_TIMES      int  = 3
-	QUIT_KEY        rune = 'q'
-	SAVE_KEY        rune = 's'
-	ENTER_KEY       rune = '\r'
-	TILDE           rune = '~'
-	SPACE_RUNE      rune = ' '
-	ESCAPE_KEY      rune = 27
+)
+
+const (
+	QUIT_TIMES int  = 3
+	QUIT_KEY   rune = 'q'
+	SAVE_KEY   rune = 's'
+	TILDE      rune = '~'
+	SPACE_RUNE rune = ' '
 )
 
 const (
@@ -69,11 +59,19 @@ const (
 	// MOVE CURSOR TO TOP LEFT
 	ESCAPE_MOVE_TO_HOME_POS = "\x1b[H"
 
-	ESCAPE_MOVE_TO_COORDS = "\x1b[%d;%dH"
-	ESCAPE_SHOW_CURSOR    = "\x1b[?25h"
-	ESCAPE_CLEAR_SCREEN   = "\033[2J"
-	ESCAPE_CURSOR_THIN    = "\x1b[6 q"
-	ESCAPE_CURSOR_THICK   = "\x1b[2 q"
+	ESCAPE_MOVE_TO_COORDS   = "\x1b[%d;%dH"
+	ESCAPE_SHOW_CURSOR      = "\x1b[?25h"
+	ESCAPE_CLEAR_SCREEN     = "\033[2J"
+	ESCAPE_CURSOR_THIN      = "\x1b[6 q"
+	ESCAPE_CURSOR_THICK     = "\x1b[2 q"
+	ESCAPE_CURSOR_DEFAULT   = "\x1b[0 q"
+	ESCAPE_ENTER_ALT_SCREEN = "\x1b[?1049h"
+	ESCAPE_LEAVE_ALT_SCREEN = "\x1b[?1049l"
+	ESCAPE_ENABLE_PASTE     = "\x1b[?2004h"
+	ESCAPE_DISABLE_PASTE    = "\x1b[?2004l"
+	// Report presses, releases and drags in the SGR encoding
+	ESCAPE_ENABLE_MOUSE  = "\x1b[?1002h\x1b[?1006h"
+	ESCAPE_DISABLE_MOUSE = "\x1b[?1002l\x1b[?1006l"
 )
 
 const (
@@ -114,8 +112,23 @@ const (
 	BACKGROUND_RESET        = "\x1b[49m"
 )
 
+const STATUS_MESSAGE_TIMEOUT = 5 * time.Second
+
+// TAB_STOP is the indent width used when nothing more specific is known
+// about a file.
 const TAB_STOP = 2
 
+const (
+	INDENT_STYLE_SPACE = "space"
+	INDENT_STYLE_TAB   = "tab"
+)
+
+const (
+	END_OF_LINE_LF   = "lf"
+	END_OF_LINE_CRLF = "crlf"
+	END_OF_LINE_CR   = "cr"
+)
+
 const (
 	HL_NORMAL = iota
 	HL_SELECTED
@@ -0,0 +1,72 @@
+package constants
+
+var PythonSyntaxHighlighting map[string]byte = map[string]byte{
+	// Control Flow
+	"if":       CategoryConstants["controlFlow"],
+	"elif":     CategoryConstants["controlFlow"],
+	"else":     CategoryConstants["controlFlow"],
+	"for":      CategoryConstants["controlFlow"],
+	"while":    CategoryConstants["controlFlow"],
+	"break":    CategoryConstants["controlFlow"],
+	"continue": CategoryConstants["controlFlow"],
+	"pass":     CategoryConstants["controlFlow"],
+	"match":    CategoryConstants["controlFlow"],
+	"case":     CategoryConstants["controlFlow"],
+	"with":     CategoryConstants["controlFlow"],
+
+	// Exceptions
+	"try":     CategoryConstants["exception"],
+	"except":  CategoryConstants["exception"],
+	"finally": CategoryConstants["exception"],
+	"raise":   CategoryConstants["exception"],
+
+	// Functions and Classes
+	"def":    CategoryConstants["function"],
+	"return": CategoryConstants["function"],
+	"yield":  CategoryConstants["function"],
+	"lambda": CategoryConstants["function"],
+	"async":  CategoryConstants["function"],
+	"await":  CategoryConstants["function"],
+	"class":  CategoryConstants["type"],
+
+	// Variables
+	"global":   CategoryConstants["variable"],
+	"nonlocal": CategoryConstants["variable"],
+	"del":      CategoryConstants["variable"],
+
+	// Types
+	"int":   CategoryConstants["type"],
+	"float": CategoryConstants["type"],
+	"str":   CategoryConstants["type"],
+	"bool":  CategoryConstants["type"],
+	"list":  CategoryConstants["type"],
+	"dict":  CategoryConstants["type"],
+	"set":   CategoryConstants["type"],
+	"tuple": CategoryConstants["type"],
+
+	// Operators
+	"and": CategoryConstants["operator"],
+	"or":  CategoryConstants["operator"],
+	"not": CategoryConstants["operator"],
+	"in":  CategoryConstants["operator"],
+	"is":  CategoryConstants["operator"],
+
+	// Boolean Literals
+	"True":  CategoryConstants["boolean"],
+	"False": CategoryConstants["boolean"],
+	"None":  CategoryConstants["constant"],
+
+	// Modules
+	"import": CategoryConstants["module"],
+	"from":   CategoryConstants["module"],
+	"as":     CategoryConstants["module"],
+
+	// Built-in Functions
+	"print": CategoryConstants["builtin"],
+	"len":   CategoryConstants["builtin"],
+	"range": CategoryConstants["builtin"],
+	"self":  CategoryConstants["builtin"],
+
+	// Debugging
+	"assert": CategoryConstants["debug"],
+}
@@ -31,6 +31,8 @@ type SyntaxHighlighting struct {
 	MultiLineCommentEnd    string
 	Flags                  int
 	Syntax                 map[string]byte
+	IndentStyle            string
+	IndentSize             int
 }
 
 var Syntaxes = []SyntaxHighlighting{
@@ -42,6 +44,8 @@ var Syntaxes = []SyntaxHighlighting{
 		MultiLineCommentEnd:    "*/",
 		Flags:                  HL_HIGHLIGHT_NUMBERS | HL_HIGHLIGHT_STRINGS,
 		Syntax:                 GoSyntaxHighlighting,
+		IndentStyle:            INDENT_STYLE_TAB,
+		IndentSize:             4,
 	},
 	{
 		FileType:               "typescript",
@@ -51,6 +55,8 @@ var Syntaxes = []SyntaxHighlighting{
 		MultiLineCommentEnd:    "*/",
 		Flags:                  HL_HIGHLIGHT_NUMBERS | HL_HIGHLIGHT_STRINGS,
 		Syntax:                 TypeScriptSyntaxHighlighting,
+		IndentStyle:            INDENT_STYLE_SPACE,
+		IndentSize:             2,
 	},
 	// {
 	// 	FileType:               "rust",
@@ -69,6 +75,17 @@ var Syntaxes = []SyntaxHighlighting{
 		MultiLineCommentEnd:    "*/",
 		Flags:                  HL_HIGHLIGHT_NUMBERS | HL_HIGHLIGHT_STRINGS,
 		Syntax:                 JavaScriptSyntaxHighlighting,
+		IndentStyle:            INDENT_STYLE_SPACE,
+		IndentSize:             2,
+	},
+	{
+		FileType:               "python",
+		FileMatch:              []string{".py"},
+		SingleLineCommentStart: "#",
+		Flags:                  HL_HIGHLIGHT_NUMBERS | HL_HIGHLIGHT_STRINGS,
+		Syntax:                 PythonSyntaxHighlighting,
+		IndentStyle:            INDENT_STYLE_SPACE,
+		IndentSize:             4,
 	},
 }
 
@@ -3,55 +3,63 @@ package core
 import (
 	"github.com/deanrtaylor1/go-editor/config"
 	"github.com/deanrtaylor1/go-editor/constants"
-	"github.com/deanrtaylor1/go-editor/utils"
+	"github.com/deanrtaylor1/go-editor/keys"
 )
 
-func FileBrowserEventsHandler(char rune, e *config.Editor) rune {
-	switch char {
-	case 'R':
+func FileBrowserEventsHandler(key keys.Key, e *config.Editor) rune {
+	switch key {
+	case keys.Rune('R'):
 		if e.IsDir() {
 			return constants.NO_OP
 		}
 		EditorRename(e)
-		ReadHandler(e, e.CurrentDirectory)
+		if err := ReadHandler(e, e.CurrentDirectory); err != nil {
+			EditorSetStatusMessage(e, "%s", err.Error())
+		}
 		return constants.INITIAL_REFRESH
-	case '%':
+	case keys.Rune('%'):
 		EditorCreate(e)
-		ReadHandler(e, e.CurrentDirectory)
+		if err := ReadHandler(e, e.CurrentDirectory); err != nil {
+			EditorSetStatusMessage(e, "%s", err.Error())
+		}
 		return constants.INITIAL_REFRESH
-	case utils.CTRL_KEY('l'), constants.ESCAPE_KEY:
+	case keys.Ctrl('l'), keys.Escape:
 		e.ClearMotionBuffer()
 		return constants.INITIAL_REFRESH
-	case 'D':
+	case keys.Rune('D'):
 		EditorDelete(e)
-		ReadHandler(e, e.CurrentDirectory)
+		if err := ReadHandler(e, e.CurrentDirectory); err != nil {
+			EditorSetStatusMessage(e, "%s", err.Error())
+		}
 		return constants.INITIAL_REFRESH
-	case 'j', constants.ARROW_DOWN:
-		EditorMoveCursor(constants.ARROW_DOWN, e)
-		return constants.ARROW_DOWN
-	case 'k', constants.ARROW_UP:
-		EditorMoveCursor(constants.ARROW_UP, e)
-		return constants.ARROW_UP
-	case 'l', constants.ARROW_LEFT:
-		EditorMoveCursor(constants.ARROW_RIGHT, e)
-		return constants.ARROW_RIGHT
-	case 'h', constants.ARROW_RIGHT:
-		EditorMoveCursor(constants.ARROW_LEFT, e)
-		return constants.ARROW_LEFT
-	case constants.ENTER_KEY:
-		ReadHandler(e, e.FileBrowserItems[e.Cy-len(e.InstructionsLines())].Path)
+	case keys.Rune('j'), keys.Down:
+		EditorMoveCursor(keys.Down, e)
+		return constants.PARTIAL_REFRESH
+	case keys.Rune('k'), keys.Up:
+		EditorMoveCursor(keys.Up, e)
+		return constants.PARTIAL_REFRESH
+	case keys.Rune('l'), keys.Left:
+		EditorMoveCursor(keys.Right, e)
+		return constants.PARTIAL_REFRESH
+	case keys.Rune('h'), keys.Right:
+		EditorMoveCursor(keys.Left, e)
+		return constants.PARTIAL_REFRESH
+	case keys.Enter:
+		if err := ReadHandler(e, e.FileBrowserItems[e.Cy-len(e.InstructionsLines())].Path); err != nil {
+			EditorSetStatusMessage(e, "%s", err.Error())
+		}
 		return constants.INITIAL_REFRESH
-	case utils.CTRL_KEY(constants.QUIT_KEY):
+	case keys.Ctrl(constants.QUIT_KEY):
 		success := QuitKeyHandler(e)
 		if !success {
-			return char
+			return RefreshFor(key)
 		}
-	case constants.HOME_KEY:
+	case keys.Home:
 		HomeKeyHandler(e)
-	case constants.BACKSPACE, utils.CTRL_KEY('h'), constants.DEL_KEY:
-		DeleteHandler(e, char)
+	case keys.Backspace, keys.Ctrl('h'), keys.Delete:
+		DeleteHandler(e, key)
 	default:
 		return constants.NO_OP
 	}
-	return char
+	return RefreshFor(key)
 }
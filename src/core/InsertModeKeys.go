@@ -3,61 +3,63 @@ package core
 import (
 	"github.com/deanrtaylor1/go-editor/config"
 	"github.com/deanrtaylor1/go-editor/constants"
-	"github.com/deanrtaylor1/go-editor/utils"
+	"github.com/deanrtaylor1/go-editor/keys"
 )
 
-func InsertModeEventsHandler(char rune, e *config.Editor) {
-	clearRedos := true
+func InsertModeEventsHandler(key keys.Key, e *config.Editor) {
+	defer trackInsertKey(key, e)()
+	defer trackInsertedText(key, e)()
 
-	switch char {
-	case utils.CTRL_KEY('z'):
+	switch key {
+	case keys.Ctrl('z'):
 		UndoAction(e)
-		clearRedos = false
-	case utils.CTRL_KEY('y'):
+	case keys.Ctrl('y'):
 		RedoAction(e)
-		clearRedos = false
-	case constants.TAB_KEY:
+	case keys.Tab:
 		TabKeyHandler(e)
-	case constants.ENTER_KEY:
+	case keys.Enter:
 		EnterKeyHandler(e)
-	case utils.CTRL_KEY(constants.QUIT_KEY):
+	case keys.Ctrl(constants.QUIT_KEY):
 		success := QuitKeyHandler(e)
 		if !success {
 			return
 		}
-	case utils.CTRL_KEY(constants.SAVE_KEY):
+	case keys.Ctrl(constants.SAVE_KEY):
 		SaveKeyHandler(e)
-	case constants.HOME_KEY:
+	case keys.Home:
 		HomeKeyHandler(e)
-	case constants.END_KEY:
+	case keys.End:
 		err := EndKeyHandler(e)
 		if err != nil {
 			config.LogToFile(err.Error())
 			break
 		}
-	case utils.CTRL_KEY('f'):
+	case keys.Ctrl('f'):
 		EditorFind(e)
-		clearRedos = false
-	case constants.BACKSPACE, utils.CTRL_KEY('h'), constants.DEL_KEY:
-		DeleteHandler(e, char)
-	case constants.PAGE_DOWN, constants.PAGE_UP:
-		PageJumpHandler(e, char)
-		clearRedos = false
-	case utils.CTRL_KEY('l'), constants.ESCAPE_KEY:
+	case keys.Backspace, keys.Ctrl('h'), keys.Delete:
+		DeleteHandler(e, key)
+	case keys.PageDown, keys.PageUp:
+		PageJumpHandler(e, key)
+	case keys.Ctrl('l'), keys.Escape:
 		e.SetMode(constants.EDITOR_MODE_NORMAL)
-	case rune(constants.ARROW_DOWN), rune(constants.ARROW_UP), rune(constants.ARROW_RIGHT), rune(constants.ARROW_LEFT):
-		EditorMoveCursor(char, e)
-		clearRedos = false
+	case keys.Down, keys.Up, keys.Right, keys.Left:
+		EditorMoveCursor(key, e)
 	default:
+		if key.Code == keys.CodePaste {
+			PasteHandler(e, key.Text)
+			break
+		}
+		// Keys without a binding, F-keys or Alt combinations, don't type
+		// anything
+		if !key.Printable() {
+			break
+		}
+		char := key.Rune
 		if IsClosingBracket(char) && e.GetCurrentRow().Length > e.CurrentBuffer.SliceIndex && IsClosingBracket(rune(e.GetCurrentRow().Chars[e.CurrentBuffer.SliceIndex])) {
-			e.Cx++
-			e.CurrentBuffer.SliceIndex++
+			e.MoveCursorRight()
 		} else {
 			InsertCharHandler(e, char)
 		}
 	}
-	if clearRedos {
-		e.ClearRedoStack()
-	}
 	e.QuitTimes = constants.QUIT_TIMES
 }
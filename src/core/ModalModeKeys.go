@@ -8,12 +8,19 @@ import (
 	"github.com/deanrtaylor1/go-editor/constants"
 	"github.com/deanrtaylor1/go-editor/fuzzy"
 	"github.com/deanrtaylor1/go-editor/grep"
-	"github.com/deanrtaylor1/go-editor/utils"
+	"github.com/deanrtaylor1/go-editor/keys"
 )
 
-func ModalModeEventsHandler(char rune, e *config.Editor) rune {
-	switch char {
-	case constants.ENTER_KEY:
+func ModalModeEventsHandler(key keys.Key, e *config.Editor) rune {
+	switch e.Modal.Type {
+	case config.MODAL_TYPE_VIEW, config.MODAL_TYPE_UNDO_TREE:
+		if key != keys.Up && key != keys.Down {
+			return listModalKey(key, e)
+		}
+	}
+
+	switch key {
+	case keys.Enter:
 		e.CacheCursorCoords()
 		e.ResetCursorCoords()
 
@@ -48,42 +55,44 @@ func ModalModeEventsHandler(char rune, e *config.Editor) rune {
 		e.Modal.ModalInput = []byte{}
 		e.ModalOpen = false
 		e.Modal.ModalDrawn = false
-		ReadHandler(e, fullPath)
+		if err := ReadHandler(e, fullPath); err != nil {
+			EditorSetStatusMessage(e, "%s", err.Error())
+		}
 		return constants.INITIAL_REFRESH
 
-	case constants.ESCAPE_KEY:
+	case keys.Escape:
 		e.ModalOpen = false
 		e.Modal.ModalDrawn = false
-	case constants.ARROW_DOWN:
+	case keys.Down:
 		switch e.Modal.Type {
 		case config.MODAL_TYPE_FUZZY:
 			data, ok := e.Modal.Data.(fuzzy.Matches)
 			if ok && e.Modal.ItemIndex < len(data)-1 {
 				e.Modal.ItemIndex++
-				return constants.ARROW_DOWN
+				return constants.PARTIAL_REFRESH
 			}
 		default:
 			data, ok := e.Modal.Data.([]string)
 			if ok && e.Modal.ItemIndex < len(data)-1 {
 				e.Modal.ItemIndex++
-				return constants.ARROW_DOWN
+				return constants.PARTIAL_REFRESH
 			}
 		}
 		return constants.NO_OP
-	case constants.ARROW_UP:
+	case keys.Up:
 		if e.Modal.ItemIndex <= 0 {
 			return constants.NO_OP
 		}
 		e.Modal.ItemIndex--
-		return constants.ARROW_UP
-	case constants.ARROW_RIGHT:
-		EditorMoveCursor(constants.ARROW_RIGHT, e)
-		return constants.ARROW_RIGHT
-	case constants.ARROW_LEFT:
-		EditorMoveCursor(constants.ARROW_LEFT, e)
-		return constants.ARROW_LEFT
-	case constants.BACKSPACE, utils.CTRL_KEY('h'), constants.DEL_KEY:
-		DeleteHandler(e, char)
+		return constants.PARTIAL_REFRESH
+	case keys.Right:
+		EditorMoveCursor(keys.Right, e)
+		return constants.PARTIAL_REFRESH
+	case keys.Left:
+		EditorMoveCursor(keys.Left, e)
+		return constants.PARTIAL_REFRESH
+	case keys.Backspace, keys.Ctrl('h'), keys.Delete:
+		DeleteHandler(e, key)
 		e.Modal.ResetToFirstItem()
 		switch e.Modal.Type {
 		case config.MODAL_TYPE_FUZZY:
@@ -101,25 +110,49 @@ func ModalModeEventsHandler(char rune, e *config.Editor) rune {
 		}
 
 	default:
-		insertCharModalInput(char, e)
+		if !key.Printable() {
+			return constants.NO_OP
+		}
+		insertCharModalInput(key.Rune, e)
 		e.Modal.ResetToFirstItem()
 		updateResults(e)
 	}
 	return constants.INITIAL_REFRESH
 }
 
+// listModalKey handles the modals that only list things, which Enter and
+// Escape close. Enter in the undo tree goes to the chosen state first.
+func listModalKey(key keys.Key, e *config.Editor) rune {
+	if key != keys.Enter && key != keys.Escape {
+		return constants.NO_OP
+	}
+	e.ModalOpen = false
+	e.Modal.ModalDrawn = false
+	if key == keys.Enter && e.Modal.Type == config.MODAL_TYPE_UNDO_TREE {
+		UndoToListed(e, e.Modal.ItemIndex)
+	}
+	return constants.INITIAL_REFRESH
+}
+
 func updateResults(e *config.Editor) {
 	switch e.Modal.Type {
 	case config.MODAL_TYPE_FUZZY:
 		matches := fuzzy.FindFrom(string(e.Modal.ModalInput), &e.Modal)
 		e.Modal.Results = matches
 	default:
-		data, err := grep.RunGrep(string(e.Modal.ModalInput), e.RootDirectory)
-		if err != nil {
-			return
-		}
-		e.Modal.Data = data
-		matches := fuzzy.FindFrom(string(e.Modal.ModalInput), &e.Modal)
-		e.Modal.Results = matches
+		// Grep runs in the background and the results are dropped if the
+		// query changed in the meantime
+		query := string(e.Modal.ModalInput)
+		root := e.RootDirectory
+		go func() {
+			data, err := grep.RunGrep(query, root)
+			e.Post(func(e *config.Editor) {
+				if err != nil || !e.ModalOpen || e.Modal.Type != config.MODAL_TYPE_GENERIC || string(e.Modal.ModalInput) != query {
+					return
+				}
+				e.Modal.Data = data
+				e.Modal.Results = fuzzy.FindFrom(query, &e.Modal)
+			})
+		}()
 	}
 }
@@ -3,109 +3,102 @@ package core
 import (
 	"github.com/deanrtaylor1/go-editor/config"
 	"github.com/deanrtaylor1/go-editor/constants"
-	"github.com/deanrtaylor1/go-editor/utils"
+	"github.com/deanrtaylor1/go-editor/keys"
 )
 
-func NormalModeEventsHandler(char rune, e *config.Editor) rune {
-	if len(e.MotionBuffer) > 0 || utils.IsValidStartingChar(char, e.EditorMode) {
-		e.MotionBuffer = append(e.MotionBuffer, char)
+func NormalModeEventsHandler(key keys.Key, e *config.Editor) rune {
+	if key == keys.Rune('.') {
+		// Only a count may come before ., which goes in place of the
+		// change's own
+		if count, rest := splitCount(string(e.MotionBuffer)); rest == "" {
+			e.ClearMotionBuffer()
+			return RepeatLastChange(e, count)
+		}
 	}
+	defer trackNormalKey(key, e)()
 
-	if len(e.MotionBuffer) > 4 {
-		e.ClearMotionBuffer()
+	if refresh, handled := RegisterKeyHandler(key, e); handled {
+		return refresh
+	}
+	defer forgetRegister(e)
+	if refresh, handled := MacroKeyHandler(key, e); handled {
+		return refresh
+	}
+	if refresh, handled := MarkKeyHandler(key, e); handled {
+		return refresh
+	}
+	if refresh, handled := OperatorKeyHandler(key, e); handled {
+		return refresh
+	}
+	if refresh, handled := MotionKeyHandler(key, e, e.MotionMap); handled {
+		return refresh
 	}
 
-	if len(e.MotionBuffer) > 1 {
-		success := e.ExecuteMotion(string(e.MotionBuffer))
-		if success {
-			e.ClearMotionBuffer()
-			return constants.INITIAL_REFRESH
+	switch key {
+	case keys.Rune(':'):
+		OpenCommandLine(e, "")
+		return constants.INITIAL_REFRESH
+	case keys.Rune('V'):
+		e.EditorMode = constants.EDITOR_MODE_VISUAL
+		e.ClearMotionBuffer()
+		e.HighlightLine()
+		err := EndKeyHandler(e)
+		if err != nil {
+			config.LogToFile(err.Error())
+			break
 		}
-	} else {
-		switch char {
-		case ':':
-			e.EditorMode = constants.EDITOR_MODE_FILE_BROWSER
-			e.CurrentBuffer.StoredCx = e.Cx
-			e.CurrentBuffer.StoredCy = e.Cy
-			e.Cx = 0
-			e.Cy = 0
-			ReadHandler(e, e.RootDirectory)
-			return constants.INITIAL_REFRESH
-		case 'V':
-			e.EditorMode = constants.EDITOR_MODE_VISUAL
-			e.ClearMotionBuffer()
-			e.HighlightLine()
-			err := EndKeyHandler(e)
-			if err != nil {
-				config.LogToFile(err.Error())
-				break
-			}
-		case 'I':
-			e.Cx = e.LineNumberWidth
-			index := 0
-			if e.GetCurrentRow().Chars[index] == ' ' {
-				index++
-				e.Cx++
-			}
-			e.CurrentBuffer.SliceIndex = index
-			e.SetMode(constants.EDITOR_MODE_INSERT)
-		case 'p':
-			PasteYank(e)
-			e.ClearMotionBuffer()
-			return constants.INITIAL_REFRESH
-		case 'v':
-			e.ClearMotionBuffer()
-			e.SetMode(constants.EDITOR_MODE_VISUAL)
-			e.HighlightSelection()
-		case 'i':
-			e.SetMode(constants.EDITOR_MODE_INSERT)
-		case 'j':
-			EditorMoveCursor(constants.ARROW_DOWN, e)
-			return constants.ARROW_DOWN
-		case 'k':
-			EditorMoveCursor(constants.ARROW_UP, e)
-			return constants.ARROW_UP
-		case 'l':
-			EditorMoveCursor(constants.ARROW_RIGHT, e)
-			return constants.ARROW_RIGHT
-		case 'h':
-			EditorMoveCursor(constants.ARROW_LEFT, e)
-			return constants.ARROW_LEFT
-		case 'u':
-			UndoAction(e)
-		case utils.CTRL_KEY('r'):
-			RedoAction(e)
-		case constants.TAB_KEY:
-			for i := 0; i < 4; i++ {
-				EditorMoveCursor(constants.ARROW_RIGHT, e)
-			}
-		case constants.ENTER_KEY:
-			EditorMoveCursor(constants.ARROW_DOWN, e)
-			return constants.ARROW_DOWN
-		case utils.CTRL_KEY(constants.QUIT_KEY):
-			success := QuitKeyHandler(e)
-			if !success {
-				return char
-			}
-		case utils.CTRL_KEY(constants.SAVE_KEY):
-			SaveKeyHandler(e)
-		case constants.HOME_KEY:
-			HomeKeyHandler(e)
-		case constants.END_KEY:
-			err := EndKeyHandler(e)
-			if err != nil {
-				config.LogToFile(err.Error())
-				break
-			}
-		case '/':
-			EditorFind(e)
-			return constants.INITIAL_REFRESH
-		case constants.BACKSPACE, utils.CTRL_KEY('h'), constants.DEL_KEY:
-			DeleteHandler(e, char)
-		case constants.PAGE_DOWN, constants.PAGE_UP:
-			PageJumpHandler(e, char)
+	case keys.Rune('I'):
+		e.Cx = e.LineNumberWidth
+		index := 0
+		if e.GetCurrentRow().Chars[index] == ' ' {
+			index++
+			e.Cx++
+		}
+		e.CurrentBuffer.SliceIndex = index
+		e.SetMode(constants.EDITOR_MODE_INSERT)
+	case keys.Rune('p'):
+		PasteRegister(e, e.SelectedRegister)
+		e.ClearMotionBuffer()
+		return constants.INITIAL_REFRESH
+	case keys.Rune('v'):
+		e.ClearMotionBuffer()
+		e.SetMode(constants.EDITOR_MODE_VISUAL)
+		e.HighlightSelection()
+	case keys.Rune('i'):
+		e.SetMode(constants.EDITOR_MODE_INSERT)
+	case keys.Rune('u'):
+		UndoAction(e)
+	case keys.Ctrl('r'):
+		RedoAction(e)
+	case keys.Tab:
+		for i := 0; i < 4; i++ {
+			EditorMoveCursor(keys.Right, e)
+		}
+	case keys.Enter:
+		EditorMoveCursor(keys.Down, e)
+		return constants.PARTIAL_REFRESH
+	case keys.Ctrl(constants.QUIT_KEY):
+		success := QuitKeyHandler(e)
+		if !success {
+			return RefreshFor(key)
+		}
+	case keys.Ctrl(constants.SAVE_KEY):
+		SaveKeyHandler(e)
+	case keys.Home:
+		HomeKeyHandler(e)
+	case keys.End:
+		err := EndKeyHandler(e)
+		if err != nil {
+			config.LogToFile(err.Error())
+			break
 		}
-		return char
+	case keys.Rune('/'):
+		EditorFind(e)
+		return constants.INITIAL_REFRESH
+	case keys.Backspace, keys.Ctrl('h'), keys.Delete:
+		DeleteHandler(e, key)
+	case keys.PageDown, keys.PageUp:
+		PageJumpHandler(e, key)
 	}
-	return constants.INITIAL_REFRESH
+	return RefreshFor(key)
 }
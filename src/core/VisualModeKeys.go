@@ -3,102 +3,65 @@ package core
 import (
 	"github.com/deanrtaylor1/go-editor/config"
 	"github.com/deanrtaylor1/go-editor/constants"
-	"github.com/deanrtaylor1/go-editor/highlighting"
-	"github.com/deanrtaylor1/go-editor/utils"
+	"github.com/deanrtaylor1/go-editor/keys"
 )
 
-func VisualModeEventsHandler(char rune, e *config.Editor) rune {
-	if len(e.MotionBuffer) > 0 || utils.IsValidStartingChar(char, e.EditorMode) {
-		e.MotionBuffer = append(e.MotionBuffer, char)
+func VisualModeEventsHandler(key keys.Key, e *config.Editor) rune {
+	if refresh, handled := RegisterKeyHandler(key, e); handled {
+		return refresh
 	}
+	defer forgetRegister(e)
 
-	if len(e.MotionBuffer) > 4 {
-		e.ClearMotionBuffer()
+	// Only motions, the mappings are for normal mode
+	if refresh, handled := MotionKeyHandler(key, e, nil); handled {
+		return refresh
 	}
 
-	if len(e.MotionBuffer) > 1 {
-		success := e.ExecuteMotion(string(e.MotionBuffer))
-		if success {
-			e.ClearMotionBuffer()
-			return constants.INITIAL_REFRESH
+	switch key {
+	case keys.Rune('y'), keys.Rune('d'), keys.Rune('c'), keys.Rune('>'), keys.Rune('<'):
+		e.SetMode(constants.EDITOR_MODE_NORMAL)
+		operators[key.Rune](e, selectionTextRange(e))
+		e.ClearSelection()
+		return constants.INITIAL_REFRESH
+	case keys.Rune('p'):
+		e.SetMode(constants.EDITOR_MODE_NORMAL)
+		ReplaceSelection(e, e.SelectedRegister)
+		e.ClearSelection()
+		return constants.INITIAL_REFRESH
+	case keys.Rune(':'):
+		OpenCommandLine(e, "'<,'>")
+		return constants.INITIAL_REFRESH
+	case keys.Rune('V'):
+		e.HighlightLine()
+		err := EndKeyHandler(e)
+		if err != nil {
+			config.LogToFile(err.Error())
+			break
 		}
-	} else {
-		switch char {
-		case 'y':
-			e.YankSelection()
-			e.SetMode(constants.EDITOR_MODE_NORMAL)
-			e.ClearSelection()
-			return constants.INITIAL_REFRESH
-		case 'V':
-			e.HighlightLine()
-			err := EndKeyHandler(e)
-			if err != nil {
-				config.LogToFile(err.Error())
-				break
-			}
-		case 'd':
-			e.DeleteSelection()
-			highlighting.HighlightFileFromRow(e.CurrentBuffer.SelectionStart.Row, e)
-			e.SetMode(constants.EDITOR_MODE_NORMAL)
-			e.ClearSelection()
-			return constants.INITIAL_REFRESH
-		case 'n':
-			e.SetMode(constants.EDITOR_MODE_NORMAL)
-			e.ClearSelection()
-			return constants.INITIAL_REFRESH
-		case 'j':
-			if e.Cy == len(e.CurrentBuffer.Rows)-1 {
-				return constants.NO_OP
-			}
-			EditorMoveCursor(constants.ARROW_DOWN, e)
+	case keys.Rune('n'):
+		e.SetMode(constants.EDITOR_MODE_NORMAL)
+		e.ClearSelection()
+		return constants.INITIAL_REFRESH
+	case keys.Tab:
+		for i := 0; i < 4; i++ {
+			EditorMoveCursor(keys.Right, e)
 			e.MoveSelection()
-			return constants.ARROW_DOWN
-
-		case 'k':
-			if e.Cy == 0 {
-				return constants.NO_OP
-			}
-
-			EditorMoveCursor(constants.ARROW_UP, e)
-			e.MoveSelection()
-			return constants.ARROW_UP
-
-		case 'l':
-			if e.Cx-e.LineNumberWidth < len(e.GetCurrentRow().Chars) {
-				EditorMoveCursor(constants.ARROW_RIGHT, e)
-				e.MoveSelection()
-				return constants.ARROW_RIGHT
-			}
-			return constants.NO_OP
-		case 'h':
-			if e.Cx > 5 {
-				EditorMoveCursor(constants.ARROW_LEFT, e)
-				e.MoveSelection()
-				return constants.ARROW_LEFT
-			}
-			return constants.NO_OP
-		case constants.TAB_KEY:
-			for i := 0; i < 4; i++ {
-				EditorMoveCursor(constants.ARROW_RIGHT, e)
-				e.MoveSelection()
-			}
-		case constants.ENTER_KEY:
-			EditorMoveCursor(constants.ARROW_DOWN, e)
-			return constants.ARROW_DOWN
-		case constants.HOME_KEY:
-			HomeKeyHandler(e)
-		case constants.END_KEY:
-			err := EndKeyHandler(e)
-			if err != nil {
-				config.LogToFile(err.Error())
-				break
-			}
-		case constants.PAGE_DOWN, constants.PAGE_UP:
-			PageJumpHandler(e, char)
-		case constants.ESCAPE_KEY, utils.CTRL_KEY('l'):
-			e.SetMode(constants.EDITOR_MODE_NORMAL)
 		}
-		return char
+	case keys.Enter:
+		EditorMoveCursor(keys.Down, e)
+		return constants.PARTIAL_REFRESH
+	case keys.Home:
+		HomeKeyHandler(e)
+	case keys.End:
+		err := EndKeyHandler(e)
+		if err != nil {
+			config.LogToFile(err.Error())
+			break
+		}
+	case keys.PageDown, keys.PageUp:
+		PageJumpHandler(e, key)
+	case keys.Escape, keys.Ctrl('l'):
+		e.SetMode(constants.EDITOR_MODE_NORMAL)
 	}
-	return constants.INITIAL_REFRESH
+	return RefreshFor(key)
 }
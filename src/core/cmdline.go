@@ -0,0 +1,183 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/constants"
+	"github.com/deanrtaylor1/go-editor/keys"
+	"github.com/deanrtaylor1/go-editor/utils"
+)
+
+// OpenCommandLine switches to command mode with text already typed. The
+// selection of visual mode becomes the marks '< and '>.
+func OpenCommandLine(e *config.Editor, text string) {
+	b := e.CurrentBuffer
+	if e.EditorMode == constants.EDITOR_MODE_VISUAL {
+		b.Marks['<'], b.Marks['>'] = e.GetNormalizedSelection()
+		e.ClearSelection()
+	}
+	e.ClearMotionBuffer()
+	e.CommandLine.Start(text)
+	e.SetMode(constants.EDITOR_MODE_COMMAND)
+	EditorSetStatusMessage(e, "")
+}
+
+// CommandModeEventsHandler edits the command line and runs it on Enter. Up
+// and Down go through the lines run before and Tab completes command names,
+// file names and settings.
+func CommandModeEventsHandler(key keys.Key, e *config.Editor) rune {
+	c := &e.CommandLine
+	if key.Code != keys.CodeTab {
+		c.ResetCompletion()
+	}
+
+	switch {
+	case key == keys.Enter:
+		line := c.String()
+		c.Remember(line)
+		e.SetMode(constants.EDITOR_MODE_NORMAL)
+		if err := RunCommandLine(e, line); err != nil {
+			reportError(e, err)
+		}
+		return constants.INITIAL_REFRESH
+	case key == keys.Escape, key == keys.Ctrl('c'):
+		e.SetMode(constants.EDITOR_MODE_NORMAL)
+	case key == keys.Backspace, key == keys.Ctrl('h'):
+		if len(c.Input) == 0 {
+			e.SetMode(constants.EDITOR_MODE_NORMAL)
+			break
+		}
+		c.DeleteBack()
+	case key == keys.Delete:
+		c.DeleteForward()
+	case key == keys.Ctrl('w'):
+		c.DeleteWord()
+	case key == keys.Ctrl('u'):
+		c.DeleteToStart()
+	case key == keys.Left:
+		c.MoveLeft()
+	case key == keys.Right:
+		c.MoveRight()
+	case key == keys.Home, key == keys.Ctrl('b'):
+		c.Cursor = 0
+	case key == keys.End, key == keys.Ctrl('e'):
+		c.Cursor = len(c.Input)
+	case key == keys.Up:
+		c.HistoryStep(-1)
+	case key == keys.Down:
+		c.HistoryStep(1)
+	case key.Code == keys.CodeTab:
+		step := 1
+		if key.Mod&keys.ModShift != 0 {
+			step = -1
+		}
+		completeCommandLine(e, step)
+	case key.Code == keys.CodePaste:
+		c.Insert(strings.NewReplacer("\r", "", "\n", " ").Replace(key.Text))
+	case key.Printable():
+		c.Insert(string(key.Rune))
+	default:
+		return constants.NO_OP
+	}
+	return constants.LINE_REFRESH
+}
+
+// completeCommandLine completes the word before the cursor: the command name,
+// or the argument of a command that takes a file name or settings.
+func completeCommandLine(e *config.Editor, step int) {
+	c := &e.CommandLine
+	if c.Completions != nil {
+		c.Complete(c.CompletionStart, nil, step)
+		return
+	}
+
+	typed := string(c.Input[:c.Cursor])
+	_, rest, err := parseRange(e, typed)
+	if err != nil {
+		return
+	}
+	rest = strings.TrimLeft(rest, " ")
+	start := len(typed) - len(rest)
+	name, arg := splitCommandName(rest)
+
+	var completions []string
+	if arg == "" {
+		for _, command := range ExCommands {
+			if strings.HasPrefix(command.Name, name) {
+				completions = append(completions, command.Name)
+			}
+		}
+	} else {
+		command, ok := findExCommand(name)
+		if !ok || !strings.HasPrefix(strings.TrimPrefix(arg, "!"), " ") {
+			return
+		}
+		word := strings.TrimLeft(strings.TrimPrefix(arg, "!"), " ")
+		start = len(typed) - len(word)
+		switch command.Arg {
+		case exArgFile:
+			completions = completeFileName(e, word)
+		case exArgSetting:
+			word = word[strings.LastIndex(word, " ")+1:]
+			start = len(typed) - len(word)
+			completions = completeSetting(word)
+		}
+	}
+	if len(completions) == 0 {
+		return
+	}
+	c.Complete(start, completions, step)
+}
+
+// completeFileName lists the files starting with word, directories ending
+// in a slash. Hidden files only show up once a dot is typed.
+func completeFileName(e *config.Editor, word string) []string {
+	dir, prefix := filepath.Split(word)
+	entries, err := os.ReadDir(exPath(e, dir))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		if entry.IsDir() {
+			name += string(filepath.Separator)
+		}
+		names = append(names, dir+name)
+	}
+	return names
+}
+
+// completeSetting lists the settings and buffer options starting with word,
+// which may start with the no of :set noname.
+func completeSetting(word string) []string {
+	var names []string
+	for _, name := range append(config.OptionNames, config.SettingNames...) {
+		switch {
+		case strings.HasPrefix(name, word):
+			names = append(names, name)
+		case strings.HasPrefix("no"+name, word) && len(word) > 2:
+			names = append(names, "no"+name)
+		}
+	}
+	return names
+}
+
+// commandLineView returns as much of the command line as fits the screen,
+// scrolled so the cursor stays in view, and the screen column of the cursor.
+func commandLineView(e *config.Editor) (string, int) {
+	c := &e.CommandLine
+	line := ":" + c.String()
+	cursor := 1 + c.Cursor
+	for utils.StringWidth(line[:cursor]) >= e.ScreenCols && cursor > 1 {
+		next := utils.NextGrapheme([]byte(line), 0)
+		line, cursor = line[next:], cursor-next
+	}
+	return utils.TruncateToWidth(line, e.ScreenCols), utils.StringWidth(line[:cursor])
+}
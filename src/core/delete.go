@@ -4,6 +4,7 @@ import (
 	"github.com/deanrtaylor1/go-editor/config"
 	"github.com/deanrtaylor1/go-editor/constants"
 	"github.com/deanrtaylor1/go-editor/highlighting"
+	"github.com/deanrtaylor1/go-editor/utils"
 )
 
 func ModalSearchDelChar(e *config.Editor) {
@@ -11,9 +12,10 @@ func ModalSearchDelChar(e *config.Editor) {
 		return
 	}
 
-	e.Modal.ModalInput = append(e.Modal.ModalInput[:e.Modal.CursorPosition-1], e.Modal.ModalInput[e.Modal.CursorPosition:]...)
+	start := utils.PrevGrapheme(e.Modal.ModalInput, e.Modal.CursorPosition)
+	e.Modal.ModalInput = append(e.Modal.ModalInput[:start], e.Modal.ModalInput[e.Modal.CursorPosition:]...)
 
-	e.Modal.CursorPosition--
+	e.Modal.CursorPosition = start
 }
 
 func EditorDelChar(e *config.Editor) {
@@ -23,39 +25,36 @@ func EditorDelChar(e *config.Editor) {
 	if e.CurrentBuffer.SliceIndex == 0 && e.Cy == 0 {
 		return
 	}
-	row := &e.CurrentBuffer.Rows[e.Cy]
+	row := e.CurrentBuffer.Row(e.Cy)
 	if e.CurrentBuffer.SliceIndex > 0 {
-		if e.Cx-e.ColOff < e.LineNumberWidth {
-			e.ColOff--
-		}
-		e.Cx--
-		EditorRowDelChar(row, e.CurrentBuffer.SliceIndex-1, e)
-		e.CurrentBuffer.SliceIndex--
+		at := utils.PrevGrapheme(row.Chars, e.CurrentBuffer.SliceIndex)
+		EditorRowDelChar(e.Cy, at, e)
+		e.CurrentBuffer.SliceIndex = at
+		e.SyncCx()
 	} else {
-		e.Cx = e.CurrentBuffer.Rows[e.Cy-1].Length + e.LineNumberWidth
-		e.CurrentBuffer.SliceIndex = e.CurrentBuffer.Rows[e.Cy-1].Length
+		e.CurrentBuffer.SliceIndex = e.CurrentBuffer.Row(e.Cy - 1).Length
 		EditorDelRow(e)
 		e.Cy--
+		e.SyncCx()
 	}
 }
 
-func EditorRowDelChar(row *config.Row, at int, e *config.Editor) {
+func EditorRowDelChar(rowIdx int, at int, e *config.Editor) {
+	row := e.CurrentBuffer.Row(rowIdx)
 	if at < 0 || at >= len(row.Chars) {
 		return
 	}
+	end := utils.NextGrapheme(row.Chars, at)
 	if closingBracket, ok := constants.BracketPairs[rune(row.Chars[at])]; ok {
 		// Check if the next character is the corresponding closing bracket
 		if at+1 < len(row.Chars) && row.Chars[at+1] == byte(closingBracket) {
 			// Delete the closing bracket along with the opening bracket
-			copy(row.Chars[at:], row.Chars[at+2:])
-			row.Chars = row.Chars[:len(row.Chars)-1]
+			end++
 		}
 	}
-	copy(row.Chars[at:], row.Chars[at+1:])
-	row.Chars = row.Chars[:len(row.Chars)-1] // Access the Row field
+	e.CurrentBuffer.DeleteText(config.Point{Row: rowIdx, Col: at}, config.Point{Row: rowIdx, Col: end})
 
-	row.Length = len(row.Chars) // Update the length of the row
-	EditorUpdateRow(row, e)
+	EditorUpdateRow(e.CurrentBuffer.Row(rowIdx), e)
 	e.CurrentBuffer.Dirty++
 }
 
@@ -64,31 +63,17 @@ func EditorDelRow(e *config.Editor) {
 		return
 	}
 
-	mergeCurrentRowWithPrevious(e)
-	updateRowIndicesFromCurrent(e)
+	// Deleting the newline between the rows merges the current row into the previous one
+	prevRow := *e.CurrentBuffer.Row(e.Cy - 1)
+	e.CurrentBuffer.DeleteText(config.Point{Row: e.Cy - 1, Col: prevRow.Length}, config.Point{Row: e.Cy, Col: 0})
+
 	highlighting.ResetRowHighlights(-1, e)
-	highlighting.SyntaxHighlightStateMachine(&e.CurrentBuffer.Rows[e.Cy-1], e)
+	highlighting.SyntaxHighlightStateMachine(e.Cy-1, e)
 	ResetRowTabs(e.Cy-1, e)
-	e.CurrentBuffer.RemoveRowAtIndex(e.Cy)
-	// deleteCurrentRow(e)
 	e.CurrentBuffer.Dirty++
 }
 
 func ResetRowTabs(idx int, e *config.Editor) {
-	row := &e.CurrentBuffer.Rows[idx]
+	row := e.CurrentBuffer.Row(idx)
 	row.Tabs = make([]byte, row.Length)
 }
-
-func mergeCurrentRowWithPrevious(e *config.Editor) {
-	prevRow := &e.CurrentBuffer.Rows[e.Cy-1]
-	currentRow := &e.CurrentBuffer.Rows[e.Cy]
-
-	prevRow.Chars = append(prevRow.Chars, currentRow.Chars...)
-	prevRow.Length = len(prevRow.Chars)
-}
-
-func updateRowIndicesFromCurrent(e *config.Editor) {
-	for i := e.Cy; i < len(e.CurrentBuffer.Rows); i++ {
-		e.CurrentBuffer.Rows[i].Idx = i
-	}
-}
@@ -1,122 +1,131 @@
 package core
 
 import (
-	"bufio"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/deanrtaylor1/go-editor/config"
 	"github.com/deanrtaylor1/go-editor/constants"
+	"github.com/deanrtaylor1/go-editor/keys"
+	"github.com/deanrtaylor1/go-editor/utils"
 )
 
-func EventHandlerMain(reader *bufio.Reader, e *config.Editor) rune {
-	char, err := ReadKey(reader)
-	if err != nil {
-		panic(err)
+// EventHandlerMain hands a key to the handler of the current mode and returns
+// the refresh hint for the screen.
+func EventHandlerMain(key keys.Key, e *config.Editor) rune {
+	// Ctrl-Z is undo in insert mode, everywhere else it suspends
+	if key == keys.Ctrl('z') && e.EditorMode != constants.EDITOR_MODE_INSERT {
+		SuspendEditor(e)
+		return constants.RESIZE_REFRESH
 	}
+	if key.Code == keys.CodeUnknown {
+		return constants.NO_OP
+	}
+	if key.Code == keys.CodeMouse {
+		return MouseHandler(e, key.Mouse)
+	}
+
+	// Whatever one key changes is undone together, and so is everything typed
+	// in one insert session
+	b := e.CurrentBuffer
+	b.BeginUndoGroup()
+	defer func() {
+		if b != e.CurrentBuffer || e.EditorMode != constants.EDITOR_MODE_INSERT {
+			b.EndUndoGroup(e.Settings.UndoLevels)
+		}
+	}()
 
+	refresh := RefreshFor(key)
 	if e.ModalOpen {
-		char = ModalModeEventsHandler(char, e)
+		refresh = ModalModeEventsHandler(key, e)
 	} else if e.EditorMode == constants.EDITOR_MODE_NORMAL {
-		char = NormalModeEventsHandler(char, e)
+		refresh = NormalModeEventsHandler(key, e)
 	} else if e.EditorMode == constants.EDITOR_MODE_INSERT {
-		InsertModeEventsHandler(char, e)
+		InsertModeEventsHandler(key, e)
 	} else if e.IsBrowsingFiles() {
-		char = FileBrowserEventsHandler(char, e)
+		refresh = FileBrowserEventsHandler(key, e)
 	} else if e.EditorMode == constants.EDITOR_MODE_VISUAL {
-		char = VisualModeEventsHandler(char, e)
+		refresh = VisualModeEventsHandler(key, e)
+	} else if e.EditorMode == constants.EDITOR_MODE_COMMAND {
+		refresh = CommandModeEventsHandler(key, e)
 	}
-	return char
+	return refresh
 }
 
-func ModalSearchCursorMovements(key rune, e *config.Editor) {
-	switch key {
-	case rune(constants.ARROW_LEFT):
+func ModalSearchCursorMovements(key keys.Key, e *config.Editor) {
+	switch key.Code {
+	case keys.CodeLeft:
 		if e.Modal.CursorPosition <= 0 {
 			return
 		}
-		e.Modal.CursorPosition--
-	case rune(constants.ARROW_RIGHT):
+		e.Modal.CursorPosition = utils.PrevGrapheme(e.Modal.ModalInput, e.Modal.CursorPosition)
+	case keys.CodeRight:
 		if e.Modal.CursorPosition >= len(e.Modal.ModalInput) {
 			return
 		}
-		e.Modal.CursorPosition++
+		e.Modal.CursorPosition = utils.NextGrapheme(e.Modal.ModalInput, e.Modal.CursorPosition)
 	}
 }
 
-func FileBrowserCursorMovements(key rune, e *config.Editor) {
-	switch key {
-	case rune(constants.ARROW_LEFT):
+func FileBrowserCursorMovements(key keys.Key, e *config.Editor) {
+	switch key.Code {
+	case keys.CodeLeft:
 		if e.Cx <= 0 {
 			return
 		}
 		e.MoveCursorLeft()
-	case rune(constants.ARROW_RIGHT):
+	case keys.CodeRight:
 		if e.Cy == len(e.FileBrowserItems)+len(e.InstructionsLines()) {
 			break
 		}
 		e.MoveCursorRight()
-	case rune(constants.ARROW_DOWN):
+	case keys.CodeDown:
 		if e.Cy < len(e.FileBrowserItems)+len(e.InstructionsLines()) {
 			e.MoveCursorDown()
 		}
-	case rune(constants.ARROW_UP):
+	case keys.CodeUp:
 		if e.Cy >= 5 {
 			e.MoveCursorUp()
 		}
 	}
 }
 
-func EditorCursorMovements(key rune, e *config.Editor) {
-	var row []byte = []byte{}
-	if e.Cy < e.CurrentBuffer.NumRows {
-		row = e.CurrentBuffer.Rows[e.Cy].Chars
-	}
-	// spacesNeeded := TAB_STOP - (e.Cx % TAB_STOP)
-	switch key {
-	case rune(constants.ARROW_LEFT):
+func EditorCursorMovements(key keys.Key, e *config.Editor) {
+	switch key.Code {
+	case keys.CodeLeft:
 		if e.CurrentBuffer.SliceIndex != 0 {
 			e.MoveCursorLeft()
 		} else if e.Cy > 0 && e.Cy < e.CurrentBuffer.NumRows {
 			e.MoveCursorUp()
-			e.Cx = (e.GetCurrentRow().Length) + e.LineNumberWidth
 			e.CurrentBuffer.SliceIndex = e.GetCurrentRow().Length
+			e.SyncCx()
 		}
-	case rune(constants.ARROW_RIGHT):
+	case keys.CodeRight:
 		if e.Cy == e.CurrentBuffer.NumRows {
 			break
 		}
 		if e.CurrentBuffer.SliceIndex < (e.GetCurrentRow().Length) {
 			e.MoveCursorRight()
-		} else if e.Cx-e.LineNumberWidth >= e.GetCurrentRow().Length && e.Cy < len(e.CurrentBuffer.Rows)-1 {
+		} else if e.Cy < e.CurrentBuffer.NumRows-1 {
 			e.MoveCursorDown()
 			e.Cx = e.LineNumberWidth
 			e.CurrentBuffer.SliceIndex = 0
 		}
-	case rune(constants.ARROW_DOWN):
+	case keys.CodeDown:
 		if e.Cy < e.CurrentBuffer.NumRows {
 			e.MoveCursorDown()
+			e.SyncSliceIndex()
 		}
-	case rune(constants.ARROW_UP):
+	case keys.CodeUp:
 		if e.Cy != 0 {
 			e.MoveCursorUp()
+			e.SyncSliceIndex()
 		}
 	}
-
-	if e.Cy < e.CurrentBuffer.NumRows {
-		row = e.CurrentBuffer.Rows[e.Cy].Chars
-	} else {
-		row = []byte{}
-	}
-
-	rowLen := len(row)
-	if e.CurrentBuffer.SliceIndex > rowLen {
-		e.Cx = rowLen + e.LineNumberWidth
-		e.CurrentBuffer.SliceIndex = rowLen
-	}
 }
 
-func EditorMoveCursor(key rune, e *config.Editor) {
+func EditorMoveCursor(key keys.Key, e *config.Editor) {
 	if e.ModalOpen {
 		ModalSearchCursorMovements(key, e)
 	} else if e.IsBrowsingFiles() {
@@ -130,3 +139,11 @@ func EditorSetStatusMessage(e *config.Editor, format string, a ...interface{}) {
 	e.StatusMsg = fmt.Sprintf(format, a...)
 	e.StatusMsgTime = time.Now()
 }
+
+// reportError shows err on the status bar and keeps it as the reason the key
+// failed, which stops a playing macro.
+func reportError(e *config.Editor, err error) {
+	e.KeyErr = err
+	msg := err.Error()
+	EditorSetStatusMessage(e, "%s", strings.ToUpper(msg[:1])+msg[1:])
+}
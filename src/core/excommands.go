@@ -0,0 +1,395 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/constants"
+	"github.com/deanrtaylor1/go-editor/highlighting"
+	"github.com/deanrtaylor1/go-editor/utils"
+)
+
+// What an ex command takes after its name, which is also what Tab completes
+// it with
+const (
+	exArgNone = iota
+	exArgFile
+	exArgSetting
+	exArgText
+)
+
+// ExCommand is a command run from the command line, like :w or :set.
+type ExCommand struct {
+	// Name is the full name, Short the shortest abbreviation of it that runs
+	// the command
+	Name  string
+	Short string
+	// Range commands take a line range, Bang ones a ! after the name
+	Range bool
+	Bang  bool
+	Arg   int
+	Run   func(e *config.Editor, cmd ExCall) error
+}
+
+// ExCall is a command line split into its parts.
+type ExCall struct {
+	Range LineRange
+	Bang  bool
+	Arg   string
+}
+
+// ExCommands holds the ex commands. A name that abbreviates more than one of
+// them runs the first.
+var ExCommands = []ExCommand{
+	{Name: "write", Short: "w", Range: true, Bang: true, Arg: exArgFile, Run: exWrite},
+	{Name: "wq", Short: "wq", Range: true, Bang: true, Arg: exArgFile, Run: exWriteQuit},
+	{Name: "xit", Short: "x", Range: true, Bang: true, Arg: exArgFile, Run: exXit},
+	{Name: "quit", Short: "q", Bang: true, Run: exQuit},
+	{Name: "edit", Short: "e", Bang: true, Arg: exArgFile, Run: exEdit},
+	{Name: "saveas", Short: "sav", Bang: true, Arg: exArgFile, Run: exSaveAs},
+	{Name: "read", Short: "r", Range: true, Arg: exArgFile, Run: exRead},
+	{Name: "set", Short: "se", Arg: exArgSetting, Run: exSet},
+	{Name: "registers", Short: "reg", Arg: exArgText, Run: exRegisters},
+	{Name: "display", Short: "di", Arg: exArgText, Run: exRegisters},
+	{Name: "earlier", Short: "ea", Arg: exArgText, Run: exEarlier},
+	{Name: "later", Short: "lat", Arg: exArgText, Run: exLater},
+}
+
+var (
+	errArgumentRequired = errors.New("argument required")
+	errFileExists       = errors.New("file exists (add ! to override)")
+	errInvalidRange     = errors.New("invalid range")
+	errNotWritten       = errors.New("no write since last change (add ! to override)")
+)
+
+// RunCommandLine runs one command line, typed without the :. A line that is
+// only a range goes to the last line of it.
+func RunCommandLine(e *config.Editor, line string) error {
+	r, rest, err := parseRange(e, line)
+	if err != nil {
+		return err
+	}
+	rest = strings.TrimLeft(rest, " ")
+	if rest == "" {
+		if r.Addresses > 0 {
+			moveCursorToRow(e, utils.Max(r.End, 1)-1)
+		}
+		return nil
+	}
+
+	name, rest := splitCommandName(rest)
+	command, ok := findExCommand(name)
+	if !ok {
+		return fmt.Errorf("not an editor command: %s", strings.TrimSpace(line))
+	}
+	call := ExCall{Range: r, Arg: strings.TrimSpace(rest)}
+	if strings.HasPrefix(rest, "!") {
+		if !command.Bang {
+			return errors.New("no ! allowed")
+		}
+		call.Bang = true
+		call.Arg = strings.TrimSpace(rest[1:])
+	}
+
+	switch {
+	case r.Addresses > 0 && !command.Range:
+		return errors.New("no range allowed")
+	case r.Addresses > 0 && (r.Start < 0 || r.End > e.CurrentBuffer.NumRows):
+		return errInvalidRange
+	case command.Arg == exArgNone && call.Arg != "":
+		return fmt.Errorf("trailing characters: %s", call.Arg)
+	}
+	return command.Run(e, call)
+}
+
+// splitCommandName splits the name of a command, its letters, from the
+// rest of the line.
+func splitCommandName(s string) (string, string) {
+	i := 0
+	for i < len(s) && ((s[i] >= 'a' && s[i] <= 'z') || (s[i] >= 'A' && s[i] <= 'Z')) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func findExCommand(name string) (ExCommand, bool) {
+	if name == "" {
+		return ExCommand{}, false
+	}
+	for _, command := range ExCommands {
+		if strings.HasPrefix(command.Name, name) && strings.HasPrefix(name, command.Short) {
+			return command, true
+		}
+	}
+	return ExCommand{}, false
+}
+
+// exPath is where a file name given to a command points, relative names
+// being taken from the root directory.
+func exPath(e *config.Editor, name string) string {
+	if filepath.IsAbs(name) {
+		return filepath.Clean(name)
+	}
+	return filepath.Join(e.RootDirectory, name)
+}
+
+// isCurrentFile reports whether path is the file of the current buffer.
+func isCurrentFile(e *config.Editor, path string) bool {
+	return e.CurrentBuffer.Name != "" && path == BufferPath(e, e.CurrentBuffer)
+}
+
+// checkOverwrite fails for a file that already exists unless bang was given.
+func checkOverwrite(path string, bang bool) error {
+	if _, err := os.Stat(path); bang || errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return errFileExists
+}
+
+func exWrite(e *config.Editor, cmd ExCall) error {
+	whole := cmd.Range.Addresses == 0 || (cmd.Range.Start <= 1 && cmd.Range.End == e.CurrentBuffer.NumRows)
+	if cmd.Arg == "" || isCurrentFile(e, exPath(e, cmd.Arg)) {
+		if !whole {
+			return errors.New("a range can only be written to another file")
+		}
+		return SaveKeyHandler(e)
+	}
+
+	path := exPath(e, cmd.Arg)
+	if err := checkOverwrite(path, cmd.Bang); err != nil {
+		return err
+	}
+	first, last := 0, e.CurrentBuffer.NumRows
+	if !whole {
+		first, last = utils.Max(cmd.Range.Start, 1)-1, cmd.Range.End
+	}
+	content := linesToDisk(e.CurrentBuffer, first, last)
+	note, err := WriteFileAtomic(path, content, e.Settings.Backup)
+	if err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+	if note != "" {
+		note = " (" + note + ")"
+	}
+	EditorSetStatusMessage(e, "\"%s\", %dL, %dB written%s", cmd.Arg, last-first, len(content), note)
+	return nil
+}
+
+func exWriteQuit(e *config.Editor, cmd ExCall) error {
+	if err := exWrite(e, cmd); err != nil {
+		return err
+	}
+	// Writing to another file leaves the changes unsaved, but that is what
+	// was asked for
+	return exQuit(e, ExCall{Bang: cmd.Arg != ""})
+}
+
+// exXit is :x, which only writes when there is something to write.
+func exXit(e *config.Editor, cmd ExCall) error {
+	if e.CurrentBuffer.Dirty == 0 && cmd.Arg == "" {
+		return exQuit(e, ExCall{})
+	}
+	return exWriteQuit(e, cmd)
+}
+
+// exQuit closes the buffer the way the quit key does, with ! standing in for
+// pressing it until it gives in.
+func exQuit(e *config.Editor, cmd ExCall) error {
+	if e.CurrentBuffer.Dirty > 0 && !cmd.Bang {
+		return errNotWritten
+	}
+	e.QuitTimes = 0
+	QuitKeyHandler(e)
+	e.QuitTimes = constants.QUIT_TIMES
+	return nil
+}
+
+// exEdit opens a file, or reads the current one again when none is given.
+func exEdit(e *config.Editor, cmd ExCall) error {
+	if cmd.Arg != "" && !isCurrentFile(e, exPath(e, cmd.Arg)) {
+		if err := ReadHandler(e, exPath(e, cmd.Arg)); err != nil {
+			return fmt.Errorf("can't open %s: %w", cmd.Arg, err)
+		}
+		return nil
+	}
+	if e.CurrentBuffer.Name == "" {
+		return errors.New("no file name")
+	}
+	if e.CurrentBuffer.Dirty > 0 && !cmd.Bang {
+		return errNotWritten
+	}
+	return ReloadFromDisk(e)
+}
+
+// exSaveAs writes the buffer to another file and makes that the buffer's
+// file from then on.
+func exSaveAs(e *config.Editor, cmd ExCall) error {
+	if cmd.Arg == "" {
+		return errArgumentRequired
+	}
+	path := exPath(e, cmd.Arg)
+	if isCurrentFile(e, path) {
+		return SaveKeyHandler(e)
+	}
+	if err := checkOverwrite(path, cmd.Bang); err != nil {
+		return err
+	}
+
+	b := e.CurrentBuffer
+	name, stamp := b.Name, b.DiskStamp
+	RemoveSwapFile(e)
+	renameBuffer(e, bufferName(e, path))
+	setDiskStamp(b, config.FileStamp{})
+	if err := SaveKeyHandler(e); err != nil {
+		renameBuffer(e, name)
+		setDiskStamp(b, stamp)
+		return err
+	}
+	return nil
+}
+
+// bufferName is the name a buffer for the file at path goes by, relative to
+// the root directory the way FileOpen names them.
+func bufferName(e *config.Editor, path string) string {
+	rel, err := filepath.Rel(e.RootDirectory, path)
+	if err != nil {
+		return path
+	}
+	return string(filepath.Separator) + rel
+}
+
+func renameBuffer(e *config.Editor, name string) {
+	b := e.CurrentBuffer
+	for i := range e.Buffers {
+		if e.Buffers[i].Name == b.Name {
+			e.Buffers[i].Name = name
+		}
+	}
+	b.Name = name
+	b.SwapPath = SwapPath(BufferPath(e, b))
+	e.FileName = name
+}
+
+// exRead puts the contents of a file below the last line of the range, or
+// at the top for line 0.
+func exRead(e *config.Editor, cmd ExCall) error {
+	if cmd.Arg == "" {
+		return errArgumentRequired
+	}
+	data, err := os.ReadFile(exPath(e, cmd.Arg))
+	if err != nil {
+		return fmt.Errorf("can't open %s: %w", cmd.Arg, err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	data = DetectLineEndings(data, config.NewBufferOptions())
+	if !bytes.HasSuffix(data, []byte{'\n'}) {
+		data = append(data, '\n')
+	}
+
+	row := cmd.Range.End
+	e.CurrentBuffer.InsertText(config.Point{Row: row}, data)
+	e.CurrentBuffer.Dirty++
+	highlighting.HighlightFileFromRow(row, e)
+	moveCursorToRow(e, row)
+	return nil
+}
+
+// exSet changes settings and the options of the current buffer, each
+// argument one of name=value, name or noname for a setting that is on or off,
+// name! to switch it and name? to show it. Without arguments it shows them
+// all.
+func exSet(e *config.Editor, cmd ExCall) error {
+	args := strings.Fields(cmd.Arg)
+	if len(args) == 0 {
+		for _, name := range append(config.OptionNames, config.SettingNames...) {
+			args = append(args, name+"?")
+		}
+	}
+	var shown []string
+	for _, arg := range args {
+		name, value, hasValue := strings.Cut(arg, "=")
+		current, err := getOption(e, strings.TrimRight(name, "?!"))
+		isBool := current == "true" || current == "false"
+		switch {
+		case hasValue:
+			err = setOption(e, name, value)
+		case strings.HasPrefix(name, "no") && err != nil:
+			err = setOption(e, name[2:], "false")
+		case err != nil:
+		case strings.HasSuffix(name, "!") && isBool:
+			err = setOption(e, name[:len(name)-1], fmt.Sprint(current != "true"))
+		case isBool && !strings.HasSuffix(name, "?"):
+			err = setOption(e, name, "true")
+		default:
+			shown = append(shown, strings.TrimRight(name, "?!")+"="+current)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	ApplySettings(e)
+	if len(shown) > 0 {
+		EditorSetStatusMessage(e, "%s", strings.Join(shown, "  "))
+	}
+	return nil
+}
+
+// getOption returns an option of the current buffer, or an editor setting
+// when no option goes by name.
+func getOption(e *config.Editor, name string) (string, error) {
+	if value, err := e.CurrentBuffer.Options.Get(name); err == nil {
+		return value, nil
+	}
+	return e.Settings.Get(name)
+}
+
+// setOption changes an option of the current buffer, or an editor setting
+// when no option goes by name.
+func setOption(e *config.Editor, name, value string) error {
+	b := e.CurrentBuffer
+	before, err := b.Options.Get(name)
+	if err != nil {
+		return e.Settings.Set(name, value)
+	}
+	if err := b.Options.Set(name, value); err != nil {
+		return err
+	}
+	after, _ := b.Options.Get(name)
+	if after == before {
+		return nil
+	}
+	switch name {
+	case "end_of_line", "insert_final_newline":
+		// The text is the same but the file it makes is not
+		b.Dirty++
+		b.ForgetSaved()
+	case "tab_width":
+		if e.Cy < b.NumRows {
+			e.SyncCx()
+		}
+	}
+	return nil
+}
+
+// exRegisters shows the registers, or only those named in its argument, as
+// in :reg a0".
+func exRegisters(e *config.Editor, cmd ExCall) error {
+	ShowRegisters(e, strings.ReplaceAll(cmd.Arg, " ", ""))
+	return nil
+}
+
+func exEarlier(e *config.Editor, cmd ExCall) error {
+	return UndoEarlier(e, cmd.Arg)
+}
+
+func exLater(e *config.Editor, cmd ExCall) error {
+	return UndoLater(e, cmd.Arg)
+}
@@ -0,0 +1,49 @@
+package core
+
+import "testing"
+
+func TestExSet(t *testing.T) {
+	tests := []struct {
+		line  string
+		name  string
+		want  string
+		dirty bool
+	}{
+		{"set tab_width=8", "tab_width", "8", false},
+		{"set indent_style=tab", "indent_style", "tab", false},
+		{"set end_of_line=crlf", "end_of_line", "crlf", true},
+		{"set end_of_line=lf", "end_of_line", "lf", false},
+		{"set noinsert_final_newline", "insert_final_newline", "false", true},
+		{"set trim_trailing_whitespace!", "trim_trailing_whitespace", "true", false},
+		{"set undo_levels=5", "undo_levels", "5", false},
+		{"set nomouse", "mouse", "false", false},
+	}
+	for _, tt := range tests {
+		e := newTestEditor("a b c\n")
+		if err := RunCommandLine(e, tt.line); err != nil {
+			t.Errorf("%q: %s", tt.line, err)
+			continue
+		}
+		if got, err := getOption(e, tt.name); err != nil || got != tt.want {
+			t.Errorf("%q left %s=%q (%v), want %q", tt.line, tt.name, got, err, tt.want)
+		}
+		if dirty := e.CurrentBuffer.Dirty > 0; dirty != tt.dirty {
+			t.Errorf("%q: dirty %v, want %v", tt.line, dirty, tt.dirty)
+		}
+	}
+
+	e := newTestEditor("a b c\n")
+	for _, line := range []string{"set tab_width=0", "set end_of_line=unix", "set nosuch"} {
+		if err := RunCommandLine(e, line); err == nil {
+			t.Errorf("%q succeeded, want an error", line)
+		}
+	}
+}
+
+func TestExQuitModified(t *testing.T) {
+	e := newTestEditor("a b c\n")
+	typeKeys(e, "dw")
+	if err := RunCommandLine(e, "q"); err != errNotWritten {
+		t.Errorf("q on a modified buffer returned %v, want %v", err, errNotWritten)
+	}
+}
@@ -0,0 +1,184 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/utils"
+)
+
+// LineRange is the lines an ex command works on, counted from 1 the way they
+// are typed. Addresses is how many were typed, 0 when the command goes with
+// its own default.
+type LineRange struct {
+	Start     int
+	End       int
+	Addresses int
+}
+
+// parseRange reads the range at the start of line, as in :.,$ or
+// :'a;/end/-1, and returns it with the rest of the line. An address is a line
+// number, . for the current line, $ for the last, 'x for a mark or a /pattern/
+// or ?pattern? searched for forwards or backwards, each followed by any number
+// of +n and -n offsets. % is the whole file. Addresses are separated by , or
+// by ; which makes the one before it the current line for the next.
+func parseRange(e *config.Editor, line string) (LineRange, string, error) {
+	s := strings.TrimLeft(line, " :")
+	if strings.HasPrefix(s, "%") {
+		return LineRange{Start: 1, End: e.CurrentBuffer.NumRows, Addresses: 2}, s[1:], nil
+	}
+
+	current := e.Cy + 1
+	var addresses []int
+	separated := false
+	for {
+		s = strings.TrimLeft(s, " ")
+		n, rest, ok, err := parseAddress(e, s, current)
+		if err != nil {
+			return LineRange{}, "", err
+		}
+		if ok {
+			s = rest
+		} else if separated || strings.HasPrefix(s, ",") || strings.HasPrefix(s, ";") {
+			// An address left out next to a separator is the current line
+			n = current
+		} else {
+			break
+		}
+		addresses = append(addresses, n)
+
+		separated = false
+		s = strings.TrimLeft(s, " ")
+		if s == "" || (s[0] != ',' && s[0] != ';') {
+			break
+		}
+		if s[0] == ';' {
+			current = n
+		}
+		s = s[1:]
+		separated = true
+	}
+
+	switch len(addresses) {
+	case 0:
+		return LineRange{Start: current, End: current}, s, nil
+	case 1:
+		return LineRange{Start: addresses[0], End: addresses[0], Addresses: 1}, s, nil
+	}
+	start, end := addresses[len(addresses)-2], addresses[len(addresses)-1]
+	if start > end {
+		start, end = end, start
+	}
+	return LineRange{Start: start, End: end, Addresses: 2}, s, nil
+}
+
+// parseAddress reads one address from the start of s, returning false when
+// there is none.
+func parseAddress(e *config.Editor, s string, current int) (int, string, bool, error) {
+	line, found := current, true
+	switch {
+	case s == "":
+		return 0, s, false, nil
+	case s[0] >= '0' && s[0] <= '9':
+		digits := leadingDigits(s)
+		n, err := strconv.Atoi(s[:digits])
+		if err != nil {
+			return 0, s, false, fmt.Errorf("invalid line number: %s", s[:digits])
+		}
+		line, s = n, s[digits:]
+	case s[0] == '.':
+		s = s[1:]
+	case s[0] == '$':
+		line, s = e.CurrentBuffer.NumRows, s[1:]
+	case s[0] == '\'':
+		if len(s) < 2 {
+			return 0, s, false, errors.New("missing mark name")
+		}
+		p, ok := e.CurrentBuffer.Marks[rune(s[1])]
+		if !ok {
+			return 0, s, false, fmt.Errorf("mark not set: '%c", s[1])
+		}
+		line, s = utils.Min(p.Row, lastRow(e))+1, s[2:]
+	case s[0] == '/' || s[0] == '?':
+		delim := s[0]
+		var pattern string
+		pattern, s = splitPattern(s[1:], delim)
+		n, err := searchLine(e, pattern, current, delim == '/')
+		if err != nil {
+			return 0, s, false, err
+		}
+		line = n
+	default:
+		found = false
+	}
+
+	for s != "" && (s[0] == '+' || s[0] == '-') {
+		sign := 1
+		if s[0] == '-' {
+			sign = -1
+		}
+		s = s[1:]
+		offset := 1
+		if digits := leadingDigits(s); digits > 0 {
+			offset, _ = strconv.Atoi(s[:digits])
+			s = s[digits:]
+		}
+		line += sign * offset
+		found = true
+	}
+	return line, s, found, nil
+}
+
+func leadingDigits(s string) int {
+	digits := 0
+	for digits < len(s) && s[digits] >= '0' && s[digits] <= '9' {
+		digits++
+	}
+	return digits
+}
+
+// splitPattern returns the pattern up to the first unescaped delim and what
+// comes after it. A pattern running to the end of s needs no closing delim.
+func splitPattern(s string, delim byte) (string, string) {
+	var pattern strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == delim:
+			pattern.WriteByte(delim)
+			i++
+		case s[i] == delim:
+			return pattern.String(), s[i+1:]
+		default:
+			pattern.WriteByte(s[i])
+		}
+	}
+	return pattern.String(), ""
+}
+
+// searchLine finds the first line after current, or before it when not
+// forward, that holds pattern, going round the end of the file. An empty
+// pattern is the last one searched for.
+func searchLine(e *config.Editor, pattern string, current int, forward bool) (int, error) {
+	if pattern == "" {
+		reg, _ := e.Registers.Get(config.LastSearchRegister)
+		if reg.Text == "" {
+			return 0, errors.New("no previous search pattern")
+		}
+		pattern = reg.Text
+	}
+	rows := e.CurrentBuffer.NumRows
+	step := 1
+	if !forward {
+		step = -1
+	}
+	for i := 1; i <= rows; i++ {
+		row := ((current-1+step*i)%rows + rows) % rows
+		if strings.Contains(string(e.CurrentBuffer.Row(row).Chars), pattern) {
+			return row + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("pattern not found: %s", pattern)
+}
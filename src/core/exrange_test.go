@@ -0,0 +1,74 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/deanrtaylor1/go-editor/config"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		line string
+		want LineRange
+		rest string
+	}{
+		{"", LineRange{Start: 3, End: 3}, ""},
+		{"d", LineRange{Start: 3, End: 3}, "d"},
+		{"5d", LineRange{Start: 5, End: 5, Addresses: 1}, "d"},
+		{":5", LineRange{Start: 5, End: 5, Addresses: 1}, ""},
+		{".", LineRange{Start: 3, End: 3, Addresses: 1}, ""},
+		{"$", LineRange{Start: 10, End: 10, Addresses: 1}, ""},
+		{"%d", LineRange{Start: 1, End: 10, Addresses: 2}, "d"},
+		{"2,4d", LineRange{Start: 2, End: 4, Addresses: 2}, "d"},
+		{"4,2", LineRange{Start: 2, End: 4, Addresses: 2}, ""},
+		{"  2 , 4 p", LineRange{Start: 2, End: 4, Addresses: 2}, "p"},
+		{",5", LineRange{Start: 3, End: 5, Addresses: 2}, ""},
+		{"5,", LineRange{Start: 3, End: 5, Addresses: 2}, ""},
+		{"1,2,6", LineRange{Start: 2, End: 6, Addresses: 2}, ""},
+		{".+2", LineRange{Start: 5, End: 5, Addresses: 1}, ""},
+		{"+", LineRange{Start: 4, End: 4, Addresses: 1}, ""},
+		{"-2", LineRange{Start: 1, End: 1, Addresses: 1}, ""},
+		{"$-1", LineRange{Start: 9, End: 9, Addresses: 1}, ""},
+		{"++-", LineRange{Start: 4, End: 4, Addresses: 1}, ""},
+		{"5,+2", LineRange{Start: 5, End: 5, Addresses: 2}, ""},
+		{"5;+2", LineRange{Start: 5, End: 7, Addresses: 2}, ""},
+		{"'a", LineRange{Start: 6, End: 6, Addresses: 1}, ""},
+		{"'a,'a+2s", LineRange{Start: 6, End: 8, Addresses: 2}, "s"},
+		{"/line7/", LineRange{Start: 7, End: 7, Addresses: 1}, ""},
+		{"/line7", LineRange{Start: 7, End: 7, Addresses: 1}, ""},
+		{"/line1/", LineRange{Start: 10, End: 10, Addresses: 1}, ""},
+		{"?line1?", LineRange{Start: 1, End: 1, Addresses: 1}, ""},
+		{"?line5?", LineRange{Start: 5, End: 5, Addresses: 1}, ""},
+		{"/line2/;+1d", LineRange{Start: 2, End: 3, Addresses: 2}, "d"},
+		{"/a\\/b/", LineRange{Start: 9, End: 9, Addresses: 1}, ""},
+		{"/line3/-1", LineRange{Start: 2, End: 2, Addresses: 1}, ""},
+	}
+	for _, tt := range tests {
+		e := newTestEditor("line1\nline2\nline3\nline4\nline5\nline6\nline7\nline8\nline9 a/b\nline10\n")
+		e.Cy = 2
+		e.CurrentBuffer.Marks['a'] = config.Point{Row: 5}
+		got, rest, err := parseRange(e, tt.line)
+		if err != nil {
+			t.Errorf("parseRange(%q): %s", tt.line, err)
+			continue
+		}
+		if got != tt.want || rest != tt.rest {
+			t.Errorf("parseRange(%q) = %+v, %q, want %+v, %q", tt.line, got, rest, tt.want, tt.rest)
+		}
+	}
+}
+
+func TestParseRangeErrors(t *testing.T) {
+	e := newTestEditor("one\ntwo\n")
+	for _, line := range []string{"'", "'b", "'b,2", "1,'b", "/three/", "?three", "//", "99999999999999999999"} {
+		if got, rest, err := parseRange(e, line); err == nil {
+			t.Errorf("parseRange(%q) = %+v, %q, want an error", line, got, rest)
+		}
+	}
+
+	// An empty pattern is the last one searched for
+	e.Registers.Fill(config.LastSearchRegister, "two")
+	if got, _, err := parseRange(e, "//"); err != nil || got.Start != 2 {
+		t.Errorf("parseRange(\"//\") after searching for two = %+v, %v, want line 2", got, err)
+	}
+}
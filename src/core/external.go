@@ -0,0 +1,205 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/editorconfig"
+	"github.com/deanrtaylor1/go-editor/highlighting"
+	"github.com/deanrtaylor1/go-editor/keys"
+	"github.com/deanrtaylor1/go-editor/utils"
+)
+
+const externalPollInterval = 2 * time.Second
+
+var (
+	lastExternalPoll time.Time
+	// externalPollRunning is set while a poll is out on its worker goroutine
+	externalPollRunning bool
+)
+
+func StampFromInfo(info fs.FileInfo) config.FileStamp {
+	return config.FileStamp{ModTime: info.ModTime(), Size: info.Size(), Inode: fileInode(info)}
+}
+
+// StatStamp returns the stamp of the file at path, or the zero stamp when it
+// does not exist.
+func StatStamp(path string) (config.FileStamp, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config.FileStamp{}, nil
+	}
+	if err != nil {
+		return config.FileStamp{}, err
+	}
+	return StampFromInfo(info), nil
+}
+
+func BufferPath(e *config.Editor, b *config.Buffer) string {
+	return filepath.Join(e.RootDirectory, b.Name)
+}
+
+func setDiskStamp(b *config.Buffer, stamp config.FileStamp) {
+	b.DiskStamp = stamp
+	b.LastSeenStamp = stamp
+}
+
+// ChangedOnDisk reports whether the file behind b is no longer the version
+// the buffer was read from or last saved to.
+func ChangedOnDisk(e *config.Editor, b *config.Buffer) bool {
+	if b.DiskStamp.IsZero() {
+		return false
+	}
+	stamp, err := StatStamp(BufferPath(e, b))
+	return err == nil && !stamp.Equal(b.DiskStamp)
+}
+
+// ReloadFromDisk replaces the current buffer with what is on disk, dropping
+// unsaved changes and undo history.
+func ReloadFromDisk(e *config.Editor) error {
+	b := e.CurrentBuffer
+	path := BufferPath(e, b)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to reload file: %w", err)
+	}
+	stamp, err := StatStamp(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	content := DetectLineEndings(data, b.Options)
+	if properties, err := editorconfig.Properties(path); err == nil {
+		b.Options.ApplyEditorConfig(properties)
+	}
+	b.SetText(content)
+	highlighting.HighlightFileFromRow(0, e)
+	b.Dirty = 0
+	setDiskStamp(b, stamp)
+	RemoveSwapFile(e)
+
+	if e.Cy > b.NumRows {
+		e.Cy = b.NumRows
+	}
+	if e.Cy < b.NumRows {
+		b.SliceIndex = utils.Min(b.SliceIndex, e.GetCurrentRow().Length)
+	} else {
+		b.SliceIndex = 0
+	}
+	e.SyncCx()
+	return nil
+}
+
+// reloadBackgroundBuffer reloads a buffer that is not on screen, using its
+// stored cursor in place of the editor's.
+func reloadBackgroundBuffer(e *config.Editor, b *config.Buffer) error {
+	current, cx, cy := e.CurrentBuffer, e.Cx, e.Cy
+	e.CurrentBuffer, e.Cx, e.Cy = b, b.StoredCx, b.StoredCy
+	err := ReloadFromDisk(e)
+	b.StoredCx, b.StoredCy = e.Cx, e.Cy
+	e.CurrentBuffer, e.Cx, e.Cy = current, cx, cy
+	return err
+}
+
+// ConfirmOverwrite asks what to do when the file of the current buffer changed
+// on disk since it was read. It returns true when the save should go ahead.
+func ConfirmOverwrite(e *config.Editor) (bool, error) {
+	if !ChangedOnDisk(e, e.CurrentBuffer) {
+		return true, nil
+	}
+	for {
+		switch EditorKeyPrompt("File changed on disk since it was read. [o]verwrite, [r]eload, [c]ancel:", e) {
+		case keys.Rune('o'), keys.Rune('O'):
+			return true, nil
+		case keys.Rune('r'), keys.Rune('R'):
+			if err := ReloadFromDisk(e); err != nil {
+				return false, err
+			}
+			return false, errors.New("reloaded from disk, nothing was written")
+		case keys.Rune('c'), keys.Rune('C'), keys.Escape:
+			return false, errors.New("save cancelled, file changed on disk")
+		}
+	}
+}
+
+// PollExternalChanges checks every open buffer against its file. The files
+// are looked at on a worker goroutine and what it finds is handed back to the
+// main loop, where clean buffers are reloaded and dirty ones only get a
+// warning since reloading would lose the user's changes.
+func PollExternalChanges(e *config.Editor) {
+	if externalPollRunning || time.Since(lastExternalPoll) < externalPollInterval {
+		return
+	}
+	lastExternalPoll = time.Now()
+
+	// Buffers are looked up by name again once the stamps are back, as they
+	// may have been closed or switched in the meantime
+	paths := map[string]string{}
+	addPath := func(b *config.Buffer) {
+		if b.Name != "" && !b.DiskStamp.IsZero() {
+			paths[b.Name] = BufferPath(e, b)
+		}
+	}
+	addPath(e.CurrentBuffer)
+	for i := range e.Buffers {
+		addPath(&e.Buffers[i])
+	}
+	if len(paths) == 0 {
+		return
+	}
+
+	externalPollRunning = true
+	go func() {
+		stamps := map[string]config.FileStamp{}
+		for name, path := range paths {
+			if stamp, err := StatStamp(path); err == nil {
+				stamps[name] = stamp
+			}
+		}
+		e.Post(func(e *config.Editor) {
+			externalPollRunning = false
+			if stamp, ok := stamps[e.CurrentBuffer.Name]; ok {
+				checkExternalChange(e, e.CurrentBuffer, stamp, true)
+			}
+			for i := range e.Buffers {
+				if stamp, ok := stamps[e.Buffers[i].Name]; ok && e.Buffers[i].Name != e.CurrentBuffer.Name {
+					checkExternalChange(e, &e.Buffers[i], stamp, false)
+				}
+			}
+		})
+	}()
+}
+
+// checkExternalChange reacts to stamp, what the file of b looks like on disk
+// now.
+func checkExternalChange(e *config.Editor, b *config.Buffer, stamp config.FileStamp, current bool) {
+	if b.Name == "" || b.DiskStamp.IsZero() || stamp.Equal(b.LastSeenStamp) {
+		return
+	}
+	// Only react once to each new version of the file
+	b.LastSeenStamp = stamp
+
+	var err error
+	switch {
+	case stamp.IsZero():
+		EditorSetStatusMessage(e, "%s was deleted on disk", b.Name)
+	case b.Dirty > 0:
+		EditorSetStatusMessage(e, "%s changed on disk, saving will ask before overwriting it", b.Name)
+	default:
+		if current {
+			err = ReloadFromDisk(e)
+		} else {
+			err = reloadBackgroundBuffer(e, b)
+		}
+		if err != nil {
+			EditorSetStatusMessage(e, "%s", err.Error())
+			return
+		}
+		EditorSetStatusMessage(e, "%s changed on disk and was reloaded", b.Name)
+	}
+}
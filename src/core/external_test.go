@@ -0,0 +1,191 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/constants"
+)
+
+// newFileEditor returns an editor showing file.txt in a new directory, read
+// from disk with content.
+func newFileEditor(t *testing.T, content string) (*config.Editor, string) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	writeTestFile(t, path, content)
+
+	e := newTestEditor(content)
+	e.RootDirectory = dir
+	e.CurrentBuffer.Name = "file.txt"
+	stampFile(t, e.CurrentBuffer, path)
+	return e, path
+}
+
+func stampFile(t *testing.T, b *config.Buffer, path string) {
+	t.Helper()
+	stamp, err := StatStamp(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setDiskStamp(b, stamp)
+}
+
+// rewriteFile changes the file at path, moving its time on so the change
+// shows even where the clock is too coarse to tell the writes apart.
+func rewriteFile(t *testing.T, path, content string) config.FileStamp {
+	t.Helper()
+	writeTestFile(t, path, content)
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	stamp, err := StatStamp(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stamp
+}
+
+func TestChangedOnDisk(t *testing.T) {
+	e, path := newFileEditor(t, "one\n")
+	b := e.CurrentBuffer
+	if ChangedOnDisk(e, b) {
+		t.Error("a file just read changed on disk")
+	}
+
+	rewriteFile(t, path, "two\n")
+	if !ChangedOnDisk(e, b) {
+		t.Error("a file written since it was read didn't change on disk")
+	}
+	stampFile(t, b, path)
+	if ChangedOnDisk(e, b) {
+		t.Error("a file saved again changed on disk")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if !ChangedOnDisk(e, b) {
+		t.Error("a deleted file didn't change on disk")
+	}
+
+	// A buffer that was never read from a file has nothing to compare
+	setDiskStamp(b, config.FileStamp{})
+	if ChangedOnDisk(e, b) {
+		t.Error("a buffer without a file changed on disk")
+	}
+}
+
+func TestExternalChangeReloadsCleanBuffer(t *testing.T) {
+	e, path := newFileEditor(t, "one\ntwo\nthree\n")
+	b := e.CurrentBuffer
+	typeKeys(e, "jll")
+
+	stamp := rewriteFile(t, path, "1\r\nabcd\r\n")
+	checkExternalChange(e, b, stamp, true)
+	if got := string(b.Text.Bytes()); got != "1\nabcd\n" {
+		t.Fatalf("reloading read %q, want %q", got, "1\nabcd\n")
+	}
+	if b.Dirty != 0 || !b.DiskStamp.Equal(stamp) || ChangedOnDisk(e, b) || b.Options.EndOfLine != constants.END_OF_LINE_CRLF {
+		t.Errorf("reloading left dirty %d, stamp %v for %v and %s endings", b.Dirty, b.DiskStamp, stamp, b.Options.EndOfLine)
+	}
+	if !strings.Contains(e.StatusMsg, "reloaded") {
+		t.Errorf("reloading showed %q", e.StatusMsg)
+	}
+	// The cursor stays where it was
+	if got := cursorPoint(e); got != (config.Point{Row: 1, Col: 2}) {
+		t.Errorf("reloading left the cursor at %v", got)
+	}
+}
+
+func TestExternalChangeWarnsDirtyBuffer(t *testing.T) {
+	e, path := newFileEditor(t, "one\ntwo\n")
+	b := e.CurrentBuffer
+	old := b.DiskStamp
+	typeKeys(e, "dd")
+
+	stamp := rewriteFile(t, path, "other\n")
+	checkExternalChange(e, b, stamp, true)
+	if got := string(b.Text.Bytes()); got != "two\n" || b.Dirty == 0 {
+		t.Errorf("a dirty buffer was reloaded to %q", got)
+	}
+	if !strings.Contains(e.StatusMsg, "changed on disk, saving will ask") {
+		t.Errorf("a dirty buffer changing on disk showed %q", e.StatusMsg)
+	}
+	// Saving still asks, as the buffer wasn't read from the new file
+	if !b.DiskStamp.Equal(old) || !ChangedOnDisk(e, b) {
+		t.Error("warning about a change took the new file as read")
+	}
+
+	// The same version of the file is only warned about once
+	e.StatusMsg = ""
+	checkExternalChange(e, b, stamp, true)
+	if e.StatusMsg != "" {
+		t.Errorf("a change already warned about showed %q", e.StatusMsg)
+	}
+}
+
+func TestExternalChangeDeletedFile(t *testing.T) {
+	e, path := newFileEditor(t, "one\n")
+	b := e.CurrentBuffer
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	stamp, err := StatStamp(path)
+	if err != nil || !stamp.IsZero() {
+		t.Fatalf("StatStamp of a deleted file = %v, %v", stamp, err)
+	}
+
+	checkExternalChange(e, b, stamp, true)
+	if got := string(b.Text.Bytes()); got != "one\n" {
+		t.Errorf("a deleted file left the buffer holding %q", got)
+	}
+	if !strings.Contains(e.StatusMsg, "was deleted") {
+		t.Errorf("a deleted file showed %q", e.StatusMsg)
+	}
+}
+
+func TestExternalChangeBackgroundBuffer(t *testing.T) {
+	e, path := newFileEditor(t, "one\ntwo\n")
+	other := config.NewBuffer()
+	other.SetText([]byte("shown\n"))
+	e.Buffers = append(e.Buffers, *e.CurrentBuffer)
+	e.CurrentBuffer = other
+	e.Cx, e.Cy = e.LineNumberWidth+2, 0
+	b := &e.Buffers[len(e.Buffers)-1]
+	b.StoredCx, b.StoredCy = e.LineNumberWidth, 1
+
+	stamp := rewriteFile(t, path, "three\nfour\n")
+	checkExternalChange(e, b, stamp, false)
+	if got := string(b.Text.Bytes()); got != "three\nfour\n" || !b.DiskStamp.Equal(stamp) {
+		t.Errorf("a buffer in the background was reloaded to %q", got)
+	}
+	if b.StoredCy != 1 {
+		t.Errorf("a buffer in the background has its cursor stored on row %d, want 1", b.StoredCy)
+	}
+	if e.CurrentBuffer != other || string(other.Text.Bytes()) != "shown\n" || e.Cx != e.LineNumberWidth+2 || e.Cy != 0 {
+		t.Error("reloading a buffer in the background changed the one on screen")
+	}
+}
+
+func TestExternalChangeIgnored(t *testing.T) {
+	e, path := newFileEditor(t, "one\n")
+	b := e.CurrentBuffer
+	stamp := rewriteFile(t, path, "two\n")
+
+	// Buffers without a file, or shown the same version again, are left alone
+	unnamed := config.NewBuffer()
+	unnamed.SetText([]byte("one\n"))
+	checkExternalChange(e, unnamed, stamp, false)
+
+	setDiskStamp(b, stamp)
+	checkExternalChange(e, b, stamp, true)
+	if got := string(b.Text.Bytes()); got != "one\n" || e.StatusMsg != "" {
+		t.Errorf("an ignored change left %q and showed %q", got, e.StatusMsg)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package core
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileInode returns the inode number of the file, which tells a file
+// replaced by another one apart from the same file written again.
+func fileInode(info fs.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
@@ -1,10 +1,10 @@
 package core
 
 import (
-	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,7 +12,9 @@ import (
 
 	"github.com/deanrtaylor1/go-editor/config"
 	"github.com/deanrtaylor1/go-editor/constants"
+	"github.com/deanrtaylor1/go-editor/editorconfig"
 	"github.com/deanrtaylor1/go-editor/highlighting"
+	"github.com/deanrtaylor1/go-editor/utils"
 )
 
 func EditorDeleteFile(e *config.Editor, fileName string) error {
@@ -83,12 +85,8 @@ func EditorRenameFile(e *config.Editor, oldName, newName string) error {
 	// Perform the rename
 	err = os.Rename(oldPath, newPath)
 	if err != nil {
-		if os.IsPermission(err) {
-			log.Fatal("Permission denied")
-		} else {
-			config.LogToFile(fmt.Sprintf("%s", err.Error()))
-			log.Fatal(err)
-		}
+		config.LogToFile(fmt.Sprintf("%s", err.Error()))
+		return fmt.Errorf("failed to rename file: %w", err)
 	}
 
 	// Check the new file's existence and permissions
@@ -121,6 +119,12 @@ func EditorRenameFile(e *config.Editor, oldName, newName string) error {
 }
 
 func FileOpen(e *config.Editor, fileName string) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
 	e.EditorMode = constants.EDITOR_MODE_NORMAL
 	if !e.FirstRead {
 		e.CurrentBuffer = config.NewBuffer()
@@ -128,12 +132,6 @@ func FileOpen(e *config.Editor, fileName string) error {
 	e.Cx = 0
 	e.Cy = 0
 	e.CurrentBuffer.SliceIndex = 0
-
-	file, err := os.Open(fileName)
-	if err != nil {
-		log.Fatal("Error opening file")
-	}
-	defer file.Close()
 	relativeFileName := strings.TrimPrefix(fileName, e.RootDirectory)
 
 	// If the fileName didn't start with RootDirectory, just use the base name
@@ -145,38 +143,43 @@ func FileOpen(e *config.Editor, fileName string) error {
 
 	highlighting.EditorSelectSyntaxHighlight(e)
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		linelen := len(line)
-		for linelen > 0 && (line[linelen-1] == '\n' || line[linelen-1] == '\r') {
-			linelen--
-		}
-		row := config.NewRow() // Create a new Row using the NewRow function
-		row.Chars = []byte(line[:linelen])
-		row.Length = linelen
-		row.Idx = len(e.CurrentBuffer.Rows)
-		row.Highlighting = make([]byte, row.Length)
-		highlighting.Fill(row.Highlighting, constants.HL_NORMAL)
-		row.Tabs = make([]byte, row.Length)
-		EditorInsertRow(row, row.Idx, e)
-		e.CurrentBuffer.NumRows++ // Update NumRows within CurrentBuffer
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
 	}
-	highlighting.HighlightFileFromRow(0, e)
-
-	if err := scanner.Err(); err != nil {
+	info, err := file.Stat()
+	if err != nil {
 		return err
 	}
+	setDiskStamp(e.CurrentBuffer, StampFromInfo(info))
+	content := DetectLineEndings(data, e.CurrentBuffer.Options)
+
+	// An .editorconfig is explicit about the format, so it wins over what the
+	// file currently uses
+	editorConfig, editorConfigErr := editorconfig.Properties(fileName)
+	if editorConfigErr == nil {
+		e.CurrentBuffer.Options.ApplyEditorConfig(editorConfig)
+	}
+
+	e.CurrentBuffer.SetText(content)
+	highlighting.HighlightFileFromRow(0, e)
+	LoadUndoFile(e, fileName)
+
 	e.CurrentBuffer.Dirty = 0
 	e.FirstRead = false
 	e.CurrentBuffer.Name = relativeFileName
+	e.CurrentBuffer.SwapPath = SwapPath(fileName)
 	if len(e.Buffers) < 1 {
 		e.Buffers = make([]config.Buffer, 0, 15)
 	}
 
-	e.LoadNewBuffer()
-
 	EditorSetStatusMessage(e, "HELP: CTRL-S = Save | Ctrl-Q = quit | Ctr-f = find")
+	if editorConfigErr != nil {
+		EditorSetStatusMessage(e, "failed to read .editorconfig: %s", editorConfigErr.Error())
+	}
+	CheckSwapFile(e)
+
+	e.LoadNewBuffer()
 
 	return nil
 }
@@ -186,42 +189,109 @@ func EditorSave(e *config.Editor) (string, error) {
 		return "", errors.New("no filename provided")
 	}
 
-	startTime := time.Now()
-	content := EditorRowsToString(e)
-
-	file, err := os.OpenFile(fmt.Sprintf("%s%s", e.RootDirectory, e.CurrentBuffer.Name), os.O_RDWR|os.O_CREATE, 0644)
-	if err != nil {
-		return "", fmt.Errorf("failed to open file: %w", err)
+	if ok, err := ConfirmOverwrite(e); !ok {
+		return "", err
 	}
-	defer file.Close()
 
-	if err := file.Truncate(int64(len(content))); err != nil {
-		return "", fmt.Errorf("failed to truncate file: %w", err)
+	startTime := time.Now()
+	if e.CurrentBuffer.Options.TrimTrailingWhitespace && e.CurrentBuffer.TrimTrailingWhitespace() {
+		highlighting.HighlightFileFromRow(0, e)
+		if e.Cy < e.CurrentBuffer.NumRows {
+			e.CurrentBuffer.SliceIndex = utils.Min(e.CurrentBuffer.SliceIndex, e.GetCurrentRow().Length)
+			e.SyncCx()
+		}
 	}
+	content := EditorRowsToString(e)
 
-	n, err := file.WriteString(content)
+	path := BufferPath(e, e.CurrentBuffer)
+	note, err := WriteFileAtomic(path, []byte(content), e.Settings.Backup)
 	if err != nil {
-		return "", fmt.Errorf("failed to write to file: %w", err)
+		return "", fmt.Errorf("save failed: %w", err)
 	}
-	if n != len(content) {
-		return "", errors.New("unexpected number of bytes written to file")
+	RemoveSwapFile(e)
+	if stamp, err := StatStamp(path); err == nil {
+		setDiskStamp(e.CurrentBuffer, stamp)
+	}
+	if err := WriteUndoFile(e, path); err != nil {
+		config.LogToFile(fmt.Sprintf("undo file for %s: %s", path, err.Error()))
 	}
 
 	elapsedTime := time.Since(startTime) // End timing
-	numLines := len(e.CurrentBuffer.Rows)
+	numLines := e.CurrentBuffer.NumRows
 	numBytes := len(content)
 	message := fmt.Sprintf("\"%s\", %dL, %dB, %.3fms: written", e.CurrentBuffer.Name, numLines, numBytes, float64(elapsedTime.Nanoseconds())/1e6)
+	if note != "" {
+		message += " (" + note + ")"
+	}
 
-	e.CurrentBuffer.Dirty = 0
+	e.CurrentBuffer.MarkSaved(e.Settings.UndoLevels)
 
 	return message, nil
 }
 
+// DetectLineEndings records the line ending and final newline used by data in
+// options and returns data with every line ending turned into '\n'. Mixed
+// files take the most common ending.
+func DetectLineEndings(data []byte, options *config.BufferOptions) []byte {
+	if len(data) == 0 {
+		return data
+	}
+	crlf := bytes.Count(data, []byte("\r\n"))
+	lf := bytes.Count(data, []byte{'\n'}) - crlf
+	cr := bytes.Count(data, []byte{'\r'}) - crlf
+
+	last := data[len(data)-1]
+	switch {
+	case crlf > 0 && crlf >= lf && crlf >= cr:
+		options.EndOfLine = constants.END_OF_LINE_CRLF
+		options.InsertFinalNewline = last == '\n'
+		return bytes.ReplaceAll(data, []byte("\r\n"), []byte{'\n'})
+	case cr > lf:
+		options.EndOfLine = constants.END_OF_LINE_CR
+		options.InsertFinalNewline = last == '\r' || last == '\n'
+		data = bytes.ReplaceAll(data, []byte("\r\n"), []byte{'\n'})
+		return bytes.ReplaceAll(data, []byte{'\r'}, []byte{'\n'})
+	}
+	options.EndOfLine = constants.END_OF_LINE_LF
+	options.InsertFinalNewline = last == '\n'
+	return data
+}
+
+// ConvertLineEndings switches the line ending the buffer is saved with.
+func ConvertLineEndings(e *config.Editor, endOfLine string) {
+	if e.CurrentBuffer.Options.EndOfLine == endOfLine {
+		EditorSetStatusMessage(e, "line endings are already %s", LineEndingName(endOfLine))
+		return
+	}
+	e.CurrentBuffer.Options.EndOfLine = endOfLine
+	e.CurrentBuffer.Dirty++
+	e.CurrentBuffer.ForgetSaved()
+	EditorSetStatusMessage(e, "line endings converted to %s", LineEndingName(endOfLine))
+}
+
+func LineEndingName(endOfLine string) string {
+	return strings.ToUpper(endOfLine)
+}
+
+// EditorRowsToString returns the buffer as it is written to disk, with the
+// line endings and final newline the buffer's options ask for.
 func EditorRowsToString(e *config.Editor) string {
-	var buffer strings.Builder
-	for _, row := range e.CurrentBuffer.Rows {
-		buffer.Write(row.Chars)
-		buffer.WriteByte('\n')
+	return string(linesToDisk(e.CurrentBuffer, 0, e.CurrentBuffer.NumRows))
+}
+
+// linesToDisk returns the rows from first up to last as they are written to
+// disk. The final newline is only left out when last is the end of the
+// buffer.
+func linesToDisk(b *config.Buffer, first, last int) []byte {
+	content := b.Text.Slice(b.PointToOffset(config.Point{Row: first}), b.PointToOffset(config.Point{Row: last}))
+	if last >= b.NumRows && !b.Options.InsertFinalNewline {
+		content = bytes.TrimSuffix(content, []byte{'\n'})
+	}
+	switch b.Options.EndOfLine {
+	case constants.END_OF_LINE_CRLF:
+		content = bytes.ReplaceAll(content, []byte{'\n'}, []byte{'\r', '\n'})
+	case constants.END_OF_LINE_CR:
+		content = bytes.ReplaceAll(content, []byte{'\n'}, []byte{'\r'})
 	}
-	return buffer.String()
+	return content
 }
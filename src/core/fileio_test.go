@@ -0,0 +1,114 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/constants"
+)
+
+func TestLineEndingsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		text         string
+		endOfLine    string
+		finalNewline bool
+		// written is what saving gives back, the data itself when empty
+		written string
+	}{
+		{"lf", "a\nb\n", "a\nb\n", constants.END_OF_LINE_LF, true, ""},
+		{"crlf", "a\r\nb\r\n", "a\nb\n", constants.END_OF_LINE_CRLF, true, ""},
+		{"cr", "a\rb\r", "a\nb\n", constants.END_OF_LINE_CR, true, ""},
+		{"lf without final newline", "a\nb", "a\nb", constants.END_OF_LINE_LF, false, ""},
+		{"crlf without final newline", "a\r\nb", "a\nb", constants.END_OF_LINE_CRLF, false, ""},
+		{"cr without final newline", "a\rb", "a\nb", constants.END_OF_LINE_CR, false, ""},
+		{"one row", "a", "a", constants.END_OF_LINE_LF, false, ""},
+		{"empty rows", "\r\n\r\n", "\n\n", constants.END_OF_LINE_CRLF, true, ""},
+		// Mixed files are saved with the most common ending
+		{"mostly crlf", "a\r\nb\nc\r\n", "a\nb\nc\n", constants.END_OF_LINE_CRLF, true, "a\r\nb\r\nc\r\n"},
+		{"as much crlf as lf", "a\r\nb\n", "a\nb\n", constants.END_OF_LINE_CRLF, true, "a\r\nb\r\n"},
+		{"mostly lf", "a\nb\nc\r\n", "a\nb\nc\r\n", constants.END_OF_LINE_LF, true, ""},
+		{"mostly cr", "a\rb\r\nc\rd\n", "a\nb\nc\nd\n", constants.END_OF_LINE_CR, true, "a\rb\rc\rd\r"},
+		{"cr ending in lf", "a\rb\rc\n", "a\nb\nc\n", constants.END_OF_LINE_CR, true, "a\rb\rc\r"},
+	}
+	for _, tt := range tests {
+		b := config.NewBuffer()
+		text := DetectLineEndings([]byte(tt.data), b.Options)
+		if string(text) != tt.text || b.Options.EndOfLine != tt.endOfLine || b.Options.InsertFinalNewline != tt.finalNewline {
+			t.Errorf("%s: read %q with %s endings and final newline %v, want %q with %s and %v", tt.name,
+				text, b.Options.EndOfLine, b.Options.InsertFinalNewline, tt.text, tt.endOfLine, tt.finalNewline)
+			continue
+		}
+		b.SetText(text)
+		want := tt.written
+		if want == "" {
+			want = tt.data
+		}
+		if got := string(linesToDisk(b, 0, b.NumRows)); got != want {
+			t.Errorf("%s: wrote %q, want %q", tt.name, got, want)
+		}
+	}
+}
+
+func TestLineEndingsEmptyFile(t *testing.T) {
+	b := config.NewBuffer()
+	b.Options.EndOfLine = constants.END_OF_LINE_CRLF
+	if text := DetectLineEndings(nil, b.Options); len(text) != 0 || b.Options.EndOfLine != constants.END_OF_LINE_CRLF {
+		t.Errorf("an empty file read as %q and changed the endings to %s", text, b.Options.EndOfLine)
+	}
+	b.SetText(nil)
+	if got := linesToDisk(b, 0, b.NumRows); len(got) != 0 {
+		t.Errorf("an empty buffer wrote %q", got)
+	}
+}
+
+func TestLinesToDiskPart(t *testing.T) {
+	tests := []struct {
+		endOfLine   string
+		first, last int
+		want        string
+	}{
+		{constants.END_OF_LINE_LF, 0, 1, "a\n"},
+		{constants.END_OF_LINE_CRLF, 0, 2, "a\r\nb\r\n"},
+		{constants.END_OF_LINE_CR, 1, 2, "b\r"},
+		// Only the end of the buffer goes without a final newline
+		{constants.END_OF_LINE_CRLF, 1, 3, "b\r\nc"},
+		{constants.END_OF_LINE_LF, 2, 3, "c"},
+	}
+	for _, tt := range tests {
+		b := config.NewBuffer()
+		b.SetText([]byte("a\nb\nc\n"))
+		b.Options.EndOfLine = tt.endOfLine
+		b.Options.InsertFinalNewline = false
+		if got := string(linesToDisk(b, tt.first, tt.last)); got != tt.want {
+			t.Errorf("rows %d to %d with %s endings wrote %q, want %q", tt.first, tt.last, tt.endOfLine, got, tt.want)
+		}
+	}
+}
+
+func TestConvertLineEndings(t *testing.T) {
+	e := newTestEditor("a\nb\n")
+	b := e.CurrentBuffer
+	b.MarkSaved(e.Settings.UndoLevels)
+
+	ConvertLineEndings(e, constants.END_OF_LINE_LF)
+	if b.Dirty != 0 || !b.IsSaved() {
+		t.Errorf("converting to the endings in use left dirty %d, saved %v", b.Dirty, b.IsSaved())
+	}
+
+	ConvertLineEndings(e, constants.END_OF_LINE_CRLF)
+	if b.Dirty == 0 || b.IsSaved() || b.SavedSeq != -1 {
+		t.Errorf("converting to crlf left dirty %d and saved state %d", b.Dirty, b.SavedSeq)
+	}
+	if got := EditorRowsToString(e); got != "a\r\nb\r\n" {
+		t.Errorf("converting to crlf writes %q", got)
+	}
+
+	// Undoing back to the text that was saved doesn't make it saved again,
+	// the file still has the old endings
+	typeKeys(e, "ddu")
+	if b.IsSaved() {
+		t.Error("undoing after converting took the buffer back to saved")
+	}
+}
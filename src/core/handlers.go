@@ -4,28 +4,26 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/deanrtaylor1/go-editor/config"
 	"github.com/deanrtaylor1/go-editor/constants"
 	"github.com/deanrtaylor1/go-editor/highlighting"
+	"github.com/deanrtaylor1/go-editor/keys"
 )
 
 func TabKeyHandler(e *config.Editor) {
 	if e.CurrentBuffer.SliceIndex == 0 {
 		e.GetCurrentRow().IndentationLevel++
 	}
-	for i := 0; i < constants.TAB_STOP; i++ {
-		EditorInsertChar(' ', e)
+	for _, c := range e.CurrentBuffer.Options.IndentUnit() {
+		EditorInsertChar(rune(c), e)
 	}
 	MapTabs(e)
 }
 
 func EnterKeyHandler(e *config.Editor) {
-	action := e.CurrentBuffer.NewEditorAction(*e.GetCurrentRow().DeepCopy(), e.Cy, constants.ACTION_INSERT_ROW, e.GetCurrentRow().Length, e.Cx, e.GetCurrentRow(), func() { EditorInsertNewLine(e) })
-	e.CurrentBuffer.AppendUndo(*action, e.UndoHistory)
 	EditorInsertNewLine(e)
 }
 
@@ -35,31 +33,37 @@ func QuitKeyHandler(e *config.Editor) bool {
 		e.QuitTimes--
 		return false
 	}
+	return CloseBuffer(e)
+}
 
+// CloseBuffer closes the current buffer, changed or not, and opens the next
+// one. It returns true when that was the last buffer, which quits the editor.
+func CloseBuffer(e *config.Editor) bool {
+	RemoveSwapFile(e)
 	e.RemoveBuffer(e.CurrentBuffer.Name) // Remove the current buffer
 
 	if len(e.Buffers) > 0 {
 		fullPath := filepath.Join(e.RootDirectory, e.Buffers[0].Name)
 		// Load the next buffer if there's any remaining
-		ReadHandler(e, fullPath)
+		if err := ReadHandler(e, fullPath); err != nil {
+			EditorSetStatusMessage(e, "%s", err.Error())
+		}
 		return false
 	}
 
-	// Clear screen and exit if no more buffers
-	fmt.Print(constants.ESCAPE_CLEAR_SCREEN)
-	fmt.Print(constants.ESCAPE_MOVE_TO_HOME_POS)
-	os.Exit(0)
+	// No buffers left, the event loop stops and main restores the terminal
+	e.Quit(nil)
 	return true
 }
 
-func SaveKeyHandler(e *config.Editor) {
+func SaveKeyHandler(e *config.Editor) error {
 	msg, err := EditorSave(e)
 	if err != nil {
 		EditorSetStatusMessage(e, "%s", err.Error())
-		return
+		return err
 	}
 	EditorSetStatusMessage(e, "%s", msg)
-	return
+	return nil
 }
 
 func HomeKeyHandler(e *config.Editor) {
@@ -71,27 +75,14 @@ func EndKeyHandler(e *config.Editor) error {
 	if e.Cy == e.CurrentBuffer.NumRows {
 		return errors.New("Can not go to end of this row")
 	}
-	e.Cx = e.CurrentBuffer.Rows[e.Cy].Length + e.LineNumberWidth
-	e.CurrentBuffer.SliceIndex = e.CurrentBuffer.Rows[e.Cy].Length
+	e.CurrentBuffer.SliceIndex = e.CurrentBuffer.Row(e.Cy).Length
+	e.SyncCx()
 	return nil
 }
 
-func createActionForUndo(e *config.Editor, cb func()) *config.EditorAction {
-	prevRowLength := 0
-	action := e.CurrentBuffer.NewEditorAction(*e.GetCurrentRow().DeepCopy(), e.Cy, constants.ACTION_UPDATE_ROW, prevRowLength, e.Cx, nil, cb)
-
-	if e.Cy > 0 && e.CurrentBuffer.SliceIndex == 0 {
-		action.ActionType = constants.ACTION_APPEND_ROW_TO_PREVIOUS
-		action.PrevRow = e.CurrentBuffer.Rows[e.Cy-1]
-		action.Cx = e.LineNumberWidth
-	}
-
-	return action
-}
-
-func handleDeleteKey(e *config.Editor, char rune) {
-	if char == constants.DEL_KEY {
-		EditorMoveCursor(constants.ARROW_RIGHT, e)
+func handleDeleteKey(e *config.Editor, key keys.Key) {
+	if key == keys.Delete {
+		EditorMoveCursor(keys.Right, e)
 	}
 }
 
@@ -108,7 +99,7 @@ func deleteTabOrChar(e *config.Editor) {
 			for startOfTab > 0 && currentRow.Tabs[startOfTab-1] == constants.HL_TAB_KEY {
 				startOfTab--
 				i++
-				if i == constants.TAB_STOP {
+				if i == e.CurrentBuffer.Options.IndentSize {
 					break // Stop after finding one complete tab
 				}
 			}
@@ -124,27 +115,24 @@ func deleteTabOrChar(e *config.Editor) {
 	}
 }
 
-func DeleteHandler(e *config.Editor, char rune) {
+func DeleteHandler(e *config.Editor, key keys.Key) {
 	if e.ModalOpen {
-		handleDeleteKey(e, char)
+		handleDeleteKey(e, key)
 		ModalSearchDelChar(e)
 	} else {
-		action := createActionForUndo(e, func() { handleDeleteKey(e, char); deleteTabOrChar(e) })
-		e.CurrentBuffer.AppendUndo(*action, e.UndoHistory)
-
-		handleDeleteKey(e, char)
+		handleDeleteKey(e, key)
 		deleteTabOrChar(e)
 
 	}
 }
 
-func PageJumpHandler(e *config.Editor, char rune) {
+func PageJumpHandler(e *config.Editor, key keys.Key) {
 	rows := e.ScreenRows
 	for rows > 0 {
-		if char == constants.PAGE_UP {
-			EditorMoveCursor(constants.ARROW_UP, e)
+		if key == keys.PageUp {
+			EditorMoveCursor(keys.Up, e)
 		} else {
-			EditorMoveCursor(constants.ARROW_DOWN, e)
+			EditorMoveCursor(keys.Down, e)
 		}
 		rows--
 	}
@@ -163,28 +151,28 @@ func HandleCharInsertion(e *config.Editor, char rune) {
 	if closingBracket, ok := constants.BracketPairs[char]; ok {
 		EditorInsertChar(char, e)
 		EditorInsertChar(closingBracket, e)
-		e.CurrentBuffer.SliceIndex--
-		e.Cx--
+		e.MoveCursorLeft()
 	} else {
 		EditorInsertChar(char, e)
 	}
 }
 
 func InsertCharHandler(e *config.Editor, char rune) {
-	var currentRow config.Row
-	var action *config.EditorAction
-	if e.Cy != e.CurrentBuffer.NumRows {
-		currentRow = *e.GetCurrentRow().DeepCopy()
-		action = e.CurrentBuffer.NewEditorAction(currentRow, e.Cy, constants.ACTION_UPDATE_ROW, 0, e.Cx, nil, func() { HandleCharInsertion(e, char) })
-	} else {
-		currentRow = *config.NewRow()
-		action = e.CurrentBuffer.NewEditorAction(currentRow, e.CurrentBuffer.NumRows, constants.ACTION_INSERT_CHAR_AT_EOF, 0, e.Cx, nil, func() { HandleCharInsertion(e, char) })
-	}
-	e.CurrentBuffer.AppendUndo(*action, e.UndoHistory)
-
 	HandleCharInsertion(e, char)
 }
 
+// PasteHandler inserts a bracketed paste verbatim.
+func PasteHandler(e *config.Editor, text string) {
+	if text == "" {
+		return
+	}
+	start := config.Point{Row: e.Cy, Col: e.CurrentBuffer.SliceIndex}
+	if start.Row >= e.CurrentBuffer.LineCount() {
+		start = config.Point{Row: e.CurrentBuffer.LineCount()}
+	}
+	EditorInsertText([]byte(text), start, e)
+}
+
 func ControlCHandler(buffer *bytes.Buffer, c rune, cColor int) {
 	sym := '?'
 	if c <= 26 {
@@ -198,41 +186,41 @@ func ControlCHandler(buffer *bytes.Buffer, c rune, cColor int) {
 	}
 }
 
-func FormatSelectedTextHandler(buffer *bytes.Buffer, c byte, cColor *int, hl byte) {
+func FormatSelectedTextHandler(buffer *bytes.Buffer, c []byte, cColor *int, hl byte) {
 	buffer.WriteString(constants.BACKGROUND_BRIGHT_BLACK)
 	color := int(highlighting.EditorSyntaxToColor(hl))
 	if color != *cColor {
 		buffer.WriteString(fmt.Sprintf("\x1b[%dm", color))
 		*cColor = color
 	}
-	buffer.WriteByte(c)
+	buffer.Write(c)
 	buffer.WriteString(constants.BACKGROUND_RESET)
 	*cColor = -1
 }
 
-func FormatFindResultHandler(buffer *bytes.Buffer, c byte) {
+func FormatFindResultHandler(buffer *bytes.Buffer, c []byte) {
 	buffer.WriteString(constants.ESCAPE_HIDE_CURSOR)
 	buffer.WriteString(constants.FOREGROUND_RESET)
 	buffer.WriteString(constants.BACKGROUND_YELLOW)
-	buffer.WriteByte(c)
+	buffer.Write(c)
 	buffer.WriteString(constants.BACKGROUND_RESET)
 }
 
-func NormalFormatHandler(buffer *bytes.Buffer, c byte, cColor int) {
+func NormalFormatHandler(buffer *bytes.Buffer, c []byte, cColor int) {
 	if cColor != -1 {
 		buffer.WriteString(constants.FOREGROUND_RESET)
 		cColor = -1
 	}
-	buffer.WriteByte(c)
+	buffer.Write(c)
 }
 
-func ColorFormatHandler(buffer *bytes.Buffer, c byte, cColor *int, hl byte) {
+func ColorFormatHandler(buffer *bytes.Buffer, c []byte, cColor *int, hl byte) {
 	color := int(highlighting.EditorSyntaxToColor(hl))
 	if color != *cColor {
 		buffer.WriteString(fmt.Sprintf("\x1b[%dm", color))
 		*cColor = color
 	}
-	buffer.WriteByte(c)
+	buffer.Write(c)
 	buffer.WriteString(constants.FOREGROUND_RESET)
 	*cColor = -1
 }
@@ -257,10 +245,10 @@ func WriteWelcomeIfNoFile(buffer *bytes.Buffer, screenCols int, screenRows int,
 	}
 }
 
-func CountSpaces(e *config.Editor, rowLength int, j int, fileRow int) (spaceCount int) {
+func CountSpaces(row *config.Row, idx int, size int) (spaceCount int) {
 	spaceCount = 0
-	for k := j; k < j+constants.TAB_STOP; k++ {
-		if k >= rowLength || e.CurrentBuffer.Rows[fileRow].Chars[e.ColOff+k] != ' ' {
+	for k := idx; k < idx+size; k++ {
+		if k >= row.Length || row.Chars[k] != ' ' {
 			break
 		}
 		spaceCount++
@@ -268,15 +256,16 @@ func CountSpaces(e *config.Editor, rowLength int, j int, fileRow int) (spaceCoun
 	return spaceCount
 }
 
-func AppendTabOrRowIndentBar(e *config.Editor, j *int, buffer *bytes.Buffer, fileRow int, rowLength int) {
-	nextCharIndex := *j + constants.TAB_STOP
-	if nextCharIndex < rowLength && e.CurrentBuffer.Rows[fileRow].Chars[e.ColOff+nextCharIndex] != '}' {
-		buffer.WriteString(strings.Repeat(" ", constants.TAB_STOP-1))
+// AppendTabOrRowIndentBar draws one level of indentation that is width cells
+// wide, either a run of spaces or a tab, with the guide in its last cell.
+func AppendTabOrRowIndentBar(row *config.Row, nextCharIndex int, width int, buffer *bytes.Buffer) {
+	if nextCharIndex < row.Length && row.Chars[nextCharIndex] != '}' {
+		buffer.WriteString(strings.Repeat(" ", width-1))
 		buffer.WriteString(constants.TEXT_BRIGHT_BLACK)
 		buffer.WriteString("│")
 		buffer.WriteString(constants.FOREGROUND_RESET)
 	} else {
 		// If the next character is a '}', just append the spaces
-		buffer.WriteString(strings.Repeat(" ", constants.TAB_STOP))
+		buffer.WriteString(strings.Repeat(" ", width))
 	}
 }
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/keys"
+)
+
+func TestPasteInInsertMode(t *testing.T) {
+	tests := []struct {
+		text   string
+		before string
+		paste  string
+		want   string
+		cursor config.Point
+	}{
+		// Brackets aren't paired and new rows aren't indented
+		{"\n", "i", "func f() {\n\treturn\n}", "func f() {\n\treturn\n}\n", config.Point{Row: 2, Col: 1}},
+		{"  x\n", "$i", "{\ny", "  {\nyx\n", config.Point{Row: 1, Col: 1}},
+		{"  {}\n", "$i", "\n", "  {\n}\n", config.Point{Row: 1, Col: 0}},
+		{"ab\n", "li", "(", "a(b\n", config.Point{Row: 0, Col: 2}},
+		{"ab\n", "li", ")", "a)b\n", config.Point{Row: 0, Col: 2}},
+		{"a)\n", "li", ")", "a))\n", config.Point{Row: 0, Col: 2}},
+		{"a\n", "i", "\r\t<Esc>é世", "\r\t<Esc>é世a\n", config.Point{Row: 0, Col: 12}},
+		{"a\n", "i", "", "a\n", config.Point{}},
+		{"", "i", "x\n", "x\n", config.Point{Row: 1, Col: 0}},
+	}
+	for _, tt := range tests {
+		e := newTestEditor(tt.text)
+		typeKeys(e, tt.before)
+		EventHandlerMain(keys.Paste(tt.paste), e)
+		if got := string(e.CurrentBuffer.Text.Bytes()); got != tt.want {
+			t.Errorf("pasting %q on %q after %q left %q, want %q", tt.paste, tt.text, tt.before, got, tt.want)
+			continue
+		}
+		if got := cursorPoint(e); got != tt.cursor {
+			t.Errorf("pasting %q on %q after %q left the cursor at %v, want %v", tt.paste, tt.text, tt.before, got, tt.cursor)
+		}
+
+		// The paste is kept for ". and undone with the rest of the insert
+		typeKeys(e, "<Esc>")
+		if reg, _ := e.Registers.Get(config.LastInsertRegister); reg.Text != tt.paste {
+			t.Errorf("pasting %q on %q kept %q as the last insert", tt.paste, tt.text, reg.Text)
+		}
+		typeKeys(e, "u")
+		if got := string(e.CurrentBuffer.Text.Bytes()); got != tt.text {
+			t.Errorf("pasting %q on %q and undoing left %q", tt.paste, tt.text, got)
+		}
+	}
+}
+
+func TestPasteWithTypedText(t *testing.T) {
+	e := newTestEditor("\n")
+	typeKeys(e, "ia{")
+	EventHandlerMain(keys.Paste("b\n"), e)
+	typeKeys(e, "c<Esc>")
+	if got, want := string(e.CurrentBuffer.Text.Bytes()), "a{b\nc}\n"; got != want {
+		t.Fatalf("typing around a paste left %q, want %q", got, want)
+	}
+	if reg, _ := e.Registers.Get(config.LastInsertRegister); reg.Text != "a{b\nc" {
+		t.Errorf("typing around a paste kept %q as the last insert", reg.Text)
+	}
+	typeKeys(e, "u")
+	if got := string(e.CurrentBuffer.Text.Bytes()); got != "\n" {
+		t.Errorf("one undo after typing around a paste left %q", got)
+	}
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/keys"
+)
+
+// newTestEditor returns an editor showing a buffer that holds text, with the
+// cursor at its start and the sequences of normal mode mapped.
+func newTestEditor(text string) *config.Editor {
+	e := config.NewEditor()
+	e.ScreenRows, e.ScreenCols = 20, 80
+	e.CurrentBuffer.SetText([]byte(text))
+	e.Cx = e.LineNumberWidth
+	e.MotionMap = Sequences(e)
+	return e
+}
+
+// typeKeys sends keys, written the way keys.Parse reads them, to the editor
+// as the main loop would.
+func typeKeys(e *config.Editor, typed string) {
+	for _, key := range keys.Parse(typed) {
+		RecordKey(e, key)
+		EventHandlerMain(key, e)
+	}
+}
+
+// typingTest is keys typed into a buffer holding text and the text they
+// should leave.
+type typingTest struct {
+	text  string
+	typed string
+	want  string
+}
+
+func checkTyping(t *testing.T, tests []typingTest) {
+	t.Helper()
+	for _, tt := range tests {
+		e := newTestEditor(tt.text)
+		typeKeys(e, tt.typed)
+		if got := string(e.CurrentBuffer.Text.Bytes()); got != tt.want {
+			t.Errorf("%q on %q left %q, want %q", tt.typed, tt.text, got, tt.want)
+		}
+	}
+}
@@ -1,82 +1,45 @@
 package core
 
 import (
+	"unicode/utf8"
+
 	"github.com/deanrtaylor1/go-editor/config"
 	"github.com/deanrtaylor1/go-editor/constants"
 	"github.com/deanrtaylor1/go-editor/highlighting"
 )
 
-func PasteYankLines(e *config.Editor) {
-	// Determine the starting row index for pasting
-	startIdx := e.Cy + 1 // Assuming you want to paste below the current row
-
-	for i, row := range e.Yank.PartialBuffer.Rows {
-		EditorInsertRow(&row, startIdx+i, e)
-	}
-
-	e.CurrentBuffer.Dirty += len(e.Yank.PartialBuffer.Rows)
-}
-
-func PasteYankChars(e *config.Editor) {
-	cRow := e.GetCurrentRow()
-	yankChars := e.Yank.PartialBuffer.Rows[0].Chars
-
-	insertIdx := e.Cx - e.LineNumberWidth + e.ColOff + 1
-	if insertIdx > len(cRow.Chars) {
-		insertIdx = len(cRow.Chars)
-	}
-
-	newChars := append(cRow.Chars[:insertIdx], append(yankChars, cRow.Chars[insertIdx:]...)...)
-
-	cRow.Chars = newChars
-	cRow.Length = len(newChars)
-
-	cRow.Highlighting = make([]byte, cRow.Length)
-	highlighting.Fill(cRow.Highlighting, constants.HL_NORMAL)
-	cRow.Tabs = make([]byte, cRow.Length)
-	highlighting.SyntaxHighlightStateMachine(cRow, e)
-
-	e.ClearYank()
+// EditorInsertText puts text in at start exactly as given, without the
+// bracket pairing and indentation typing adds, and leaves the cursor after it.
+func EditorInsertText(text []byte, start config.Point, e *config.Editor) config.Point {
+	end := e.CurrentBuffer.InsertText(start, text)
+	highlighting.HighlightFileFromRow(start.Row, e)
 	e.CurrentBuffer.Dirty++
-}
 
-func PasteYank(e *config.Editor) {
-	if e.Yank.Type == config.EMPTY_YANK {
-		return
-	} else if e.Yank.Type == config.CharWise {
-		PasteYankChars(e)
-	} else if e.Yank.Type == config.LineWise {
-		PasteYankLines(e)
-	}
+	e.Cy = end.Row
+	e.CurrentBuffer.SliceIndex = end.Col
+	e.SyncCx()
+	return end
 }
 
-func editorRowInsertChar(row *config.Row, at int, char rune, e *config.Editor) {
-	row.Chars = append(row.Chars, 0)
-	copy(row.Chars[at+1:], row.Chars[at:])
-	row.Chars[at] = byte(char)
+func editorRowInsertChar(rowIdx int, at int, char rune, e *config.Editor) {
+	e.CurrentBuffer.InsertText(config.Point{Row: rowIdx, Col: at}, []byte(string(char)))
 
-	row.Length = len(row.Chars)
-	row.Highlighting = make([]byte, row.Length)
+	row := e.CurrentBuffer.Row(rowIdx)
 	highlighting.Fill(row.Highlighting, constants.HL_NORMAL)
-	row.Tabs = make([]byte, row.Length)
-
-	highlighting.SyntaxHighlightStateMachine(row, e)
+	highlighting.SyntaxHighlightStateMachine(rowIdx, e)
 
 	e.CurrentBuffer.Dirty++
 }
 
 func EditorInsertChar(char rune, e *config.Editor) {
-	if e.Cy == e.CurrentBuffer.NumRows {
-		EditorInsertRow(config.NewRow(), -1, e)
-		e.CurrentBuffer.NumRows++
-	}
-	editorRowInsertChar(&e.CurrentBuffer.Rows[e.Cy], e.CurrentBuffer.SliceIndex, char, e)
+	editorRowInsertChar(e.Cy, e.CurrentBuffer.SliceIndex, char, e)
 
-	e.MoveCursorRight()
+	e.CurrentBuffer.SliceIndex += utf8.RuneLen(char)
+	e.SyncCx()
 }
 
 func EditorInsertNewLine(e *config.Editor) {
-	row := e.CurrentBuffer.Rows[e.Cy]
+	row := *e.CurrentBuffer.Row(e.Cy)
 	isBetweenBrackets := false
 
 	// Check if the cursor is between an opening and a closing bracket
@@ -93,29 +56,18 @@ func EditorInsertNewLine(e *config.Editor) {
 		at := e.Cy
 		EditorInsertRow(newRow, at, e)
 	} else {
-		// If we are between brackets another row will be inserted in between this row and the previous
-		currentRow := e.GetCurrentRow()
-		currentRow.Chars = row.Chars[:e.CurrentBuffer.SliceIndex]
-		currentRow.Length = len(e.CurrentBuffer.Rows[e.Cy].Chars)
-
-		newRow := config.Row{Chars: row.Chars[e.CurrentBuffer.SliceIndex:], IndentationLevel: currentRow.IndentationLevel}
-		indentBytes := make([]byte, newRow.IndentationLevel)
-		for i := 0; i < newRow.IndentationLevel; i++ {
-			indentBytes[i] = byte('\t')
-		}
-		newRow.Chars = append(indentBytes, newRow.Chars...)
-
-		EditorInsertRow(&newRow, e.Cy+1, e)
-		e.Cx = e.LineNumberWidth
-		e.CurrentBuffer.SliceIndex = 0
-		if e.GetCurrentRow().IndentationLevel > 0 {
-			e.CurrentBuffer.SliceIndex = constants.TAB_STOP * e.GetCurrentRow().IndentationLevel
-			e.Cx = constants.TAB_STOP*e.GetCurrentRow().IndentationLevel + 5
-		}
+		// Split the row at the cursor, carrying the indentation over to the new row
+		indent := e.CurrentBuffer.Options.IndentText(row.IndentationLevel)
+		e.CurrentBuffer.InsertText(config.Point{Row: e.Cy, Col: e.CurrentBuffer.SliceIndex}, append([]byte{'\n'}, indent...))
+		e.CurrentBuffer.Row(e.Cy + 1).IndentationLevel = row.IndentationLevel
+		highlighting.SyntaxHighlightStateMachine(e.Cy, e)
+		highlighting.SyntaxHighlightStateMachine(e.Cy+1, e)
+
+		e.CurrentBuffer.SliceIndex = len(indent)
 	}
 
-	e.CurrentBuffer.NumRows++ // Update NumRows within CurrentBuffer
 	e.Cy++
+	e.SyncCx()
 
 	// If the cursor was between brackets, insert an additional new line
 	if isBetweenBrackets {
@@ -123,45 +75,22 @@ func EditorInsertNewLine(e *config.Editor) {
 
 		newRow := config.NewRow()
 		newRow.IndentationLevel = currentRow.IndentationLevel + 1
-		// Create a byte slice with as many tabs as newRow.IndentationLevel
-		indentBytes := make([]byte, newRow.IndentationLevel)
-		for i := 0; i < newRow.IndentationLevel; i++ {
-			indentBytes[i] = byte('\t')
-		}
-
-		newRow.Chars = append(indentBytes, newRow.Chars...)
-		newRow.Length = len(newRow.Chars)
+		newRow.Chars = e.CurrentBuffer.Options.IndentText(newRow.IndentationLevel)
 
 		EditorInsertRow(newRow, e.Cy, e)
-		e.Cx = newRow.Length + e.LineNumberWidth
-		e.CurrentBuffer.SliceIndex = newRow.Length
-		e.CurrentBuffer.NumRows++
+		e.CurrentBuffer.SliceIndex = len(newRow.Chars)
+		e.SyncCx()
 	}
 }
 
 func EditorInsertRow(row *config.Row, at int, e *config.Editor) {
-	// Replace tabs with spaces
-	convertedChars := ReplaceTabsWithSpaces(row.Chars)
-	row.Chars = convertedChars
-	row.Length = len(convertedChars)
-	row.Idx = at // Set the index to the insertion point
-	row.Highlighting = make([]byte, row.Length)
-	row.Tabs = make([]byte, row.Length)
-
-	if at < 0 || at >= len(e.CurrentBuffer.Rows) {
+	row.Length = len(row.Chars)
+
+	if at < 0 || at >= e.CurrentBuffer.NumRows {
 		// If at is outside the valid range, append the row to the end
-		row.Idx = len(e.CurrentBuffer.Rows)
-		highlighting.SyntaxHighlightStateMachine(row, e)
-		e.CurrentBuffer.Rows = append(e.CurrentBuffer.Rows, *row)
-		return
+		at = e.CurrentBuffer.NumRows
 	}
-	highlighting.SyntaxHighlightStateMachine(row, e)
 
-	// Use InsertRowAtIndex to insert the row at the specified position
 	e.CurrentBuffer.InsertRowAtIndex(at, *row)
-
-	// Update the Idx of the subsequent rows
-	for i := at + 1; i < len(e.CurrentBuffer.Rows); i++ {
-		e.CurrentBuffer.Rows[i].Idx = i
-	}
+	highlighting.SyntaxHighlightStateMachine(at, e)
 }
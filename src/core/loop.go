@@ -0,0 +1,134 @@
+package core
+
+import (
+	"os"
+	"time"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/constants"
+	"github.com/deanrtaylor1/go-editor/keys"
+)
+
+const tickInterval = time.Second
+
+var (
+	resizeSignals = make(chan os.Signal, 1)
+	jobSignals    = make(chan os.Signal, 1)
+)
+
+// StartInput starts the goroutine that turns stdin into key events and
+// subscribes to window size changes and job control.
+func StartInput(e *config.Editor) {
+	notifySignals()
+	e.Keys = keys.NewDecoder(os.Stdin, e.Settings.EscapeTimeout)
+	go func() {
+		for {
+			key, err := e.Keys.ReadKey()
+			e.Input <- config.InputEvent{Key: key, Err: err}
+			if err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// RunEventLoop waits on input, resizes, timers and background results and
+// redraws the screen whenever one of them changed something. It returns once
+// the editor quits, with the error that made it quit if there was one.
+func RunEventLoop(e *config.Editor) error {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	EditorRefreshScreen(e, constants.INITIAL_REFRESH)
+	for {
+		char := rune(constants.NO_OP)
+		select {
+		case event := <-e.Input:
+			if event.Err != nil {
+				return event.Err
+			}
+			RecordKey(e, event.Key)
+			char = EventHandlerMain(event.Key, e)
+			UpdateSwapFile(e)
+		case <-resizeSignals:
+			EditorResize(e)
+			char = constants.RESIZE_REFRESH
+		case sig := <-jobSignals:
+			if isSuspendSignal(sig) {
+				SuspendEditor(e)
+			} else {
+				ResumeEditor(e)
+			}
+			char = constants.RESIZE_REFRESH
+		case result := <-e.Results:
+			result(e)
+			char = constants.INITIAL_REFRESH
+		case <-ticker.C:
+			char = onTick(e)
+		}
+		if e.Quitting {
+			return e.QuitErr
+		}
+		EditorRefreshScreen(e, char)
+	}
+}
+
+// WaitForKey blocks until the next key press, for prompts that need an answer
+// before the main loop can continue. A playing macro answers with its own
+// keys first. Resizes are still handled, everything else waits for the main
+// loop. When input fails the editor is told to quit, so the prompt only has to
+// give up.
+func WaitForKey(e *config.Editor) (keys.Key, error) {
+	if key, ok := nextPendingKey(e); ok {
+		return key, nil
+	}
+	for {
+		select {
+		case event := <-e.Input:
+			if event.Err != nil {
+				e.Quit(event.Err)
+			}
+			RecordKey(e, event.Key)
+			return event.Key, event.Err
+		case <-resizeSignals:
+			EditorResize(e)
+			EditorRefreshScreen(e, constants.RESIZE_REFRESH)
+		}
+	}
+}
+
+// EditorResize picks up the new terminal size after a SIGWINCH. The cursor
+// and scroll offsets are brought back on screen and an open modal is laid out
+// again on the next refresh.
+func EditorResize(e *config.Editor) {
+	if err := config.GetWindowSize(e); err != nil {
+		return
+	}
+	e.ScreenRows -= 2
+	// Keep at least one text row and one text column so the layout maths
+	// never goes negative on a tiny terminal
+	if e.ScreenRows < 1 {
+		e.ScreenRows = 1
+	}
+	if e.ScreenCols < e.LineNumberWidth+1 {
+		e.ScreenCols = e.LineNumberWidth + 1
+	}
+	EditorScroll(e)
+	e.Modal.ModalDrawn = false
+}
+
+// onTick does the periodic housekeeping and returns how much of the screen
+// needs to be redrawn. Files on disk are checked in the background and come
+// back as a result.
+func onTick(e *config.Editor) rune {
+	UpdateSwapFile(e)
+	PollExternalChanges(e)
+	if checkWindowSize(e) {
+		return constants.RESIZE_REFRESH
+	}
+	if e.StatusMsg != "" && time.Since(e.StatusMsgTime) >= constants.STATUS_MESSAGE_TIMEOUT {
+		e.StatusMsg = ""
+		return constants.INITIAL_REFRESH
+	}
+	return constants.NO_OP
+}
@@ -0,0 +1,25 @@
+//go:build !unix
+
+package core
+
+import (
+	"os"
+
+	"github.com/deanrtaylor1/go-editor/config"
+)
+
+// notifySignals has nothing to subscribe to where there are no resize or job
+// control signals, so those channels stay quiet.
+func notifySignals() {}
+
+func isSuspendSignal(sig os.Signal) bool {
+	return false
+}
+
+// checkWindowSize picks up resizes on every tick, without SIGWINCH to report
+// them, and reports whether the size changed.
+func checkWindowSize(e *config.Editor) bool {
+	rows, cols := e.ScreenRows, e.ScreenCols
+	EditorResize(e)
+	return rows != e.ScreenRows || cols != e.ScreenCols
+}
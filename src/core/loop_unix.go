@@ -0,0 +1,28 @@
+//go:build unix
+
+package core
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/deanrtaylor1/go-editor/config"
+)
+
+// notifySignals subscribes to SIGWINCH for resizes and to job control.
+// Ctrl-Z is a plain key in raw mode, so SIGTSTP only arrives when it is sent
+// from outside.
+func notifySignals() {
+	signal.Notify(resizeSignals, syscall.SIGWINCH)
+	signal.Notify(jobSignals, syscall.SIGTSTP, syscall.SIGCONT)
+}
+
+func isSuspendSignal(sig os.Signal) bool {
+	return sig == syscall.SIGTSTP
+}
+
+// checkWindowSize has nothing to do here, SIGWINCH reports every resize.
+func checkWindowSize(e *config.Editor) bool {
+	return false
+}
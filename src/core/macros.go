@@ -0,0 +1,149 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/constants"
+	"github.com/deanrtaylor1/go-editor/keys"
+)
+
+// maxMacroRuns is how many macros one macro may start, itself included,
+// before it is taken to be running away.
+const maxMacroRuns = 1000
+
+var (
+	errMacroRecursion = errors.New("too many nested macros")
+	errMotionFailed   = errors.New("motion failed")
+	errNoTextObject   = errors.New("nothing to act on")
+)
+
+// MacroKeyHandler handles q{register} to start recording keys, q to stop and
+// [count]@{register} to play them back, @@ playing the last macro again.
+func MacroKeyHandler(key keys.Key, e *config.Editor) (rune, bool) {
+	if !key.Printable() {
+		return constants.NO_OP, false
+	}
+	typed := string(append(e.MotionBuffer, key.Rune))
+	count, rest := splitCount(typed)
+
+	switch {
+	case rest == "q" && e.Recording != 0:
+		e.ClearMotionBuffer()
+		StopRecording(e)
+		return constants.INITIAL_REFRESH, true
+	case rest == "q" || rest == "@":
+		e.MotionBuffer = []rune(typed)
+		return constants.NO_OP, true
+	case len(rest) != 2 || (rest[0] != 'q' && rest[0] != '@'):
+		return constants.NO_OP, false
+	}
+
+	e.ClearMotionBuffer()
+	if rest[0] == 'q' {
+		if !isMacroRegister(key.Rune) {
+			EditorSetStatusMessage(e, "Invalid register: %c", key.Rune)
+			return constants.NO_OP, true
+		}
+		StartRecording(e, key.Rune)
+		return constants.INITIAL_REFRESH, true
+	}
+	return PlayMacro(e, key.Rune, countOrOne(count)), true
+}
+
+// Macros go in the registers a to z, an upper case letter records onto the
+// end of the lower case one. The digit registers can hold them too.
+func isMacroRegister(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+func StartRecording(e *config.Editor, register rune) {
+	e.Recording = register
+	e.RecordedKeys = nil
+}
+
+// StopRecording keeps the recorded keys as text in the register, leaving out
+// the q that stopped the recording.
+func StopRecording(e *config.Editor) {
+	recorded := e.RecordedKeys
+	if len(recorded) > 0 {
+		recorded = recorded[:len(recorded)-1]
+	}
+	e.Registers.Set(e.Recording, config.Register{Text: keys.Format(recorded), Type: config.CharWise})
+	e.Recording = 0
+	e.RecordedKeys = nil
+}
+
+// RecordKey adds a typed key to the macro being recorded. Mouse events depend
+// on what was on the screen at the time, so they are left out.
+func RecordKey(e *config.Editor, key keys.Key) {
+	if e.Recording == 0 || key.Code == keys.CodeMouse {
+		return
+	}
+	e.RecordedKeys = append(e.RecordedKeys, key)
+}
+
+// PlayMacro runs the keys in register count times through EventHandlerMain,
+// without drawing the screen in between. A key that fails stops the whole
+// playback, which is also how a macro that calls itself comes to an end.
+func PlayMacro(e *config.Editor, register rune, count int) rune {
+	if register == '@' {
+		if e.LastMacro == 0 {
+			e.KeyErr = errors.New("no previous macro")
+			EditorSetStatusMessage(e, "No previous macro")
+			return constants.NO_OP
+		}
+		register = e.LastMacro
+	}
+	reg, ok := e.Registers.Get(register)
+	if !ok || reg.Text == "" {
+		e.KeyErr = fmt.Errorf("register %c is empty", register)
+		EditorSetStatusMessage(e, "Register %c is empty", register)
+		return constants.NO_OP
+	}
+	e.LastMacro = register
+
+	e.MacroRuns++
+	if e.MacroRuns > maxMacroRuns {
+		e.KeyErr = errMacroRecursion
+		return constants.NO_OP
+	}
+	played := keys.Parse(reg.Text)
+	var queued []keys.Key
+	for i := 0; i < count; i++ {
+		queued = append(queued, played...)
+	}
+	e.PendingKeys = append(queued, e.PendingKeys...)
+
+	// A macro started by a playing one is played by the outer loop
+	if e.MacroRuns > 1 {
+		return constants.NO_OP
+	}
+	for !e.Quitting {
+		key, ok := nextPendingKey(e)
+		if !ok {
+			break
+		}
+		e.KeyErr = nil
+		EventHandlerMain(key, e)
+		if e.KeyErr != nil {
+			EditorSetStatusMessage(e, "Macro @%c stopped at %s: %s", register, key, e.KeyErr.Error())
+			e.PendingKeys = nil
+		}
+	}
+	e.MacroRuns = 0
+	e.KeyErr = nil
+	return constants.INITIAL_REFRESH
+}
+
+// nextPendingKey takes the next key of a playing macro, for prompts that read
+// keys of their own.
+func nextPendingKey(e *config.Editor) (keys.Key, bool) {
+	if len(e.PendingKeys) == 0 {
+		return keys.Key{}, false
+	}
+	key := e.PendingKeys[0]
+	e.PendingKeys = e.PendingKeys[1:]
+	return key, true
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/deanrtaylor1/go-editor/config"
+)
+
+func TestRecordMacro(t *testing.T) {
+	tests := []struct {
+		typed string
+		want  string
+	}{
+		{"qadwq", "dw"},
+		{"qa3dwjq", "3dwj"},
+		{"qaiab<CR><Esc>q", "iab<CR><Esc>"},
+		{"qai<lt><Esc>q", "i<lt><Esc>"},
+		{"qa<Paste>x<lt>{<PasteEnd>q", "<Paste>x<lt>{<PasteEnd>"},
+		{"qadwqqAjq", "dwj"},
+		{"qadwqqajq", "j"},
+		{"qaq", ""},
+	}
+	for _, tt := range tests {
+		e := newTestEditor("one two\nthree four\n")
+		typeKeys(e, tt.typed)
+		if reg, _ := e.Registers.Get('a'); reg.Text != tt.want {
+			t.Errorf("%q recorded %q, want %q", tt.typed, reg.Text, tt.want)
+		}
+		if e.Recording != 0 {
+			t.Errorf("%q is still recording", tt.typed)
+		}
+	}
+}
+
+func TestPlayMacro(t *testing.T) {
+	const text = "a b c d e f\n"
+	checkTyping(t, []typingTest{
+		{text, "qadwq@a", "c d e f\n"},
+		{text, "qadwq3@a", "e f\n"},
+		{text, "qadwq@a@@", "d e f\n"},
+		{text, "qadwq@a2@@", "e f\n"},
+		{text, "qbdwqqadwq@b@@", "e f\n"},
+		{text, "qaiab<Esc>q@a", "ababa b c d e f\n"},
+		// A macro playing another
+		{text, "qbdwqqa@bq@a", "d e f\n"},
+		// A failing key stops the rest of the macro and its repeats
+		{"a b c\nd e f\n", "qadwkq3@a", "c\nd e f\n"},
+		{"a b c\nd e f\n", "jqadwjq3@a", "a b c\nf\n"},
+		// Nothing to play
+		{text, "@a", text},
+		{text, "@@", text},
+	})
+}
+
+func TestPlayMacroStopsFailing(t *testing.T) {
+	e := newTestEditor("a b c\nd e f\n")
+	typeKeys(e, "qadwkq")
+	typeKeys(e, "3@a")
+	if got := e.StatusMsg; !strings.Contains(got, "@a stopped at k") {
+		t.Errorf("a failing macro showed %q", got)
+	}
+	if e.KeyErr != nil || len(e.PendingKeys) > 0 || e.MacroRuns != 0 {
+		t.Errorf("a failing macro left error %v, %d keys and %d runs", e.KeyErr, len(e.PendingKeys), e.MacroRuns)
+	}
+}
+
+func TestPlayMacroCallingItself(t *testing.T) {
+	e := newTestEditor("\n")
+	e.Registers.Set('a', config.Register{Text: "ix<Esc>@a", Type: config.CharWise})
+	typeKeys(e, "@a")
+	if got := strings.Count(string(e.CurrentBuffer.Text.Bytes()), "x"); got != maxMacroRuns {
+		t.Errorf("a macro calling itself ran %d times, want %d", got, maxMacroRuns)
+	}
+	if !strings.Contains(e.StatusMsg, errMacroRecursion.Error()) {
+		t.Errorf("a macro calling itself showed %q", e.StatusMsg)
+	}
+
+	// The editor is back to normal once it stopped
+	typeKeys(e, "ddiy<Esc>")
+	if got := string(e.CurrentBuffer.Text.Bytes()); got != "y\n" || e.MacroRuns != 0 {
+		t.Errorf("after the macro stopped typing left %q with %d runs", got, e.MacroRuns)
+	}
+}
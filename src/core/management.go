@@ -5,10 +5,10 @@ import (
 	"path/filepath"
 
 	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/keys"
 )
 
-func EditorCreateFileCallback(buf []rune, c rune, e *config.Editor, trigger bool) {
-	buf = append(buf, c)
+func EditorCreateFileCallback(buf []rune, c keys.Key, e *config.Editor, trigger bool) {
 	if trigger {
 		fileName := string(buf)
 
@@ -29,8 +29,7 @@ func EditorCreateFileCallback(buf []rune, c rune, e *config.Editor, trigger bool
 	}
 }
 
-func EditorRenameCallback(buf []rune, c rune, e *config.Editor, trigger bool) {
-	buf = append(buf, c)
+func EditorRenameCallback(buf []rune, c keys.Key, e *config.Editor, trigger bool) {
 	if trigger {
 
 		oldName := e.FileBrowserItems[e.Cy-len(e.InstructionsLines())].Name
@@ -46,7 +45,9 @@ func EditorRenameCallback(buf []rune, c rune, e *config.Editor, trigger bool) {
 
 		if EditorConfirmationPrompt(confirmationMessage, e) {
 			// Perform the rename operation here
-			EditorRenameFile(e, oldName, newName)
+			if err := EditorRenameFile(e, oldName, newName); err != nil {
+				EditorSetStatusMessage(e, "%s", err.Error())
+			}
 		} else {
 			// User said 'no', cancel the operation
 			EditorSetStatusMessage(e, "Rename operation cancelled.")
@@ -0,0 +1,34 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/constants"
+	"github.com/deanrtaylor1/go-editor/keys"
+)
+
+// MarkKeyHandler handles m{a-z}, which sets a mark at the cursor for ex
+// command ranges to refer to as 'a to 'z.
+func MarkKeyHandler(key keys.Key, e *config.Editor) (rune, bool) {
+	if !key.Printable() {
+		return constants.NO_OP, false
+	}
+	typed := string(append(e.MotionBuffer, key.Rune))
+	_, rest := splitCount(typed)
+
+	switch rest {
+	case "m":
+		e.MotionBuffer = []rune(typed)
+		return constants.NO_OP, true
+	case "m" + string(key.Rune):
+		e.ClearMotionBuffer()
+		if key.Rune < 'a' || key.Rune > 'z' {
+			reportError(e, fmt.Errorf("invalid mark: %c", key.Rune))
+			return constants.NO_OP, true
+		}
+		e.CurrentBuffer.Marks[key.Rune] = config.Point{Row: e.Cy, Col: e.CurrentBuffer.SliceIndex}
+		return constants.NO_OP, true
+	}
+	return constants.NO_OP, false
+}
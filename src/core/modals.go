@@ -7,6 +7,7 @@ import (
 	"github.com/deanrtaylor1/go-editor/config"
 	"github.com/deanrtaylor1/go-editor/constants"
 	"github.com/deanrtaylor1/go-editor/fuzzy"
+	"github.com/deanrtaylor1/go-editor/utils"
 )
 
 func DrawTopLabel(buffer *bytes.Buffer, startX, startY, width int, label, bgColor, textColor string) {
@@ -41,16 +42,13 @@ func DrawContent(buffer *bytes.Buffer, startX, startY, width, height int, e *con
 
 		if dataIndex < len(results) {
 			str := results[dataIndex]
-			strLen := len(str)
 			maxStrLen := width - 2
 
-			if strLen > maxStrLen {
-				str = str[:maxStrLen]
-			}
+			str = utils.TruncateToWidth(str, maxStrLen)
 
 			buffer.WriteString(str)
 
-			remainingSpace := maxStrLen - len(str)
+			remainingSpace := maxStrLen - utils.StringWidth(str)
 
 			buffer.WriteString(strings.Repeat(" ", remainingSpace))
 		} else {
@@ -102,9 +100,7 @@ func DrawFuzzyContent(buffer *bytes.Buffer, startX, startY, width, height int, e
 
 			// Ensure that the string does not exceed the defined width
 			maxStrWidth := width - 2
-			if len(str) > maxStrWidth {
-				str = str[:maxStrWidth]
-			}
+			str = utils.TruncateToWidth(str, maxStrWidth)
 
 			for j, char := range str {
 				if contains(matchedIndexes, j) {
@@ -117,7 +113,7 @@ func DrawFuzzyContent(buffer *bytes.Buffer, startX, startY, width, height int, e
 			}
 
 			// Fill the remaining space with empty characters
-			remainingSpace := maxStrWidth - len(str)
+			remainingSpace := maxStrWidth - utils.StringWidth(str)
 			buffer.WriteString(strings.Repeat(" ", remainingSpace))
 		} else {
 			// If the index doesn't exist, fill the entire space with empty characters
@@ -150,7 +146,11 @@ func DrawContentArea(buffer *bytes.Buffer, startX, startY, width, height int, e
 		DrawFuzzyContent(buffer, startX, startY, width, height, e)
 
 	default:
-		DrawFuzzyContent(buffer, startX, startY, width, height, e)
+		if _, ok := e.Modal.Results.([]string); ok {
+			DrawContent(buffer, startX, startY, width, height, e)
+		} else {
+			DrawFuzzyContent(buffer, startX, startY, width, height, e)
+		}
 	}
 
 	if !e.Modal.ModalDrawn {
@@ -187,11 +187,11 @@ func DrawSearchBox(buffer *bytes.Buffer, startX, startY, width int, e *config.Ed
 	buffer.WriteString(constants.VERTICAL_LINE) // Left vertical line
 
 	// Convert ModalInput to string and write it inside the search box
-	inputText := string(e.Modal.ModalInput[e.Modal.SearchColOffset:])
+	inputText := utils.TruncateToWidth(string(e.Modal.ModalInput[e.Modal.SearchColOffset:]), width-2)
 	buffer.WriteString(inputText)
 
 	// Fill the remaining space with empty characters
-	remainingSpace := width - 2 - len(inputText)
+	remainingSpace := width - 2 - utils.StringWidth(inputText)
 	buffer.WriteString(strings.Repeat(" ", remainingSpace))
 
 	buffer.WriteString(constants.VERTICAL_LINE) // Right vertical line
@@ -203,22 +203,34 @@ func DrawSearchBox(buffer *bytes.Buffer, startX, startY, width int, e *config.Ed
 	buffer.WriteString(constants.RIGHT_BOTTOM_CORNER)                      // Right-bottom corner of the search box
 }
 
+// modalBounds returns where the modal is drawn, in terminal coordinates
+// starting at 1.
+func modalBounds(e *config.Editor) (startX, startY, width, height int) {
+	width = e.ScreenCols * 85 / 100
+	height = e.ScreenRows * 90 / 100
+	// The borders need at least two columns
+	if width < 2 {
+		width = 2
+	}
+	startX = (e.ScreenCols - width) / 2
+	startY = ((e.ScreenRows - height) / 2) + 2
+	return startX, startY, width, height
+}
+
 func EditorDrawModal(buffer *bytes.Buffer, e *config.Editor) string {
-	// Calculate the dimensions and position of the modal
-	modalAvailableWidth := e.ScreenCols * 85 / 100
-	modalAvailableHeight := e.ScreenRows * 90 / 100
-	modalWidth := modalAvailableWidth
-	modalHeight := modalAvailableHeight
-	startX := (e.ScreenCols - modalWidth) / 2
-	startY := ((e.ScreenRows - modalHeight) / 2) + 2
+	startX, startY, modalWidth, modalHeight := modalBounds(e)
 
 	label1 := "Results"
+	if e.Modal.Title != "" {
+		label1 = e.Modal.Title
+	}
 	label1Start := startX + (modalWidth-len(label1))/2
 
+	ModalScroll(e, modalHeight-6, modalWidth-2)
 	DrawContentArea(buffer, startX, startY, modalWidth, modalHeight, e)
 	DrawTopLabel(buffer, label1Start, startY, len(label1), label1, constants.BACKGROUND_BLUE, constants.TEXT_BLACK)
 
-	searchBoxStartY := modalAvailableHeight + 1
+	searchBoxStartY := modalHeight + 1
 	searchBoxWidth := modalWidth
 
 	DrawSearchBox(buffer, startX, searchBoxStartY, searchBoxWidth, e)
@@ -228,15 +240,39 @@ func EditorDrawModal(buffer *bytes.Buffer, e *config.Editor) string {
 
 	DrawTopLabel(buffer, label2Start, searchBoxStartY, len(label2), label2, constants.BACKGROUND_YELLOW, constants.TEXT_BLACK)
 
-	cursorX := startX + 1 + e.Modal.CursorPosition
+	cursorX := startX + 1 + utils.StringWidth(string(e.Modal.ModalInput[e.Modal.SearchColOffset:e.Modal.CursorPosition]))
 	cursorY := searchBoxStartY + 1
 	return SetCursorPos(cursorY, cursorX)
 }
 
+// ModalScroll keeps the selected item within the visible result rows and the
+// input cursor within the search box, which both change size with the
+// terminal.
+func ModalScroll(e *config.Editor, visibleRows, inputWidth int) {
+	m := &e.Modal
+	if visibleRows < 1 {
+		visibleRows = 1
+	}
+	if m.ItemIndex < m.DataRowOffset {
+		m.DataRowOffset = m.ItemIndex
+	}
+	if m.ItemIndex >= m.DataRowOffset+visibleRows {
+		m.DataRowOffset = m.ItemIndex - visibleRows + 1
+	}
+
+	if m.SearchColOffset > m.CursorPosition {
+		m.SearchColOffset = m.CursorPosition
+	}
+	for m.SearchColOffset < m.CursorPosition && utils.StringWidth(string(m.ModalInput[m.SearchColOffset:m.CursorPosition])) >= inputWidth {
+		m.SearchColOffset = utils.NextGrapheme(m.ModalInput, m.SearchColOffset)
+	}
+}
+
 func insertCharModalInput(char rune, e *config.Editor) {
-	e.Modal.ModalInput = append(e.Modal.ModalInput, 0)
-	copy(e.Modal.ModalInput[e.Modal.CursorPosition+1:], e.Modal.ModalInput)
-	e.Modal.ModalInput[e.Modal.CursorPosition] = byte(char)
+	encoded := []byte(string(char))
+	input := append([]byte{}, e.Modal.ModalInput[:e.Modal.CursorPosition]...)
+	input = append(input, encoded...)
+	e.Modal.ModalInput = append(input, e.Modal.ModalInput[e.Modal.CursorPosition:]...)
 
-	e.Modal.CursorPosition++
+	e.Modal.CursorPosition += len(encoded)
 }
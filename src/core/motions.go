@@ -0,0 +1,469 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/constants"
+	"github.com/deanrtaylor1/go-editor/keys"
+	"github.com/deanrtaylor1/go-editor/utils"
+)
+
+// Motion is a cursor movement of normal and visual mode that takes a count,
+// like 5j or 3w.
+type Motion struct {
+	// Linewise motions cover whole rows when an operator is applied to them
+	Linewise bool
+	// Inclusive motions include the character they stop on
+	Inclusive bool
+	// Absolute motions go to a fixed place, the others fail when they can't
+	// move at all
+	Absolute bool
+	// Target returns where the motion goes from p. count is 0 when none was
+	// typed, most motions then move once.
+	Target func(e *config.Editor, p config.Point, count int) config.Point
+}
+
+// Motions holds every motion by the keys that run it. The motion map refers
+// to these, so a motion can also be bound to other keys.
+var Motions = map[string]Motion{
+	"h":  {Target: motionLeft},
+	"l":  {Target: motionRight},
+	"j":  {Linewise: true, Target: motionDown},
+	"k":  {Linewise: true, Target: motionUp},
+	"w":  {Target: wordMotion(wordStart, false)},
+	"W":  {Target: wordMotion(wordStart, true)},
+	"b":  {Target: wordMotion(wordBack, false)},
+	"B":  {Target: wordMotion(wordBack, true)},
+	"e":  {Inclusive: true, Target: wordMotion(wordEnd, false)},
+	"E":  {Inclusive: true, Target: wordMotion(wordEnd, true)},
+	"0":  {Absolute: true, Target: motionLineStart},
+	"^":  {Absolute: true, Target: motionFirstNonBlank},
+	"$":  {Inclusive: true, Absolute: true, Target: motionLineEnd},
+	"gg": {Linewise: true, Absolute: true, Target: motionFirstLine},
+	"G":  {Linewise: true, Absolute: true, Target: motionLastLine},
+	"{":  {Target: motionParagraphBack},
+	"}":  {Target: motionParagraphForward},
+}
+
+// MotionAction returns a motion map entry that runs the motion called name
+// with the count typed before it.
+func MotionAction(e *config.Editor, name string) func() {
+	motion := Motions[name]
+	return func() {
+		MoveByMotion(e, motion, e.MotionCount)
+	}
+}
+
+// Sequences returns the sequence map of normal mode for everything core
+// runs itself, every motion along with undo travel, the undo tree and line
+// ending conversion. The mappings package adds the ones that open the file
+// browser and finders.
+func Sequences(e *config.Editor) map[string]func() {
+	sequences := map[string]func(){
+		"g-": func() {
+			UndoSteps(e, -utils.Max(e.MotionCount, 1))
+		},
+		"g+": func() {
+			UndoSteps(e, utils.Max(e.MotionCount, 1))
+		},
+		" ut": func() {
+			ShowUndoTree(e)
+		},
+		" fl": func() {
+			ConvertLineEndings(e, constants.END_OF_LINE_LF)
+		},
+		" fc": func() {
+			ConvertLineEndings(e, constants.END_OF_LINE_CRLF)
+		},
+	}
+	for name := range Motions {
+		sequences[name] = MotionAction(e, name)
+	}
+	return sequences
+}
+
+// MoveByMotion moves the cursor with m. In visual mode the selection follows
+// the cursor.
+func MoveByMotion(e *config.Editor, m Motion, count int) {
+	to := m.Target(e, cursorPoint(e), count)
+	e.Cy = to.Row
+	e.CurrentBuffer.SliceIndex = to.Col
+	e.SyncCx()
+	if e.EditorMode == constants.EDITOR_MODE_VISUAL {
+		e.MoveSelection()
+	}
+}
+
+// MotionKeyHandler collects typed characters into a count and a motion or
+// mapped sequence, and runs it once it is complete. sequences are the
+// mappings available on top of the motions, nil for none. It reports whether
+// the key was used; keys that start no sequence are left to the mode.
+func MotionKeyHandler(key keys.Key, e *config.Editor, sequences map[string]func()) (rune, bool) {
+	if !key.Printable() {
+		e.ClearMotionBuffer()
+		return constants.NO_OP, false
+	}
+	typed := append(e.MotionBuffer, key.Rune)
+	count, name := splitCount(string(typed))
+	if name == "" {
+		e.MotionBuffer = typed
+		return constants.NO_OP, true
+	}
+
+	if motion, ok := Motions[name]; ok {
+		e.ClearMotionBuffer()
+		from := cursorPoint(e)
+		MoveByMotion(e, motion, count)
+		if cursorPoint(e) == from && !motion.Absolute {
+			e.KeyErr = errMotionFailed
+		}
+		// A short move only needs the rows around the cursor redrawn
+		if utils.Abs(e.Cy-from.Row) <= 1 {
+			return constants.PARTIAL_REFRESH, true
+		}
+		return constants.INITIAL_REFRESH, true
+	}
+	if action, ok := sequences[name]; ok {
+		e.ClearMotionBuffer()
+		e.MotionCount = count
+		action()
+		e.MotionCount = 0
+		return constants.INITIAL_REFRESH, true
+	}
+	if hasSequencePrefix(name, sequences) {
+		e.MotionBuffer = typed
+		return constants.NO_OP, true
+	}
+
+	// Not a sequence after all, the key is handled on its own
+	e.ClearMotionBuffer()
+	return constants.NO_OP, false
+}
+
+// splitCount separates a leading count from the rest of a typed sequence. A
+// count can't start with 0, a lone 0 is the motion to the start of the line.
+func splitCount(typed string) (int, string) {
+	digits := 0
+	for digits < len(typed) && typed[digits] >= '0' && typed[digits] <= '9' {
+		if digits == 0 && typed[0] == '0' {
+			break
+		}
+		digits++
+	}
+	if digits == 0 {
+		return 0, typed
+	}
+	count, err := strconv.Atoi(typed[:digits])
+	if err != nil {
+		// Too large to be meant
+		count = 0
+	}
+	return count, typed[digits:]
+}
+
+func hasSequencePrefix(prefix string, sequences map[string]func()) bool {
+	for name := range Motions {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	for name := range sequences {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func cursorPoint(e *config.Editor) config.Point {
+	return config.Point{Row: e.Cy, Col: e.CurrentBuffer.SliceIndex}
+}
+
+func countOrOne(count int) int {
+	if count < 1 {
+		return 1
+	}
+	return count
+}
+
+func lastRow(e *config.Editor) int {
+	return utils.Max(e.CurrentBuffer.NumRows-1, 0)
+}
+
+func rowChars(e *config.Editor, row int) []byte {
+	if row < 0 || row >= e.CurrentBuffer.NumRows {
+		return nil
+	}
+	return e.CurrentBuffer.Row(row).Chars
+}
+
+// lastChar is the index of the last character of a row, 0 for an empty one.
+func lastChar(chars []byte) int {
+	if len(chars) == 0 {
+		return 0
+	}
+	return utils.PrevGrapheme(chars, len(chars))
+}
+
+func firstNonBlank(chars []byte) int {
+	for i, c := range chars {
+		if c != ' ' && c != '\t' {
+			return i
+		}
+	}
+	return lastChar(chars)
+}
+
+func motionLeft(e *config.Editor, p config.Point, count int) config.Point {
+	chars := rowChars(e, p.Row)
+	for i := 0; i < countOrOne(count) && p.Col > 0; i++ {
+		p.Col = utils.PrevGrapheme(chars, p.Col)
+	}
+	return p
+}
+
+// motionRight stops at the end of the row, where typing appends to it.
+func motionRight(e *config.Editor, p config.Point, count int) config.Point {
+	chars := rowChars(e, p.Row)
+	for i := 0; i < countOrOne(count) && p.Col < len(chars); i++ {
+		p.Col = utils.NextGrapheme(chars, p.Col)
+	}
+	return p
+}
+
+// verticalMotion moves to another row, keeping the cursor's screen column.
+func verticalMotion(e *config.Editor, row int) config.Point {
+	row = utils.Max(utils.Min(row, lastRow(e)), 0)
+	if row >= e.CurrentBuffer.NumRows {
+		return config.Point{Row: row}
+	}
+	col := e.CurrentBuffer.Row(row).ByteIndexForColumn(e.Cx-e.LineNumberWidth, e.CurrentBuffer.Options.TabWidth)
+	return config.Point{Row: row, Col: col}
+}
+
+func motionDown(e *config.Editor, p config.Point, count int) config.Point {
+	return verticalMotion(e, p.Row+countOrOne(count))
+}
+
+func motionUp(e *config.Editor, p config.Point, count int) config.Point {
+	return verticalMotion(e, p.Row-countOrOne(count))
+}
+
+func motionLineStart(e *config.Editor, p config.Point, count int) config.Point {
+	return config.Point{Row: p.Row}
+}
+
+func motionFirstNonBlank(e *config.Editor, p config.Point, count int) config.Point {
+	return config.Point{Row: p.Row, Col: firstNonBlank(rowChars(e, p.Row))}
+}
+
+// motionLineEnd goes to the last character, count-1 rows further down.
+func motionLineEnd(e *config.Editor, p config.Point, count int) config.Point {
+	row := utils.Min(p.Row+countOrOne(count)-1, lastRow(e))
+	return config.Point{Row: row, Col: lastChar(rowChars(e, row))}
+}
+
+// motionFirstLine goes to the first row, or row count when one was typed.
+func motionFirstLine(e *config.Editor, p config.Point, count int) config.Point {
+	row := utils.Max(utils.Min(count-1, lastRow(e)), 0)
+	return config.Point{Row: row, Col: firstNonBlank(rowChars(e, row))}
+}
+
+// motionLastLine goes to the last row, or row count when one was typed.
+func motionLastLine(e *config.Editor, p config.Point, count int) config.Point {
+	row := lastRow(e)
+	if count > 0 {
+		row = utils.Min(count-1, row)
+	}
+	return config.Point{Row: row, Col: firstNonBlank(rowChars(e, row))}
+}
+
+// Paragraphs are separated by empty rows. Without another one the motions
+// stop at the start or end of the buffer.
+func motionParagraphBack(e *config.Editor, p config.Point, count int) config.Point {
+	row := p.Row
+	for i := 0; i < countOrOne(count) && row > 0; i++ {
+		row--
+		for row > 0 && len(rowChars(e, row)) > 0 {
+			row--
+		}
+	}
+	return config.Point{Row: row}
+}
+
+func motionParagraphForward(e *config.Editor, p config.Point, count int) config.Point {
+	row := p.Row
+	last := lastRow(e)
+	for i := 0; i < countOrOne(count) && row < last; i++ {
+		row++
+		for row < last && len(rowChars(e, row)) > 0 {
+			row++
+		}
+	}
+	if row == last && len(rowChars(e, row)) > 0 {
+		return config.Point{Row: row, Col: lastChar(rowChars(e, row))}
+	}
+	return config.Point{Row: row}
+}
+
+// Character classes for word motions. A word is a run of letters, digits and
+// underscores or a run of other non-blank characters, a WORD is any run of
+// non-blank characters. Empty rows count as a word of their own.
+const (
+	classBlank = iota
+	classPunctuation
+	classWord
+	classEmptyRow
+)
+
+func charClass(e *config.Editor, p config.Point, bigWord bool) int {
+	chars := rowChars(e, p.Row)
+	if len(chars) == 0 {
+		return classEmptyRow
+	}
+	if p.Col >= len(chars) {
+		return classBlank
+	}
+	r, _ := utf8.DecodeRune(chars[p.Col:])
+	switch {
+	case r == ' ' || r == '\t':
+		return classBlank
+	case bigWord:
+		return classWord
+	case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+		return classWord
+	}
+	return classPunctuation
+}
+
+// nextChar steps to the next character, from the end of a row to the start
+// of the next one. It reports false at the end of the buffer.
+func nextChar(e *config.Editor, p config.Point) (config.Point, bool) {
+	chars := rowChars(e, p.Row)
+	if next := utils.NextGrapheme(chars, p.Col); next < len(chars) {
+		return config.Point{Row: p.Row, Col: next}, true
+	}
+	if p.Row+1 >= e.CurrentBuffer.NumRows {
+		return p, false
+	}
+	return config.Point{Row: p.Row + 1}, true
+}
+
+// prevChar steps to the previous character, from the start of a row to the
+// last character of the one above. It reports false at the start of the
+// buffer.
+func prevChar(e *config.Editor, p config.Point) (config.Point, bool) {
+	if p.Col > 0 {
+		return config.Point{Row: p.Row, Col: utils.PrevGrapheme(rowChars(e, p.Row), p.Col)}, true
+	}
+	if p.Row == 0 {
+		return p, false
+	}
+	return config.Point{Row: p.Row - 1, Col: lastChar(rowChars(e, p.Row-1))}, true
+}
+
+func wordMotion(step func(*config.Editor, config.Point, bool) config.Point, bigWord bool) func(*config.Editor, config.Point, int) config.Point {
+	return func(e *config.Editor, p config.Point, count int) config.Point {
+		for i := 0; i < countOrOne(count); i++ {
+			p = step(e, p, bigWord)
+		}
+		return p
+	}
+}
+
+// wordStart is w, the start of the next word.
+func wordStart(e *config.Editor, p config.Point, bigWord bool) config.Point {
+	p, _ = nextWordStart(e, p, bigWord)
+	return p
+}
+
+// nextWordStart is wordStart, reporting false when it ran out of text and
+// stopped at the end of the buffer instead.
+func nextWordStart(e *config.Editor, p config.Point, bigWord bool) (config.Point, bool) {
+	class := charClass(e, p, bigWord)
+	if class == classBlank || class == classEmptyRow {
+		q, ok := nextChar(e, p)
+		if !ok {
+			return p, false
+		}
+		p = q
+	} else {
+		// Leave the current word, a row break ends it too
+		for {
+			q, ok := nextChar(e, p)
+			if !ok {
+				return p, false
+			}
+			rowChanged := q.Row != p.Row
+			p = q
+			if rowChanged || charClass(e, p, bigWord) != class {
+				break
+			}
+		}
+	}
+	for charClass(e, p, bigWord) == classBlank {
+		q, ok := nextChar(e, p)
+		if !ok {
+			return p, false
+		}
+		p = q
+	}
+	return p, true
+}
+
+// wordEnd is e, the end of the current word or the next one when already at
+// the end.
+func wordEnd(e *config.Editor, p config.Point, bigWord bool) config.Point {
+	q, ok := nextChar(e, p)
+	if !ok {
+		return p
+	}
+	p = q
+	for class := charClass(e, p, bigWord); class == classBlank || class == classEmptyRow; class = charClass(e, p, bigWord) {
+		q, ok := nextChar(e, p)
+		if !ok {
+			return p
+		}
+		p = q
+	}
+	class := charClass(e, p, bigWord)
+	for {
+		q, ok := nextChar(e, p)
+		if !ok || q.Row != p.Row || charClass(e, q, bigWord) != class {
+			return p
+		}
+		p = q
+	}
+}
+
+// wordBack is b, the start of the current word or the previous one when
+// already at the start.
+func wordBack(e *config.Editor, p config.Point, bigWord bool) config.Point {
+	q, ok := prevChar(e, p)
+	if !ok {
+		return p
+	}
+	p = q
+	for charClass(e, p, bigWord) == classBlank {
+		q, ok := prevChar(e, p)
+		if !ok {
+			return p
+		}
+		p = q
+	}
+	class := charClass(e, p, bigWord)
+	if class == classEmptyRow {
+		return p
+	}
+	for {
+		q, ok := prevChar(e, p)
+		if !ok || q.Row != p.Row || charClass(e, q, bigWord) != class {
+			return p
+		}
+		p = q
+	}
+}
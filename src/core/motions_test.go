@@ -0,0 +1,177 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/deanrtaylor1/go-editor/config"
+)
+
+// motionText has an empty row, indented rows, punctuation and wide
+// characters for the motions to cross.
+const motionText = "one two.three\n\n  four  five\né世 x\n"
+
+func TestMotionTargets(t *testing.T) {
+	at := func(row, col int) config.Point { return config.Point{Row: row, Col: col} }
+	tests := []struct {
+		name  string
+		from  config.Point
+		count int
+		want  config.Point
+	}{
+		{"h", at(0, 2), 0, at(0, 1)},
+		{"h", at(0, 0), 0, at(0, 0)},
+		{"h", at(0, 5), 9, at(0, 0)},
+		{"l", at(0, 0), 3, at(0, 3)},
+		{"l", at(0, 10), 9, at(0, 13)},
+		{"l", at(3, 0), 0, at(3, 2)},
+		{"l", at(3, 2), 0, at(3, 5)},
+		{"h", at(3, 5), 0, at(3, 2)},
+
+		// w stops at each change between words and punctuation, W only at
+		// blanks, and both stop on an empty row
+		{"w", at(0, 0), 0, at(0, 4)},
+		{"w", at(0, 4), 0, at(0, 7)},
+		{"w", at(0, 4), 2, at(0, 8)},
+		{"W", at(0, 4), 0, at(1, 0)},
+		{"w", at(0, 8), 0, at(1, 0)},
+		{"w", at(1, 0), 0, at(2, 2)},
+		{"w", at(2, 2), 0, at(2, 8)},
+		{"w", at(2, 8), 0, at(3, 0)},
+		{"w", at(3, 0), 0, at(3, 6)},
+		{"w", at(3, 6), 0, at(3, 6)},
+		{"w", at(0, 0), 100, at(3, 6)},
+		{"b", at(0, 8), 0, at(0, 7)},
+		{"B", at(0, 8), 0, at(0, 4)},
+		{"b", at(2, 2), 0, at(1, 0)},
+		{"b", at(1, 0), 0, at(0, 8)},
+		{"b", at(3, 6), 0, at(3, 0)},
+		{"b", at(0, 0), 0, at(0, 0)},
+		{"b", at(3, 6), 100, at(0, 0)},
+		{"e", at(0, 0), 0, at(0, 2)},
+		{"e", at(0, 2), 0, at(0, 6)},
+		{"E", at(0, 2), 0, at(0, 12)},
+		{"e", at(0, 12), 0, at(2, 5)},
+		{"e", at(2, 8), 0, at(2, 11)},
+		{"e", at(2, 11), 0, at(3, 2)},
+		{"e", at(3, 6), 0, at(3, 6)},
+
+		{"0", at(2, 5), 0, at(2, 0)},
+		{"^", at(2, 9), 0, at(2, 2)},
+		{"^", at(1, 0), 0, at(1, 0)},
+		{"$", at(0, 0), 0, at(0, 12)},
+		{"$", at(0, 0), 3, at(2, 11)},
+		{"$", at(3, 0), 0, at(3, 6)},
+		{"$", at(1, 0), 0, at(1, 0)},
+		{"$", at(2, 0), 100, at(3, 6)},
+		{"gg", at(3, 0), 0, at(0, 0)},
+		{"gg", at(0, 0), 3, at(2, 2)},
+		{"gg", at(0, 0), 100, at(3, 0)},
+		{"G", at(0, 0), 0, at(3, 0)},
+		{"G", at(3, 0), 3, at(2, 2)},
+		{"G", at(3, 0), 1, at(0, 0)},
+
+		{"}", at(0, 5), 0, at(1, 0)},
+		{"}", at(1, 0), 0, at(3, 6)},
+		{"}", at(0, 0), 9, at(3, 6)},
+		{"{", at(3, 2), 0, at(1, 0)},
+		{"{", at(1, 0), 0, at(0, 0)},
+		{"{", at(3, 2), 9, at(0, 0)},
+	}
+	for _, tt := range tests {
+		e := newTestEditor(motionText)
+		if got := Motions[tt.name].Target(e, tt.from, tt.count); got != tt.want {
+			t.Errorf("%d%s from %v went to %v, want %v", tt.count, tt.name, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestMotionKeys(t *testing.T) {
+	tests := []struct {
+		typed  string
+		want   config.Point
+		failed bool
+	}{
+		{"w", config.Point{Row: 0, Col: 4}, false},
+		{"3w", config.Point{Row: 0, Col: 8}, false},
+		{"4w", config.Point{Row: 1, Col: 0}, false},
+		{"jj", config.Point{Row: 2, Col: 0}, false},
+		{"$j", config.Point{Row: 1, Col: 0}, false},
+		{"5j", config.Point{Row: 3, Col: 0}, false},
+		{"G", config.Point{Row: 3, Col: 0}, false},
+		{"3G", config.Point{Row: 2, Col: 2}, false},
+		{"Ggg", config.Point{Row: 0, Col: 0}, false},
+		{"2}", config.Point{Row: 3, Col: 6}, false},
+		// Keeping the screen column across wide characters
+		{"3jl", config.Point{Row: 3, Col: 2}, false},
+		{"3jlk", config.Point{Row: 2, Col: 1}, false},
+		{"3jllk", config.Point{Row: 2, Col: 3}, false},
+		// A count too large to be meant moves once
+		{"99999999999999999999w", config.Point{Row: 0, Col: 4}, false},
+		// Motions that can't move fail, which stops a macro, except the ones
+		// that go to a fixed place
+		{"h", config.Point{Row: 0, Col: 0}, true},
+		{"k", config.Point{Row: 0, Col: 0}, true},
+		{"b", config.Point{Row: 0, Col: 0}, true},
+		{"Gj", config.Point{Row: 3, Col: 0}, true},
+		{"G$w", config.Point{Row: 3, Col: 6}, true},
+		{"0", config.Point{Row: 0, Col: 0}, false},
+		{"gg", config.Point{Row: 0, Col: 0}, false},
+	}
+	for _, tt := range tests {
+		e := newTestEditor(motionText)
+		typeKeys(e, tt.typed)
+		if got := cursorPoint(e); got != tt.want {
+			t.Errorf("%q left the cursor at %v, want %v", tt.typed, got, tt.want)
+		}
+		if failed := e.KeyErr == errMotionFailed; failed != tt.failed {
+			t.Errorf("%q failed %v, want %v", tt.typed, failed, tt.failed)
+		}
+		if len(e.MotionBuffer) > 0 {
+			t.Errorf("%q left %q typed", tt.typed, string(e.MotionBuffer))
+		}
+	}
+}
+
+func TestMotionKeysExtendSelection(t *testing.T) {
+	tests := []struct {
+		typed      string
+		start, end config.Point
+	}{
+		{"vw", config.Point{}, config.Point{Row: 0, Col: 4}},
+		{"v2e", config.Point{}, config.Point{Row: 0, Col: 6}},
+		{"wvb", config.Point{Row: 0, Col: 4}, config.Point{}},
+		{"vjj$", config.Point{}, config.Point{Row: 2, Col: 11}},
+		{"Vj", config.Point{}, config.Point{Row: 1, Col: 0}},
+	}
+	for _, tt := range tests {
+		e := newTestEditor(motionText)
+		typeKeys(e, tt.typed)
+		b := e.CurrentBuffer
+		if b.SelectionStart != tt.start || b.SelectionEnd != tt.end {
+			t.Errorf("%q selected %v to %v, want %v to %v", tt.typed, b.SelectionStart, b.SelectionEnd, tt.start, tt.end)
+		}
+	}
+}
+
+func TestSplitCount(t *testing.T) {
+	tests := []struct {
+		typed string
+		count int
+		rest  string
+	}{
+		{"", 0, ""},
+		{"w", 0, "w"},
+		{"3w", 3, "w"},
+		{"12dd", 12, "dd"},
+		{"0", 0, "0"},
+		{"10", 10, ""},
+		{"20j", 20, "j"},
+		{"99999999999999999999j", 0, "j"},
+	}
+	for _, tt := range tests {
+		count, rest := splitCount(tt.typed)
+		if count != tt.count || rest != tt.rest {
+			t.Errorf("splitCount(%q) = %d, %q, want %d, %q", tt.typed, count, rest, tt.count, tt.rest)
+		}
+	}
+}
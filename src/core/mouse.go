@@ -0,0 +1,167 @@
+package core
+
+import (
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/constants"
+	"github.com/deanrtaylor1/go-editor/fuzzy"
+	"github.com/deanrtaylor1/go-editor/keys"
+	"github.com/deanrtaylor1/go-editor/utils"
+)
+
+const wheelScrollLines = 3
+
+// MouseHandler acts on a mouse event in whatever is under it, an open modal,
+// the file browser or the text, and returns the refresh hint for the screen.
+func MouseHandler(e *config.Editor, m keys.Mouse) rune {
+	if e.ModalOpen {
+		return modalMouse(e, m)
+	}
+	if e.IsBrowsingFiles() {
+		return fileBrowserMouse(e, m)
+	}
+	return textMouse(e, m)
+}
+
+func textMouse(e *config.Editor, m keys.Mouse) rune {
+	switch m.Button {
+	case keys.MouseWheelUp:
+		scrollText(e, -wheelScrollLines)
+		return constants.INITIAL_REFRESH
+	case keys.MouseWheelDown:
+		scrollText(e, wheelScrollLines)
+		return constants.INITIAL_REFRESH
+	case keys.MouseLeft:
+	default:
+		return constants.NO_OP
+	}
+
+	switch m.Action {
+	case keys.MousePress:
+		// The status and message bars are below the text
+		if m.Y >= e.ScreenRows {
+			return constants.NO_OP
+		}
+		if e.EditorMode == constants.EDITOR_MODE_VISUAL {
+			e.ClearSelection()
+			e.SetMode(constants.EDITOR_MODE_NORMAL)
+		}
+		moveCursorToCell(e, m.X, m.Y)
+	case keys.MouseDrag:
+		// Dragging starts a selection where the button went down
+		if e.EditorMode != constants.EDITOR_MODE_VISUAL {
+			e.ClearMotionBuffer()
+			e.SetMode(constants.EDITOR_MODE_VISUAL)
+			e.HighlightSelection()
+		}
+		// Dragging past the bottom edge scrolls down a line at a time
+		moveCursorToCell(e, m.X, utils.Min(m.Y, e.ScreenRows))
+		e.MoveSelection()
+	default:
+		return constants.NO_OP
+	}
+	return constants.INITIAL_REFRESH
+}
+
+// moveCursorToCell puts the cursor on the character drawn at screen cell x, y,
+// or the nearest one when the cell is past the end of a row or the buffer.
+func moveCursorToCell(e *config.Editor, x, y int) {
+	row := utils.Min(e.RowOff+y, e.CurrentBuffer.NumRows-1)
+	e.Cy = utils.Max(row, 0)
+	e.Cx = e.LineNumberWidth + e.ColOff + utils.Max(x-e.LineNumberWidth, 0)
+	e.SyncSliceIndex()
+}
+
+// scrollText moves the view by lines rows. The cursor is carried along when it
+// would leave the screen, otherwise EditorScroll would scroll straight back.
+func scrollText(e *config.Editor, lines int) {
+	maxRowOff := utils.Max(e.CurrentBuffer.NumRows-1, 0)
+	e.RowOff = utils.Max(utils.Min(e.RowOff+lines, maxRowOff), 0)
+
+	cy := utils.Max(utils.Min(e.Cy, e.RowOff+e.ScreenRows-1), e.RowOff)
+	if cy == e.Cy {
+		return
+	}
+	e.Cy = cy
+	e.SyncSliceIndex()
+	if e.EditorMode == constants.EDITOR_MODE_VISUAL {
+		e.MoveSelection()
+	}
+}
+
+func fileBrowserMouse(e *config.Editor, m keys.Mouse) rune {
+	instructions := len(e.InstructionsLines())
+	if len(e.FileBrowserItems) == 0 {
+		return constants.NO_OP
+	}
+	lastCy := instructions + len(e.FileBrowserItems) - 1
+
+	switch m.Button {
+	case keys.MouseWheelUp:
+		e.Cy = utils.Max(e.Cy-wheelScrollLines, instructions)
+		return constants.INITIAL_REFRESH
+	case keys.MouseWheelDown:
+		e.Cy = utils.Min(e.Cy+wheelScrollLines, lastCy)
+		return constants.INITIAL_REFRESH
+	case keys.MouseLeft:
+		if m.Action != keys.MousePress {
+			return constants.NO_OP
+		}
+	default:
+		return constants.NO_OP
+	}
+
+	cy := m.Y + e.RowOff
+	if m.Y < instructions || cy > lastCy {
+		return constants.NO_OP
+	}
+	// Clicking the entry that is already selected opens it
+	if cy == e.Cy {
+		return FileBrowserEventsHandler(keys.Enter, e)
+	}
+	e.Cy = cy
+	e.Cx = 0
+	return constants.INITIAL_REFRESH
+}
+
+func modalMouse(e *config.Editor, m keys.Mouse) rune {
+	switch m.Button {
+	case keys.MouseWheelUp:
+		return ModalModeEventsHandler(keys.Up, e)
+	case keys.MouseWheelDown:
+		return ModalModeEventsHandler(keys.Down, e)
+	case keys.MouseLeft:
+		if m.Action != keys.MousePress {
+			return constants.NO_OP
+		}
+	default:
+		return constants.NO_OP
+	}
+
+	// Results are drawn on the rows between the top and bottom border
+	startX, startY, width, height := modalBounds(e)
+	row := m.Y + 1 - startY
+	col := m.X + 1
+	if row < 1 || row >= height-5 || col <= startX || col >= startX+width-1 {
+		return constants.NO_OP
+	}
+	item := row - 1 + e.Modal.DataRowOffset
+	if item >= modalResultCount(e) {
+		return constants.NO_OP
+	}
+	// Clicking the result that is already selected opens it
+	if item == e.Modal.ItemIndex {
+		return ModalModeEventsHandler(keys.Enter, e)
+	}
+	e.Modal.ItemIndex = item
+	return constants.PARTIAL_REFRESH
+}
+
+func modalResultCount(e *config.Editor) int {
+	switch results := e.Modal.Results.(type) {
+	case fuzzy.Matches:
+		return len(results)
+	case []string:
+		return len(results)
+	}
+	return 0
+}
@@ -0,0 +1,212 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/constants"
+	"github.com/deanrtaylor1/go-editor/keys"
+)
+
+func click(x, y int) keys.Mouse { return keys.Mouse{Button: keys.MouseLeft, X: x, Y: y} }
+
+func drag(x, y int) keys.Mouse {
+	return keys.Mouse{Button: keys.MouseLeft, Action: keys.MouseDrag, X: x, Y: y}
+}
+
+func wheel(button keys.MouseButton) keys.Mouse { return keys.Mouse{Button: button} }
+
+func TestMouseClick(t *testing.T) {
+	tests := []struct {
+		rowOff, colOff int
+		// x counts from the start of the text, after the line numbers
+		x, y int
+		want config.Point
+	}{
+		{0, 0, 0, 0, config.Point{Row: 0, Col: 0}},
+		// Anywhere on a tab is the tab
+		{0, 0, 3, 0, config.Point{Row: 0, Col: 0}},
+		{0, 0, 4, 0, config.Point{Row: 0, Col: 1}},
+		{0, 0, 5, 0, config.Point{Row: 0, Col: 2}},
+		// Either half of a wide character is the character
+		{0, 0, 1, 1, config.Point{Row: 1, Col: 0}},
+		{0, 0, 2, 1, config.Point{Row: 1, Col: 3}},
+		{0, 0, 3, 1, config.Point{Row: 1, Col: 3}},
+		{0, 0, 4, 1, config.Point{Row: 1, Col: 6}},
+		{0, 0, 7, 1, config.Point{Row: 1, Col: 6}},
+		{0, 0, 8, 1, config.Point{Row: 1, Col: 7}},
+		// The line numbers are the start of the row
+		{0, 0, -3, 2, config.Point{Row: 2, Col: 0}},
+		// Scrolled down and to the right
+		{1, 0, 2, 0, config.Point{Row: 1, Col: 3}},
+		{0, 2, 0, 0, config.Point{Row: 0, Col: 0}},
+		{0, 2, 2, 0, config.Point{Row: 0, Col: 1}},
+		{0, 2, 1, 1, config.Point{Row: 1, Col: 3}},
+		{1, 2, 1, 0, config.Point{Row: 1, Col: 3}},
+		// Below the end of the buffer is its last row
+		{0, 0, 1, 3, config.Point{Row: 2, Col: 1}},
+		{0, 0, 1, 15, config.Point{Row: 2, Col: 1}},
+		{2, 0, 0, 5, config.Point{Row: 2, Col: 0}},
+	}
+	for _, tt := range tests {
+		e := newTestEditor("\tab\n世界\tx\ncd\n")
+		e.CurrentBuffer.Options.TabWidth = 4
+		e.RowOff, e.ColOff = tt.rowOff, tt.colOff
+		MouseHandler(e, click(e.LineNumberWidth+tt.x, tt.y))
+		if got := cursorPoint(e); got != tt.want {
+			t.Errorf("click at %d, %d scrolled to %d, %d went to %v, want %v", tt.x, tt.y, tt.rowOff, tt.colOff, got, tt.want)
+		}
+	}
+}
+
+func TestMouseClickOutsideText(t *testing.T) {
+	e := newTestEditor("one\ntwo\n")
+	typeKeys(e, "jl")
+
+	// The status and message bars, other buttons and releases do nothing
+	for _, m := range []keys.Mouse{
+		click(e.LineNumberWidth, e.ScreenRows),
+		click(e.LineNumberWidth, e.ScreenRows+1),
+		{Button: keys.MouseRight, X: e.LineNumberWidth},
+		{Button: keys.MouseLeft, Action: keys.MouseRelease, X: e.LineNumberWidth},
+	} {
+		if refresh := MouseHandler(e, m); refresh != constants.NO_OP || cursorPoint(e) != (config.Point{Row: 1, Col: 1}) {
+			t.Errorf("%+v moved the cursor to %v", m, cursorPoint(e))
+		}
+	}
+
+	// An empty buffer has nowhere else to go
+	e = newTestEditor("")
+	MouseHandler(e, click(e.LineNumberWidth+5, 5))
+	if got := cursorPoint(e); got != (config.Point{}) {
+		t.Errorf("click in an empty buffer went to %v", got)
+	}
+}
+
+func TestMouseDrag(t *testing.T) {
+	e := newTestEditor("one two\nthree four\n")
+	x := e.LineNumberWidth
+
+	MouseHandler(e, click(x+1, 0))
+	MouseHandler(e, drag(x+2, 0))
+	MouseHandler(e, drag(x+3, 1))
+	b := e.CurrentBuffer
+	if e.EditorMode != constants.EDITOR_MODE_VISUAL {
+		t.Fatalf("dragging left mode %d, want visual", e.EditorMode)
+	}
+	if b.SelectionStart != (config.Point{Row: 0, Col: 1}) || b.SelectionEnd != (config.Point{Row: 1, Col: 3}) {
+		t.Errorf("dragging selected %v to %v", b.SelectionStart, b.SelectionEnd)
+	}
+
+	// Dragging below the screen stops at the last row of the buffer
+	MouseHandler(e, drag(x, e.ScreenRows+5))
+	if b.SelectionEnd != (config.Point{Row: 1, Col: 0}) {
+		t.Errorf("dragging below the text selected to %v", b.SelectionEnd)
+	}
+
+	// Letting go keeps the selection for an operator, a click drops it
+	MouseHandler(e, keys.Mouse{Button: keys.MouseLeft, Action: keys.MouseRelease, X: x, Y: 0})
+	typeKeys(e, "d")
+	if got := string(b.Text.Bytes()); got != "ohree four\n" {
+		t.Errorf("deleting what was dragged over left %q", got)
+	}
+	typeKeys(e, "v")
+	MouseHandler(e, click(x+2, 0))
+	if e.EditorMode != constants.EDITOR_MODE_NORMAL || cursorPoint(e) != (config.Point{Row: 0, Col: 2}) {
+		t.Errorf("clicking in visual mode left mode %d and the cursor at %v", e.EditorMode, cursorPoint(e))
+	}
+}
+
+func TestMouseWheel(t *testing.T) {
+	e := newTestEditor(strings.Repeat("row\n", 30))
+	b := e.CurrentBuffer
+
+	// Up at the top goes nowhere
+	MouseHandler(e, wheel(keys.MouseWheelUp))
+	if e.RowOff != 0 || e.Cy != 0 {
+		t.Errorf("wheel up at the top scrolled to %d with the cursor on %d", e.RowOff, e.Cy)
+	}
+
+	// The cursor is carried along when it would go off the screen
+	MouseHandler(e, wheel(keys.MouseWheelDown))
+	if e.RowOff != wheelScrollLines || e.Cy != wheelScrollLines {
+		t.Errorf("wheel down scrolled to %d with the cursor on %d, want both %d", e.RowOff, e.Cy, wheelScrollLines)
+	}
+	MouseHandler(e, wheel(keys.MouseWheelUp))
+	if e.RowOff != 0 || e.Cy != wheelScrollLines {
+		t.Errorf("wheel up scrolled to %d with the cursor on %d, want 0 and %d", e.RowOff, e.Cy, wheelScrollLines)
+	}
+
+	// The last row stays on the screen however far it goes
+	for i := 0; i < 20; i++ {
+		MouseHandler(e, wheel(keys.MouseWheelDown))
+	}
+	if last := b.NumRows - 1; e.RowOff != last || e.Cy != last {
+		t.Errorf("wheel down to the end scrolled to %d with the cursor on %d, want both %d", e.RowOff, e.Cy, last)
+	}
+	for i := 0; i < 20; i++ {
+		MouseHandler(e, wheel(keys.MouseWheelUp))
+	}
+	if want := e.ScreenRows - 1; e.RowOff != 0 || e.Cy != want {
+		t.Errorf("wheel up to the start scrolled to %d with the cursor on %d, want 0 and %d", e.RowOff, e.Cy, want)
+	}
+
+	// A selection follows the cursor once it is carried along
+	typeKeys(e, "v")
+	for i := 0; i < 8; i++ {
+		MouseHandler(e, wheel(keys.MouseWheelDown))
+	}
+	if b.SelectionStart.Row != e.ScreenRows-1 || b.SelectionEnd.Row != 8*wheelScrollLines {
+		t.Errorf("wheel in visual mode selected %v to %v", b.SelectionStart, b.SelectionEnd)
+	}
+}
+
+func TestMouseModal(t *testing.T) {
+	e := newTestEditor("")
+	e.ModalOpen = true
+	e.Modal = config.Modal{Type: config.MODAL_TYPE_GENERIC, Data: []string{"a", "b", "c"}, Results: []string{"a", "b", "c"}}
+	startX, startY, width, _ := modalBounds(e)
+	// The bounds count from one, mouse cells from zero, so the first result is
+	// drawn on the row below the top border at startY and the borders are at
+	// startX-1 and startX+width-2
+	first := startY
+	inside := startX + width/2
+
+	tests := []struct {
+		m       keys.Mouse
+		refresh rune
+		item    int
+	}{
+		{click(inside, first+1), constants.PARTIAL_REFRESH, 1},
+		{click(inside, first+2), constants.PARTIAL_REFRESH, 2},
+		{wheel(keys.MouseWheelDown), constants.NO_OP, 2},
+		{wheel(keys.MouseWheelUp), constants.PARTIAL_REFRESH, 1},
+		{wheel(keys.MouseWheelUp), constants.PARTIAL_REFRESH, 0},
+		{wheel(keys.MouseWheelUp), constants.NO_OP, 0},
+		{wheel(keys.MouseWheelDown), constants.PARTIAL_REFRESH, 1},
+		// Past the results, on the border or outside the modal
+		{click(inside, first+3), constants.NO_OP, 1},
+		{click(inside, first-1), constants.NO_OP, 1},
+		{click(startX-1, first), constants.NO_OP, 1},
+		{click(startX+width-2, first), constants.NO_OP, 1},
+		{drag(inside, first), constants.NO_OP, 1},
+		{click(startX, first), constants.PARTIAL_REFRESH, 0},
+		{click(startX+width-3, first+1), constants.PARTIAL_REFRESH, 1},
+	}
+	for i, tt := range tests {
+		if refresh := MouseHandler(e, tt.m); refresh != tt.refresh || e.Modal.ItemIndex != tt.item {
+			t.Errorf("event %d, %+v: refresh %d and item %d, want %d and %d", i, tt.m, refresh, e.Modal.ItemIndex, tt.refresh, tt.item)
+		}
+	}
+
+	// Results scrolled off the top shift what is under a row
+	e.Modal.DataRowOffset = 1
+	MouseHandler(e, click(inside, first))
+	if e.Modal.ItemIndex != 1 {
+		t.Errorf("click scrolled down by one chose item %d, want 1", e.Modal.ItemIndex)
+	}
+	if !e.ModalOpen || cursorPoint(e) != (config.Point{}) {
+		t.Error("clicks in a modal reached the text")
+	}
+}
@@ -0,0 +1,369 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/constants"
+	"github.com/deanrtaylor1/go-editor/highlighting"
+	"github.com/deanrtaylor1/go-editor/keys"
+	"github.com/deanrtaylor1/go-editor/utils"
+)
+
+// textRange is the text an operator acts on. End is just past the text, or
+// the last row for a linewise range, which covers whole rows.
+type textRange struct {
+	Start    config.Point
+	End      config.Point
+	Linewise bool
+}
+
+// operators act on the text a motion or text object covers, as in d3w or ci(.
+// Each one is a single undo step.
+var operators = map[rune]func(e *config.Editor, r textRange){
+	'd': deleteOperator,
+	'c': changeOperator,
+	'y': yankOperator,
+	'>': indentOperator,
+	'<': outdentOperator,
+}
+
+// OperatorKeyHandler collects an operator with the counts and the motion or
+// text object after it, like 2d3w or ci(, and applies it once complete.
+// Doubling the operator, as in dd or >>, acts on count whole rows. Keys that
+// don't start with an operator are left to MotionKeyHandler.
+func OperatorKeyHandler(key keys.Key, e *config.Editor) (rune, bool) {
+	if !key.Printable() {
+		return constants.NO_OP, false
+	}
+	typed := string(append(e.MotionBuffer, key.Rune))
+	count, rest := splitCount(typed)
+	op, size := utf8.DecodeRuneInString(rest)
+	operator, ok := operators[op]
+	if !ok {
+		return constants.NO_OP, false
+	}
+
+	// The counts before and after the operator multiply, 2d3w deletes six words
+	motionCount, name := splitCount(rest[size:])
+	if motionCount > 0 {
+		count = countOrOne(count) * motionCount
+	}
+	r, pending, ok := operatorRange(e, op, name, count)
+	if pending {
+		e.MotionBuffer = []rune(typed)
+		return constants.NO_OP, true
+	}
+	e.ClearMotionBuffer()
+	if !ok || e.CurrentBuffer.NumRows == 0 {
+		e.KeyErr = errNoTextObject
+		return constants.NO_OP, true
+	}
+	operator(e, r)
+	return constants.INITIAL_REFRESH, true
+}
+
+// operatorRange finds the text for op followed by name. It reports pending
+// while name can still grow into a motion or text object, and false when it
+// can't or there's nothing to act on.
+func operatorRange(e *config.Editor, op rune, name string, count int) (textRange, bool, bool) {
+	if name == "" {
+		return textRange{}, true, false
+	}
+	if name == string(op) {
+		last := utils.Min(e.Cy+countOrOne(count)-1, lastRow(e))
+		return textRange{Start: config.Point{Row: e.Cy}, End: config.Point{Row: last}, Linewise: true}, false, true
+	}
+	if motion, ok := Motions[name]; ok {
+		if m, ok := operatorMotions[name]; ok {
+			motion = m
+		}
+		// cw changes to the end of the word like ce, as it always has in vi
+		if op == 'c' && (name == "w" || name == "W") {
+			if class := charClass(e, cursorPoint(e), name == "W"); class != classBlank && class != classEmptyRow {
+				motion = Motion{Inclusive: true, Target: changeWordTarget(name == "W")}
+			}
+		}
+		return motionRange(e, motion, count), false, true
+	}
+	if object, ok := textObjects[name]; ok {
+		r, found := object(e, cursorPoint(e), count)
+		return r, false, found
+	}
+	for candidate := range Motions {
+		if strings.HasPrefix(candidate, name) {
+			return textRange{}, true, false
+		}
+	}
+	for candidate := range textObjects {
+		if strings.HasPrefix(candidate, name) {
+			return textRange{}, true, false
+		}
+	}
+	return textRange{}, false, false
+}
+
+// motionRange is the text between the cursor and where m goes.
+func motionRange(e *config.Editor, m Motion, count int) textRange {
+	from := cursorPoint(e)
+	to := m.Target(e, from, count)
+	start, end := from, to
+	if pointBefore(to, from) {
+		start, end = to, from
+	}
+	if m.Linewise {
+		return textRange{Start: start, End: end, Linewise: true}
+	}
+
+	if m.Inclusive {
+		end.Col = utils.NextGrapheme(rowChars(e, end.Row), end.Col)
+		return textRange{Start: start, End: end}
+	}
+
+	// Stopping at the start of a later row leaves that row's line break alone.
+	// From the start of a row that makes the motion linewise, like d}
+	if end.Col == 0 && end.Row > start.Row {
+		end = config.Point{Row: end.Row - 1, Col: len(rowChars(e, end.Row-1))}
+		if start.Col <= firstNonBlank(rowChars(e, start.Row)) {
+			return textRange{Start: start, End: end, Linewise: true}
+		}
+	}
+	return textRange{Start: start, End: end}
+}
+
+// operatorMotions stand in for motions that stop short of the end of the
+// buffer, for which an operator goes on to the end.
+var operatorMotions = map[string]Motion{
+	"w": {Target: operatorWordTarget(false)},
+	"W": {Target: operatorWordTarget(true)},
+	"}": {Target: operatorParagraphTarget},
+}
+
+// operatorWordTarget is w or W for an operator. When the last word moved over
+// ends its row, the operator stops at the end of that word, leaving the line
+// break and the next row alone as in vi. Running out of words does the same
+// on the last row.
+func operatorWordTarget(bigWord bool) func(*config.Editor, config.Point, int) config.Point {
+	return func(e *config.Editor, p config.Point, count int) config.Point {
+		to, ok := p, true
+		for i := 0; i < countOrOne(count) && ok; i++ {
+			to, ok = nextWordStart(e, to, bigWord)
+		}
+		row := lastRow(e)
+		if ok {
+			if to.Row <= p.Row || to.Col > firstNonBlank(rowChars(e, to.Row)) {
+				return to
+			}
+			row = to.Row - 1
+		}
+		chars := rowChars(e, row)
+		end := config.Point{Row: row, Col: len(bytes.TrimRight(chars, " \t"))}
+		if !pointBefore(p, end) {
+			end.Col = len(chars)
+		}
+		if !pointBefore(p, end) {
+			return to
+		}
+		return end
+	}
+}
+
+// operatorParagraphTarget is } for an operator. Without an empty row to stop
+// at, } stops on the last character, which the operator takes along.
+func operatorParagraphTarget(e *config.Editor, p config.Point, count int) config.Point {
+	to := motionParagraphForward(e, p, count)
+	if chars := rowChars(e, to.Row); to.Row == lastRow(e) && len(chars) > 0 {
+		to.Col = len(chars)
+	}
+	return to
+}
+
+// changeWordTarget is the motion of cw, the end of the word under the cursor
+// even when already on its last character.
+func changeWordTarget(bigWord bool) func(*config.Editor, config.Point, int) config.Point {
+	return func(e *config.Editor, p config.Point, count int) config.Point {
+		_, p.Col = runBounds(e, p, bigWord)
+		for i := 1; i < countOrOne(count); i++ {
+			p = wordEnd(e, p, bigWord)
+		}
+		return p
+	}
+}
+
+func pointBefore(a, b config.Point) bool {
+	return a.Row < b.Row || (a.Row == b.Row && a.Col < b.Col)
+}
+
+// selectRange makes r the selection, so the selection machinery can yank and
+// delete it. It reports false when r is empty.
+func selectRange(e *config.Editor, r textRange) bool {
+	b := e.CurrentBuffer
+	b.SelectionLinewise = r.Linewise
+	b.SelectionStart = r.Start
+	if r.Linewise {
+		b.SelectionEnd = r.End
+		return true
+	}
+	if !pointBefore(r.Start, r.End) {
+		return false
+	}
+
+	// The selection end is the last character in it
+	end := r.End
+	if end.Col > 0 {
+		end.Col = utils.PrevGrapheme(rowChars(e, end.Row), end.Col)
+	} else if len(rowChars(e, end.Row)) > 0 {
+		end = config.Point{Row: end.Row - 1, Col: len(rowChars(e, end.Row-1))}
+	}
+	b.SelectionEnd = end
+	return true
+}
+
+// selectionTextRange is the text of the visual mode selection.
+func selectionTextRange(e *config.Editor) textRange {
+	if e.CurrentBuffer.SelectionLinewise {
+		start, end := e.GetNormalizedSelection()
+		return textRange{Start: start, End: end, Linewise: true}
+	}
+	start, end := e.SelectionRange()
+	return textRange{Start: start, End: end}
+}
+
+// deleteSelection removes the selected text.
+func deleteSelection(e *config.Editor) {
+	start, removed := e.DeleteSelection()
+	if len(removed) == 0 {
+		return
+	}
+	e.CurrentBuffer.Dirty++
+	highlighting.HighlightFileFromRow(start.Row, e)
+}
+
+// cutSelection keeps the selected text in the register given for the command
+// and deletes it. It reports false when the register can't take the text.
+func cutSelection(e *config.Editor, text config.Register) bool {
+	if err := e.Registers.Delete(e.SelectedRegister, text); err != nil {
+		reportError(e, err)
+		return false
+	}
+	deleteSelection(e)
+	return true
+}
+
+func moveCursorTo(e *config.Editor, p config.Point) {
+	e.Cy = utils.Max(utils.Min(p.Row, lastRow(e)), 0)
+	e.CurrentBuffer.SliceIndex = utils.Min(p.Col, len(rowChars(e, e.Cy)))
+	e.SyncCx()
+}
+
+// moveCursorToRow puts the cursor on the first non-blank character of row.
+func moveCursorToRow(e *config.Editor, row int) {
+	row = utils.Max(utils.Min(row, lastRow(e)), 0)
+	moveCursorTo(e, config.Point{Row: row, Col: firstNonBlank(rowChars(e, row))})
+}
+
+func yankOperator(e *config.Editor, r textRange) {
+	if !selectRange(e, r) {
+		return
+	}
+	if err := e.YankSelection(e.SelectedRegister); err != nil {
+		reportError(e, err)
+	}
+	e.ClearSelection()
+	if r.Linewise {
+		e.Cy = r.Start.Row
+		e.SyncSliceIndex()
+		return
+	}
+	moveCursorTo(e, r.Start)
+}
+
+func deleteOperator(e *config.Editor, r textRange) {
+	if !selectRange(e, r) {
+		return
+	}
+	cut := cutSelection(e, e.SelectedText())
+	e.ClearSelection()
+	if !cut {
+		return
+	}
+	if r.Linewise {
+		moveCursorToRow(e, r.Start.Row)
+		return
+	}
+	moveCursorTo(e, r.Start)
+}
+
+// changeOperator deletes the text and starts insert mode in its place. Whole
+// rows are emptied rather than removed, keeping the first one's indentation,
+// though the register still gets them whole.
+func changeOperator(e *config.Editor, r textRange) {
+	var text config.Register
+	if selectRange(e, r) {
+		text = e.SelectedText()
+	}
+	if r.Linewise {
+		first := rowChars(e, r.Start.Row)
+		r = textRange{
+			Start: config.Point{Row: r.Start.Row, Col: len(first) - len(bytes.TrimLeft(first, " \t"))},
+			End:   config.Point{Row: r.End.Row, Col: len(rowChars(e, r.End.Row))},
+		}
+	}
+	if selectRange(e, r) {
+		cut := cutSelection(e, text)
+		e.ClearSelection()
+		if !cut {
+			return
+		}
+	}
+	moveCursorTo(e, r.Start)
+	e.SetMode(constants.EDITOR_MODE_INSERT)
+}
+
+func indentOperator(e *config.Editor, r textRange) {
+	shiftRows(e, r.Start.Row, r.End.Row, false)
+}
+
+func outdentOperator(e *config.Editor, r textRange) {
+	shiftRows(e, r.Start.Row, r.End.Row, true)
+}
+
+// shiftRows indents or outdents the rows from first to last by one level.
+// Empty rows are left alone.
+func shiftRows(e *config.Editor, first, last int, outdent bool) {
+	b := e.CurrentBuffer
+	changed := false
+	unit := b.Options.IndentUnit()
+	for row := first; row <= last; row++ {
+		chars := rowChars(e, row)
+		if outdent {
+			width := outdentWidth(chars, b.Options.IndentSize)
+			b.DeleteText(config.Point{Row: row}, config.Point{Row: row, Col: width})
+			changed = changed || width > 0
+		} else if len(chars) > 0 {
+			b.InsertText(config.Point{Row: row}, unit)
+			changed = true
+		}
+	}
+	moveCursorToRow(e, first)
+
+	if changed {
+		b.Dirty++
+		highlighting.HighlightFileFromRow(first, e)
+	}
+}
+
+// outdentWidth is how much of the start of chars one level of indentation
+// takes up, a tab or up to size spaces.
+func outdentWidth(chars []byte, size int) int {
+	if len(chars) > 0 && chars[0] == '\t' {
+		return 1
+	}
+	width := 0
+	for width < size && width < len(chars) && chars[width] == ' ' {
+		width++
+	}
+	return width
+}
@@ -0,0 +1,31 @@
+package core
+
+import "testing"
+
+func TestDeleteWordAtEndOfRow(t *testing.T) {
+	checkTyping(t, []typingTest{
+		{"one two\nthree\n", "wdw", "one \nthree\n"},
+		{"one two\n  three\n", "wdw", "one \n  three\n"},
+		{"one two   \nthree\n", "wdw", "one    \nthree\n"},
+		{"one\ntwo three\n", "d2w", "three\n"},
+		{"one two\nthree\n", "d2w", "\nthree\n"},
+		{"one two\nthree four\n", "d3w", "four\n"},
+		{"one two\nthree\n", "wyw$p", "one twotwo\nthree\n"},
+		{"one two\n", "wdw", "one \n"},
+		{"one\nx\n", "dw", "\nx\n"},
+		{"one two\n\nthree\n", "wdw", "one \n\nthree\n"},
+		{"one two\nthree\n", "wcwx<Esc>", "one x\nthree\n"},
+		// A word of one character at the end of the buffer is still a word
+		// start, not where w gave up
+		{"a b\n", "dw", "b\n"},
+		{"foo b\n", "yw$p", "foo bfoo \n"},
+		{"foo b\n", "wdw", "foo \n"},
+		{"foo b\n", "wyw0p", "fboo b\n"},
+		{"foo  \n", "dw", "  \n"},
+		{"one\n", "dw", "\n"},
+		{"one\n", "$dw", "on\n"},
+		{"one\n\ntwo\n", "d}", "\ntwo\n"},
+		{"one\ntwo\n", "d}", "\n"},
+		{"one\ntwo\n", "jd}", "one\n\n"},
+	})
+}
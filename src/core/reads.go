@@ -1,7 +1,7 @@
 package core
 
 import (
-	"log"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
@@ -12,50 +12,48 @@ import (
 )
 
 // this function checks the type of the item and directs to the relevant function
-func ReadHandler(e *config.Editor, arg string) {
+func ReadHandler(e *config.Editor, arg string) error {
 	fileInfo, err := os.Stat(arg)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	if arg == "." {
 		e.SetMode(constants.EDITOR_MODE_FILE_BROWSER)
 		currentDir, err := os.Getwd()
 		if err != nil {
-			log.Fatal("Could not get current directory")
+			return fmt.Errorf("could not get current directory: %w", err)
 		}
 		if e.RootDirectory == "" {
 			e.RootDirectory = currentDir
 		}
-		DirectoryOpen(e, currentDir)
+		return DirectoryOpen(e, currentDir)
 	} else if fileInfo.IsDir() {
 		e.SetMode(constants.EDITOR_MODE_FILE_BROWSER)
 		// Set the current directory path in the config
 		if e.RootDirectory == "" {
 			e.RootDirectory = arg
 		}
-		DirectoryOpen(e, arg)
-	} else {
-		if e.RootDirectory == "" {
-			currentDir, err := os.Getwd()
-			if err != nil {
-				log.Fatal("Could Not Get the current directory")
-			}
-			e.RootDirectory = currentDir
+		return DirectoryOpen(e, arg)
+	}
 
+	if e.RootDirectory == "" {
+		currentDir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("could not get current directory: %w", err)
 		}
-		e.EditorMode = constants.EDITOR_MODE_NORMAL
-		if e.CurrentBuffer.Name != "" {
-			e.ReplaceBuffer()
-		}
-		foundBuffer := e.ReloadBuffer(arg)
-		if !foundBuffer {
-			err := FileOpen(e, arg)
-			if err != nil {
-				log.Fatal(err)
-			}
-		}
+		e.RootDirectory = currentDir
+
 	}
+	e.EditorMode = constants.EDITOR_MODE_NORMAL
+	if e.CurrentBuffer.Name != "" {
+		e.ReplaceBuffer()
+	}
+	foundBuffer := e.ReloadBuffer(arg)
+	if !foundBuffer {
+		return FileOpen(e, arg)
+	}
+	return nil
 }
 
 func DirectoryOpen(e *config.Editor, path string) error {
@@ -5,16 +5,9 @@ import (
 )
 
 func RedoAction(e *config.Editor) {
-	lastAction, success := e.CurrentBuffer.PopRedo()
+	from, cursor, success := e.CurrentBuffer.Redo(e.Settings.UndoLevels)
 	if !success {
 		return
 	}
-
-	e.CurrentBuffer.AppendUndo(lastAction, e.UndoHistory)
-
-	e.Cx = lastAction.Cx
-	e.Cy = lastAction.Index
-	e.CurrentBuffer.SliceIndex = lastAction.Cx - e.LineNumberWidth
-
-	lastAction.RedoFunction()
+	afterUndo(e, from, cursor)
 }
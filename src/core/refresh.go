@@ -6,7 +6,7 @@ import (
 
 	"github.com/deanrtaylor1/go-editor/config"
 	"github.com/deanrtaylor1/go-editor/constants"
-	"github.com/deanrtaylor1/go-editor/utils"
+	"github.com/deanrtaylor1/go-editor/keys"
 )
 
 func FullRefresh(e *config.Editor, buffer *bytes.Buffer) {
@@ -64,20 +64,51 @@ func SingleLineRefresh(e *config.Editor, buffer *bytes.Buffer, startRow, endRow
 	}
 }
 
-func EditorRefreshScreen(e *config.Editor, lastKeyPress rune) {
+// RefreshFor is the refresh hint for a key whose handler has nothing more
+// specific to say: edits that can move lines redraw everything, arrows the
+// rows around the cursor and anything else the current line.
+func RefreshFor(key keys.Key) rune {
+	if key.Mod&keys.ModCtrl != 0 {
+		return constants.INITIAL_REFRESH
+	}
+	switch key.Code {
+	case keys.CodeUnknown:
+		return constants.NO_OP
+	case keys.CodeEnter, keys.CodeBackspace, keys.CodeDelete, keys.CodeEscape, keys.CodePageUp, keys.CodePageDown, keys.CodePaste, keys.CodeMouse:
+		return constants.INITIAL_REFRESH
+	case keys.CodeUp, keys.CodeDown, keys.CodeLeft, keys.CodeRight:
+		return constants.PARTIAL_REFRESH
+	}
+	return constants.LINE_REFRESH
+}
+
+func EditorRefreshScreen(e *config.Editor, refresh rune) {
 	var cursorPosition string
-	if lastKeyPress == constants.NO_OP {
+	if refresh == constants.NO_OP {
 		return
 	}
 	var buffer bytes.Buffer
 	EditorScroll(e)
 	buffer.WriteString(constants.ESCAPE_HIDE_CURSOR)
 
-	if e.ModalOpen {
+	// After a resize the old layout may be left anywhere on the screen, so
+	// start from a blank one and redraw the text behind an open modal too
+	if refresh == constants.RESIZE_REFRESH {
+		buffer.WriteString(constants.ESCAPE_CLEAR_SCREEN)
+		if e.ModalOpen {
+			FullRefresh(e, &buffer)
+		}
+	}
+
+	if e.ModalOpen || e.EditorMode == constants.EDITOR_MODE_COMMAND {
 		buffer.WriteString(constants.ESCAPE_CURSOR_THIN)
+	}
+	if e.ModalOpen {
 		cursorPosition = EditorDrawModal(&buffer, e)
 	} else {
-		buffer.WriteString(constants.ESCAPE_CURSOR_THICK)
+		if e.EditorMode != constants.EDITOR_MODE_COMMAND {
+			buffer.WriteString(constants.ESCAPE_CURSOR_THICK)
+		}
 
 		// Cursor position adjustment logic for non file browser modes
 		if !e.IsBrowsingFiles() && e.Cx < e.LineNumberWidth {
@@ -97,10 +128,10 @@ func EditorRefreshScreen(e *config.Editor, lastKeyPress rune) {
 			if startRow < 0 {
 				startRow = 0
 			}
-			switch lastKeyPress {
-			case constants.INITIAL_REFRESH, constants.ENTER_KEY, constants.BACKSPACE, constants.DEL_KEY, utils.CTRL_KEY(lastKeyPress), constants.PAGE_DOWN, constants.PAGE_UP:
+			switch refresh {
+			case constants.INITIAL_REFRESH, constants.RESIZE_REFRESH:
 				FullRefresh(e, &buffer)
-			case constants.ARROW_DOWN, constants.ARROW_UP, constants.ARROW_LEFT, constants.ARROW_RIGHT:
+			case constants.PARTIAL_REFRESH:
 				PartialRefresh(e, &buffer, startRow, endRow)
 			default:
 				SingleLineRefresh(e, &buffer, 0, e.Cy)
@@ -115,7 +146,10 @@ func EditorRefreshScreen(e *config.Editor, lastKeyPress rune) {
 	EditorDrawMessageBar(&buffer, e)
 
 	// Set cursor position
-	if !e.ModalOpen {
+	if e.EditorMode == constants.EDITOR_MODE_COMMAND && !e.ModalOpen {
+		_, col := commandLineView(e)
+		cursorPosition = SetCursorPos(e.ScreenRows+2, col+1)
+	} else if !e.ModalOpen {
 		cursorPosition = SetCursorPos((e.Cy-e.RowOff)+1, (e.Cx-e.ColOff)+1)
 	}
 	buffer.WriteString(cursorPosition)
@@ -0,0 +1,202 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/constants"
+	"github.com/deanrtaylor1/go-editor/highlighting"
+	"github.com/deanrtaylor1/go-editor/keys"
+	"github.com/deanrtaylor1/go-editor/utils"
+)
+
+// registerOrder is the order ShowRegisters lists the registers in.
+const registerOrder = `"0123456789abcdefghijklmnopqrstuvwxyz./`
+
+// RegisterKeyHandler handles "{register} in front of a command, as in "ayy or
+// "_dd. The register is kept for the command and a count typed before it
+// stays in the motion buffer.
+func RegisterKeyHandler(key keys.Key, e *config.Editor) (rune, bool) {
+	if !key.Printable() {
+		return constants.NO_OP, false
+	}
+	typed := string(append(e.MotionBuffer, key.Rune))
+	_, rest := splitCount(typed)
+
+	switch rest {
+	case `"`:
+		e.MotionBuffer = []rune(typed)
+		return constants.NO_OP, true
+	case `"` + string(key.Rune):
+		if !config.IsRegister(key.Rune) {
+			e.ClearMotionBuffer()
+			reportError(e, fmt.Errorf("invalid register: %c", key.Rune))
+			return constants.NO_OP, true
+		}
+		e.SelectedRegister = key.Rune
+		e.MotionBuffer = []rune(typed[:len(typed)-len(rest)])
+		return constants.NO_OP, true
+	}
+	return constants.NO_OP, false
+}
+
+// forgetRegister drops the register given with " once the command it was for
+// has run.
+func forgetRegister(e *config.Editor) {
+	if len(e.MotionBuffer) == 0 {
+		e.SelectedRegister = 0
+	}
+}
+
+// registerText returns the register called name, reporting it on the status
+// bar when there's nothing in it.
+func registerText(e *config.Editor, name rune) (config.Register, bool) {
+	reg, ok := e.Registers.Get(name)
+	if !ok || reg.Text == "" {
+		if name == 0 {
+			name = config.UnnamedRegister
+		}
+		reportError(e, fmt.Errorf("register %c is empty", name))
+		return config.Register{}, false
+	}
+	return reg, true
+}
+
+// PasteRegister puts the text of the register called name after the cursor,
+// or below the current row when it holds whole rows.
+func PasteRegister(e *config.Editor, name rune) {
+	reg, ok := registerText(e, name)
+	if !ok {
+		return
+	}
+	if reg.Type == config.LineWise {
+		putText(e, reg, config.Point{Row: e.Cy + 1})
+		return
+	}
+	col := utils.NextGrapheme(rowChars(e, e.Cy), e.CurrentBuffer.SliceIndex)
+	putText(e, reg, config.Point{Row: e.Cy, Col: col})
+}
+
+// ReplaceSelection puts the text of the register called name in place of the
+// visual mode selection. The replaced text goes in the unnamed register.
+func ReplaceSelection(e *config.Editor, name rune) {
+	reg, ok := registerText(e, name)
+	if !ok {
+		return
+	}
+	linewise := e.CurrentBuffer.SelectionLinewise
+	start, _ := e.SelectionRange()
+	e.SelectedRegister = 0
+	if !cutSelection(e, e.SelectedText()) {
+		return
+	}
+
+	// Rows replacing part of a row go on rows of their own
+	switch {
+	case linewise:
+		reg.Type = config.LineWise
+	case reg.Type == config.LineWise:
+		reg = config.Register{Text: "\n" + reg.Text + "\n", Type: config.CharWise}
+	}
+	putText(e, reg, start)
+}
+
+// putText inserts reg at p. Whole rows go in at the start of
+// p's row with the cursor on the first of them, other text leaves the cursor
+// on its last character.
+func putText(e *config.Editor, reg config.Register, p config.Point) {
+	b := e.CurrentBuffer
+	text := []byte(reg.Text)
+	if reg.Type == config.LineWise {
+		p = config.Point{Row: utils.Min(p.Row, b.NumRows)}
+		text = append(text, '\n')
+	}
+
+	end := b.InsertText(p, text)
+	b.Dirty++
+	highlighting.HighlightFileFromRow(p.Row, e)
+
+	if reg.Type == config.LineWise {
+		moveCursorToRow(e, p.Row)
+		return
+	}
+	last, _ := prevChar(e, end)
+	moveCursorTo(e, last)
+}
+
+// trackInsertedText collects what is typed in insert mode and keeps it in
+// the last inserted text register once insert mode ends.
+func trackInsertedText(key keys.Key, e *config.Editor) func() {
+	switch {
+	case key.Code == keys.CodePaste:
+		e.InsertedText = append(e.InsertedText, key.Text...)
+	case key == keys.Enter:
+		e.InsertedText = append(e.InsertedText, '\n')
+	case key == keys.Tab:
+		e.InsertedText = append(e.InsertedText, '\t')
+	case key == keys.Backspace || key == keys.Ctrl('h'):
+		_, size := utf8.DecodeLastRune(e.InsertedText)
+		e.InsertedText = e.InsertedText[:len(e.InsertedText)-size]
+	case key.Printable():
+		e.InsertedText = utf8.AppendRune(e.InsertedText, key.Rune)
+	}
+
+	return func() {
+		if e.EditorMode == constants.EDITOR_MODE_INSERT {
+			return
+		}
+		if len(e.InsertedText) > 0 {
+			e.Registers.Fill(config.LastInsertRegister, string(e.InsertedText))
+		}
+		e.InsertedText = nil
+	}
+}
+
+// ShowRegisters lists the registers that hold something in a modal, line
+// breaks and other control characters written as ^J and the like. When names
+// isn't empty only the registers named in it are listed.
+func ShowRegisters(e *config.Editor, names string) {
+	var lines []string
+	for _, name := range registerOrder {
+		if names != "" && !strings.ContainsRune(names, name) {
+			continue
+		}
+		reg, ok := e.Registers.Get(name)
+		if !ok || reg.Text == "" {
+			continue
+		}
+		kind := "c"
+		if reg.Type == config.LineWise {
+			kind = "l"
+		}
+		lines = append(lines, fmt.Sprintf("%s  \"%c  %s", kind, name, visibleText(reg.Text)))
+	}
+	if len(lines) == 0 {
+		EditorSetStatusMessage(e, "No registers")
+		return
+	}
+
+	e.Modal = config.InitModal(config.MODAL_TYPE_VIEW)
+	e.Modal.Title = "Registers"
+	e.Modal.Data = lines
+	e.Modal.Results = lines
+	e.ModalOpen = true
+}
+
+func visibleText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == 0x7f:
+			b.WriteString("^?")
+		case r < 0x20:
+			b.WriteByte('^')
+			b.WriteRune(r + '@')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
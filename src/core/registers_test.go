@@ -0,0 +1,45 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/deanrtaylor1/go-editor/config"
+)
+
+func TestPasteRegister(t *testing.T) {
+	checkTyping(t, []typingTest{
+		// Whole rows go below the current one, other text after the cursor
+		{"one\ntwo\n", "yyp", "one\none\ntwo\n"},
+		{"one\ntwo\n", "yyjp", "one\ntwo\none\n"},
+		{"one\ntwo\n", "yjjp", "one\ntwo\none\ntwo\n"},
+		{"one two\n", "ywp", "oone ne two\n"},
+		{"one two\n", "yw$p", "one twoone \n"},
+		{"é世x\n", "yl$p", "é世xé\n"},
+		{"one\ntwo\n", "vjyp", "oone\ntne\ntwo\n"},
+		// Deletes go in 1 and shift, yanks stay in 0
+		{"one\ntwo\nthree\n", "yyjdd\"0p", "one\nthree\none\n"},
+		{"one\ntwo\nthree\n", "dddd\"2p", "three\none\n"},
+		{"one\ntwo\nthree\n", "dd\"_ddp", "three\none\n"},
+		// Named registers, appending with upper case
+		{"one\ntwo\n", "\"ayyj\"ap", "one\ntwo\none\n"},
+		{"one\ntwo\n", "\"ayyj\"Ayy\"ap", "one\ntwo\none\ntwo\n"},
+		{"one two\n", "\"ayww\"Ayw$\"ap", "one twoone two\n"},
+		{"one two\n", "\"ayw\"byw\"ap", "oone ne two\n"},
+		{"one\n", "\"zp", "one\n"},
+		// The registers the editor fills in itself
+		{"one\n", "iab<Esc>$\".p", "aboneab\n"},
+	})
+}
+
+func TestPasteReadOnlyRegister(t *testing.T) {
+	e := newTestEditor("one\n")
+	e.Registers.Fill(config.LastSearchRegister, "two")
+	typeKeys(e, "\"/p")
+	if got := string(e.CurrentBuffer.Text.Bytes()); got != "otwone\n" {
+		t.Errorf("\"/p left %q, want %q", got, "otwone\n")
+	}
+	typeKeys(e, "\"/yy")
+	if reg, _ := e.Registers.Get(config.LastSearchRegister); reg.Text != "two" || e.KeyErr == nil {
+		t.Errorf("\"/yy left / holding %q with error %v, want two and an error", reg.Text, e.KeyErr)
+	}
+}
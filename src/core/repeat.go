@@ -0,0 +1,115 @@
+package core
+
+import (
+	"strconv"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/constants"
+	"github.com/deanrtaylor1/go-editor/keys"
+)
+
+// trackNormalKey adds key to the change being typed and returns what to run
+// once it's handled. A change ends with the key that edits the buffer, or
+// carries on into insert mode until that ends.
+func trackNormalKey(key keys.Key, e *config.Editor) func() {
+	if e.Repeating {
+		return func() {}
+	}
+	if len(e.MotionBuffer) == 0 {
+		e.Change = nil
+	}
+	e.Change = append(e.Change, key)
+	buffer, dirty := e.CurrentBuffer, e.CurrentBuffer.Dirty
+
+	return func() {
+		switch {
+		case len(e.MotionBuffer) > 0, e.SelectedRegister != 0, e.EditorMode == constants.EDITOR_MODE_INSERT:
+			// Not finished yet
+		case e.CurrentBuffer == buffer && buffer.Dirty > dirty && !isUndoCommand(e.Change):
+			e.LastChange = e.Change
+			e.Change = nil
+		default:
+			e.Change = nil
+		}
+	}
+}
+
+// isUndoCommand reports whether the keys of a change are an undo or redo,
+// which change the text without being a change to repeat.
+func isUndoCommand(change []keys.Key) bool {
+	_, name := splitCount(keys.Format(change))
+	switch name {
+	case "u", "<C-r>", "g-", "g+":
+		return true
+	}
+	return false
+}
+
+// trackInsertKey adds key to an insert session started by a change and
+// returns what to run once it's handled, which ends the change when insert
+// mode does.
+func trackInsertKey(key keys.Key, e *config.Editor) func() {
+	if e.Repeating || e.Change == nil {
+		return func() {}
+	}
+	e.Change = append(e.Change, key)
+
+	return func() {
+		if e.EditorMode != constants.EDITOR_MODE_INSERT {
+			e.LastChange = e.Change
+			e.Change = nil
+		}
+	}
+}
+
+// RepeatLastChange replays the last change at the cursor. A count other than
+// 0 replaces the one the change was made with.
+func RepeatLastChange(e *config.Editor, count int) rune {
+	// The count typed before . isn't a change of its own
+	e.Change = nil
+	if len(e.LastChange) == 0 {
+		return constants.NO_OP
+	}
+	change := e.LastChange
+	if count > 0 {
+		// The new count sticks for the next . as well
+		change = withCount(change, count)
+		e.LastChange = change
+	}
+
+	e.Repeating = true
+	for _, key := range change {
+		EventHandlerMain(key, e)
+	}
+	e.Repeating = false
+	return constants.INITIAL_REFRESH
+}
+
+// withCount puts count in place of the counts change was typed with. An
+// operator's own count goes too, so 3. after d2w deletes three words.
+func withCount(change []keys.Key, count int) []keys.Key {
+	rest := dropCount(change)
+	if len(rest) > 0 && rest[0].Printable() {
+		if _, ok := operators[rest[0].Rune]; ok {
+			rest = append([]keys.Key{rest[0]}, dropCount(rest[1:])...)
+		}
+	}
+	var counted []keys.Key
+	for _, r := range strconv.Itoa(count) {
+		counted = append(counted, keys.Rune(r))
+	}
+	return append(counted, rest...)
+}
+
+// dropCount removes the count at the start of typed. A count can't start with
+// 0, that is a motion.
+func dropCount(typed []keys.Key) []keys.Key {
+	digits := 0
+	for digits < len(typed) && typed[digits].Printable() && typed[digits].Rune >= '0' && typed[digits].Rune <= '9' {
+		if digits == 0 && typed[0].Rune == '0' {
+			break
+		}
+		digits++
+	}
+	return typed[digits:]
+}
@@ -0,0 +1,15 @@
+package core
+
+import "testing"
+
+func TestRepeatLastChangeWithCount(t *testing.T) {
+	checkTyping(t, []typingTest{
+		{"a b c d e f\n", "dw.", "c d e f\n"},
+		{"a b c d e f\n", "dw3.", "e f\n"},
+		{"a b c d e f g h i\n", "d2w3.", "f g h i\n"},
+		{"a b c d e f g h i\n", "2dw3.", "f g h i\n"},
+		{"a b c d e f g h i\n", "dw3..", "h i\n"},
+		{"one\ntwo\nthree\nfour\nfive\n", "dd2.", "four\nfive\n"},
+		{"a b c\n", "3.", "a b c\n"},
+	})
+}
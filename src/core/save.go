@@ -0,0 +1,179 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// WriteFileAtomic replaces the file at path with content. The data is written
+// to a temporary file next to the target, synced and renamed over it, so a
+// failed save never leaves a half written file behind. Symlinks are followed
+// and the original mode and ownership are kept. When backup is set the
+// previous contents are kept in path~.
+//
+// Files with hard links, and files that can't be handed back to their owner
+// after the rename, are overwritten in place instead, which a crash can leave
+// half written. The returned note tells the user when that happened.
+func WriteFileAtomic(path string, content []byte, backup bool) (string, error) {
+	target, err := resolveSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve symlink: %w", err)
+	}
+
+	info, err := os.Stat(target)
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	if exists && backup {
+		if err := writeBackup(target, info); err != nil {
+			return "", fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+
+	// A rename would split hard links apart, so those are written in place
+	if exists && hardLinkCount(info) > 1 {
+		return "written in place to keep hard links", writeInPlace(target, content)
+	}
+
+	perm := fs.FileMode(0666)
+	if exists {
+		perm = info.Mode().Perm()
+	}
+	tmp, err := createTempFile(target, perm)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmpName)
+	}
+
+	if _, err := tmp.Write(content); err != nil {
+		cleanup()
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		cleanup()
+		return "", fmt.Errorf("failed to sync temp file: %w", err)
+	}
+
+	if exists {
+		// The umask applied when the temp file was created, so set the mode
+		// explicitly
+		if err := tmp.Chmod(info.Mode()); err != nil {
+			cleanup()
+			return "", fmt.Errorf("failed to set file mode: %w", err)
+		}
+		if err := preserveOwner(tmp, info); err != nil {
+			// Without the rights to hand the file back to its owner the only
+			// way to keep the ownership is to overwrite the original
+			cleanup()
+			return "written in place to keep the owner", writeInPlace(target, content)
+		}
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return "", fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tmpName, target); err != nil {
+		os.Remove(tmpName)
+		return "", fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	// Make the rename itself durable. Not every filesystem supports syncing a
+	// directory, so failures here are ignored.
+	if dir, err := os.Open(filepath.Dir(target)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return "", nil
+}
+
+// maxSymlinks is how many links resolveSymlinks follows before giving up on
+// a loop.
+const maxSymlinks = 40
+
+// resolveSymlinks follows path through any symlinks to the file they point
+// to. A link to a file that doesn't exist yet resolves to where the file
+// would be, so saving creates it.
+func resolveSymlinks(path string) (string, error) {
+	for i := 0; i < maxSymlinks; i++ {
+		info, err := os.Lstat(path)
+		if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+			return path, nil
+		}
+		link, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(path), link)
+		}
+		path = link
+	}
+	return "", errors.New("too many levels of symbolic links")
+}
+
+func createTempFile(target string, perm fs.FileMode) (*os.File, error) {
+	dir, base := filepath.Split(target)
+	seed := time.Now().UnixNano()
+	for i := 0; i < 100; i++ {
+		name := filepath.Join(dir, "."+base+"."+strconv.FormatInt(seed+int64(i), 36)+".tmp")
+		file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		return file, err
+	}
+	return nil, errors.New("could not find an unused temp file name")
+}
+
+// writeBackup copies the current contents of path to path~. It is a copy
+// rather than a link because some saves still overwrite the original in
+// place.
+func writeBackup(path string, info fs.FileInfo) error {
+	backupPath := path + "~"
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(backupPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+func writeInPlace(path string, content []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	if err := file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate file: %w", err)
+	}
+	if _, err := file.Write(content); err != nil {
+		return fmt.Errorf("failed to write to file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync file: %w", err)
+	}
+	return nil
+}
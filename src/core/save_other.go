@@ -0,0 +1,19 @@
+//go:build !unix
+
+package core
+
+import (
+	"io/fs"
+	"os"
+)
+
+// Without link counts every file is taken to have a single link.
+func hardLinkCount(info fs.FileInfo) uint64 {
+	return 1
+}
+
+// Ownership isn't carried by a Unix uid and gid here, so there is nothing to
+// keep.
+func preserveOwner(file *os.File, info fs.FileInfo) error {
+	return nil
+}
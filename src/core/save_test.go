@@ -0,0 +1,100 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup prepares dir and returns the path to save to and the file
+		// that should end up holding the content
+		setup    func(t *testing.T, dir string) (string, string)
+		symlink  bool
+		wantNote bool
+	}{
+		{
+			name: "new file",
+			setup: func(t *testing.T, dir string) (string, string) {
+				path := filepath.Join(dir, "new.txt")
+				return path, path
+			},
+		},
+		{
+			name: "existing file",
+			setup: func(t *testing.T, dir string) (string, string) {
+				path := filepath.Join(dir, "old.txt")
+				writeTestFile(t, path, "old\n")
+				return path, path
+			},
+		},
+		{
+			name: "symlink",
+			setup: func(t *testing.T, dir string) (string, string) {
+				target := filepath.Join(dir, "target.txt")
+				writeTestFile(t, target, "old\n")
+				link := filepath.Join(dir, "link.txt")
+				symlink(t, "target.txt", link)
+				return link, target
+			},
+			symlink: true,
+		},
+		{
+			name: "dangling symlink",
+			setup: func(t *testing.T, dir string) (string, string) {
+				link := filepath.Join(dir, "link.txt")
+				symlink(t, "missing.txt", link)
+				return link, filepath.Join(dir, "missing.txt")
+			},
+			symlink: true,
+		},
+		{
+			name: "hard link",
+			setup: func(t *testing.T, dir string) (string, string) {
+				path := filepath.Join(dir, "file.txt")
+				writeTestFile(t, path, "old\n")
+				other := filepath.Join(dir, "other.txt")
+				if err := os.Link(path, other); err != nil {
+					t.Skip("hard links not supported:", err)
+				}
+				return path, other
+			},
+			wantNote: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, want := tt.setup(t, t.TempDir())
+			note, err := WriteFileAtomic(path, []byte("new\n"), false)
+			if err != nil {
+				t.Fatalf("WriteFileAtomic() error = %v", err)
+			}
+			if (note != "") != tt.wantNote {
+				t.Errorf("WriteFileAtomic() note = %q", note)
+			}
+			got, err := os.ReadFile(want)
+			if err != nil || string(got) != "new\n" {
+				t.Errorf("%s holds %q, %v, want %q", want, got, err, "new\n")
+			}
+			if info, err := os.Lstat(path); err != nil || (tt.symlink && info.Mode()&os.ModeSymlink == 0) {
+				t.Errorf("%s is no longer a symlink", path)
+			}
+		})
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func symlink(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+}
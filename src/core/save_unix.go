@@ -0,0 +1,29 @@
+//go:build unix
+
+package core
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+func hardLinkCount(info fs.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Nlink)
+	}
+	return 1
+}
+
+func preserveOwner(file *os.File, info fs.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if current, err := file.Stat(); err == nil {
+		if currentStat, ok := current.Sys().(*syscall.Stat_t); ok && currentStat.Uid == stat.Uid && currentStat.Gid == stat.Gid {
+			return nil
+		}
+	}
+	return file.Chown(int(stat.Uid), int(stat.Gid))
+}
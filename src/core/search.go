@@ -5,21 +5,22 @@ import (
 
 	"github.com/deanrtaylor1/go-editor/config"
 	"github.com/deanrtaylor1/go-editor/constants"
+	"github.com/deanrtaylor1/go-editor/keys"
 )
 
-func EditorFindCallback(buf []rune, c rune, e *config.Editor, trigger bool) {
+func EditorFindCallback(buf []rune, c keys.Key, e *config.Editor, trigger bool) {
 	if len(e.CurrentBuffer.SearchState.SavedHl) > 0 {
 		sl := e.CurrentBuffer.SearchState.SavedHlLine
-		e.CurrentBuffer.Rows[sl].Highlighting = e.CurrentBuffer.SearchState.SavedHl
+		e.CurrentBuffer.Row(sl).Highlighting = e.CurrentBuffer.SearchState.SavedHl
 	}
 
-	if c == '\r' || c == '\x1b' {
+	if c == keys.Enter || c == keys.Escape {
 		e.CurrentBuffer.SearchState.LastMatch = -1
 		e.CurrentBuffer.SearchState.Direction = 1
 		return
-	} else if c == constants.ARROW_RIGHT || c == constants.ARROW_DOWN {
+	} else if c.Code == keys.CodeRight || c.Code == keys.CodeDown {
 		e.CurrentBuffer.SearchState.Direction = 1
-	} else if c == constants.ARROW_LEFT || c == constants.ARROW_UP {
+	} else if c.Code == keys.CodeLeft || c.Code == keys.CodeUp {
 		e.CurrentBuffer.SearchState.Direction = -1
 	} else {
 		e.CurrentBuffer.SearchState.LastMatch = -1
@@ -38,21 +39,21 @@ func EditorFindCallback(buf []rune, c rune, e *config.Editor, trigger bool) {
 			current = 0
 		}
 
-		row := e.CurrentBuffer.Rows[current].Chars
+		row := e.CurrentBuffer.Row(current).Chars
 		matchIndex := strings.Index(string(row), string(buf))
 		if matchIndex != -1 {
 			e.CurrentBuffer.SearchState.LastMatch = current
 			e.Cy = current
-			e.Cx = matchIndex + e.LineNumberWidth
 			e.CurrentBuffer.SliceIndex = matchIndex
+			e.SyncCx()
 			e.RowOff = e.CurrentBuffer.NumRows
 
 			e.CurrentBuffer.SearchState.SavedHlLine = current
-			e.CurrentBuffer.SearchState.SavedHl = make([]byte, len(e.CurrentBuffer.Rows[e.Cy].Highlighting))
-			copy(e.CurrentBuffer.SearchState.SavedHl, e.CurrentBuffer.Rows[e.Cy].Highlighting)
+			e.CurrentBuffer.SearchState.SavedHl = make([]byte, len(e.CurrentBuffer.Row(e.Cy).Highlighting))
+			copy(e.CurrentBuffer.SearchState.SavedHl, e.CurrentBuffer.Row(e.Cy).Highlighting)
 
 			for i := 0; i < len(buf); i++ {
-				e.CurrentBuffer.Rows[e.Cy].Highlighting[matchIndex+i] = constants.HL_MATCH
+				e.CurrentBuffer.Row(e.Cy).Highlighting[matchIndex+i] = constants.HL_MATCH
 			}
 			break
 		}
@@ -75,6 +76,8 @@ func EditorFind(e *config.Editor) {
 		e.Cy = cy
 		e.RowOff = rowOff
 		e.ColOff = colOff
+	} else {
+		e.Registers.Fill(config.LastSearchRegister, string(query))
 	}
 	e.CurrentBuffer.SearchState.Searching = false
 }
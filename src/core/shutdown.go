@@ -0,0 +1,65 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/deanrtaylor1/go-editor/config"
+)
+
+// DirtyBuffers lists the names of the open buffers with unsaved changes.
+func DirtyBuffers(e *config.Editor) []string {
+	var names []string
+	if e.CurrentBuffer != nil && e.CurrentBuffer.Dirty > 0 {
+		names = append(names, e.CurrentBuffer.Name)
+	}
+	for _, b := range e.Buffers {
+		if b.Dirty > 0 && (e.CurrentBuffer == nil || b.Name != e.CurrentBuffer.Name) {
+			names = append(names, b.Name)
+		}
+	}
+	return names
+}
+
+// WriteCrashReport saves what is known about a panic: its value, the stack
+// trace and the buffers that had unsaved changes. The current buffer is also
+// written to its swap file so the changes can be recovered on the next open.
+// It returns the path of the report.
+func WriteCrashReport(e *config.Editor, reason interface{}, stack []byte) (string, error) {
+	var report strings.Builder
+	fmt.Fprintf(&report, "go-editor crashed at %s\n\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&report, "panic: %v\n\n", reason)
+
+	dirty := DirtyBuffers(e)
+	if len(dirty) == 0 {
+		report.WriteString("No buffers had unsaved changes.\n")
+	} else {
+		report.WriteString("Buffers with unsaved changes:\n")
+		for _, name := range dirty {
+			fmt.Fprintf(&report, "  %s\n", name)
+		}
+	}
+	if err := FlushSwapFile(e); err != nil {
+		fmt.Fprintf(&report, "Failed to write the swap files: %s\n", err.Error())
+	}
+
+	report.WriteString("\n")
+	report.Write(stack)
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	dir = filepath.Join(dir, "go-editor")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "crash-"+time.Now().Format("20060102-150405")+".log")
+	if err := os.WriteFile(path, []byte(report.String()), 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,368 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/highlighting"
+	"github.com/deanrtaylor1/go-editor/keys"
+)
+
+const (
+	swapHeader = "go-editor swap"
+	// Buffers bigger than swapLargeBuffer are snapshotted at most every
+	// swapInterval, smaller ones after every change
+	swapLargeBuffer = 1 << 20
+	swapInterval    = 4 * time.Second
+)
+
+// swapJob asks the swap writer to write data to path, or to remove path when
+// data is nil. done is closed once the job has been carried out.
+type swapJob struct {
+	path string
+	data []byte
+	done chan struct{}
+}
+
+var (
+	swapMu      sync.Mutex
+	swapQueue   = map[string]swapJob{}
+	swapWake    = make(chan struct{}, 1)
+	swapStarted sync.Once
+)
+
+// queueSwapJob hands a job to the background writer. Only the newest job for
+// each path is kept, so a slow disk skips stale snapshots instead of falling
+// behind.
+func queueSwapJob(job swapJob) {
+	swapStarted.Do(func() {
+		go swapWriter()
+	})
+	swapMu.Lock()
+	if old, ok := swapQueue[job.path]; ok && old.done != nil {
+		close(old.done)
+	}
+	swapQueue[job.path] = job
+	swapMu.Unlock()
+
+	select {
+	case swapWake <- struct{}{}:
+	default:
+	}
+}
+
+func swapWriter() {
+	for range swapWake {
+		for {
+			swapMu.Lock()
+			var job swapJob
+			found := false
+			for path, queued := range swapQueue {
+				job, found = queued, true
+				delete(swapQueue, path)
+				break
+			}
+			swapMu.Unlock()
+			if !found {
+				break
+			}
+
+			var err error
+			if job.data == nil {
+				err = os.Remove(job.path)
+				if errors.Is(err, fs.ErrNotExist) {
+					err = nil
+				}
+			} else {
+				err = writePrivateFile(job.path, job.data)
+			}
+			if err != nil {
+				config.LogToFile(fmt.Sprintf("swap file %s: %s", job.path, err.Error()))
+			}
+			if job.done != nil {
+				close(job.done)
+			}
+		}
+	}
+}
+
+// writePrivateFile writes data to path through a temporary file, readable by
+// the user alone. Each write gets a temporary file of its own, so another
+// editor or a flush racing the swap writer can't write into it as well.
+func writePrivateFile(path string, data []byte) error {
+	dir, base := filepath.Split(path)
+	tmp, err := os.CreateTemp(dir, base+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// SwapPath returns the swap file used for the file at path, .name.swp next to
+// the file itself.
+func SwapPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	dir, base := filepath.Split(path)
+	return filepath.Join(dir, "."+base+".swp")
+}
+
+// UpdateSwapFile snapshots every modified buffer into its swap file when it
+// has changed since its last snapshot. The snapshots are taken here and
+// written in the background.
+func UpdateSwapFile(e *config.Editor) {
+	updateSwapFile(e.CurrentBuffer)
+	for i := range e.Buffers {
+		if e.Buffers[i].Name != e.CurrentBuffer.Name {
+			updateSwapFile(&e.Buffers[i])
+		}
+	}
+}
+
+func updateSwapFile(b *config.Buffer) {
+	if b.SwapPath == "" || b.Dirty == 0 || b.Dirty == b.SwapDirty {
+		return
+	}
+	if b.Text.Len() > swapLargeBuffer && time.Since(b.SwapWrittenAt) < swapInterval {
+		return
+	}
+	b.SwapDirty = b.Dirty
+	b.SwapWrittenAt = time.Now()
+	queueSwapJob(swapJob{path: b.SwapPath, data: encodeSwap(b)})
+}
+
+// RemoveSwapFile deletes the swap file of the current buffer and waits for it
+// to be gone, so nothing queued earlier can bring it back.
+func RemoveSwapFile(e *config.Editor) {
+	b := e.CurrentBuffer
+	if b.SwapPath == "" {
+		return
+	}
+	b.SwapDirty = 0
+	b.SwapWrittenAt = time.Time{}
+	done := make(chan struct{})
+	queueSwapJob(swapJob{path: b.SwapPath, done: done})
+	<-done
+}
+
+// FlushSwapFile writes the swap files of every modified buffer straight
+// away, for when the background writer may not get the chance to.
+func FlushSwapFile(e *config.Editor) error {
+	if e.CurrentBuffer == nil {
+		return nil
+	}
+	var errs []error
+	errs = append(errs, flushSwapFile(e.CurrentBuffer))
+	for i := range e.Buffers {
+		if e.Buffers[i].Name != e.CurrentBuffer.Name {
+			errs = append(errs, flushSwapFile(&e.Buffers[i]))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func flushSwapFile(b *config.Buffer) (err error) {
+	if b.SwapPath == "" || b.Dirty == 0 {
+		return nil
+	}
+	// The buffer may be what broke, so don't let it panic a second time
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return writePrivateFile(b.SwapPath, encodeSwap(b))
+}
+
+func encodeSwap(b *config.Buffer) []byte {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s\npid: %d\nname: %s\n\n", swapHeader, os.Getpid(), b.Name)
+	out.Write(b.Text.Bytes())
+	return out.Bytes()
+}
+
+type swapFile struct {
+	pid      int
+	content  []byte
+	modified time.Time
+}
+
+func readSwapFile(path string) (*swapFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header, content, ok := bytes.Cut(data, []byte("\n\n"))
+	if !ok || !bytes.HasPrefix(header, []byte(swapHeader+"\n")) {
+		return nil, fmt.Errorf("%s is not a swap file", path)
+	}
+	swap := &swapFile{content: content, modified: info.ModTime()}
+	scanner := bufio.NewScanner(bytes.NewReader(header))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), ": ")
+		if key == "pid" {
+			swap.pid, _ = strconv.Atoi(value)
+		}
+	}
+	return swap, nil
+}
+
+// processRunning reports whether another live process owns the swap file.
+func (s *swapFile) processRunning() bool {
+	if s.pid <= 0 || s.pid == os.Getpid() {
+		return false
+	}
+	return processExists(s.pid)
+}
+
+// CheckSwapFile looks for a swap file left behind for the current buffer and
+// lets the user recover it, look at what it changes, or discard it.
+func CheckSwapFile(e *config.Editor) {
+	swap, err := readSwapFile(e.CurrentBuffer.SwapPath)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			EditorSetStatusMessage(e, "failed to read swap file: %s", err.Error())
+		}
+		return
+	}
+
+	owner := ""
+	if swap.processRunning() {
+		owner = fmt.Sprintf(", in use by process %d", swap.pid)
+	}
+	prompt := fmt.Sprintf("Swap file found (%s%s). [r]ecover, [d]iff, [x] discard, [i]gnore:", swap.modified.Format("2006-01-02 15:04:05"), owner)
+
+	defer func() {
+		e.ModalOpen = false
+		e.Modal.ModalDrawn = false
+	}()
+	for {
+		c := EditorKeyPrompt(prompt, e)
+		switch c {
+		case keys.Rune('r'), keys.Rune('R'):
+			e.CurrentBuffer.SetText(swap.content)
+			highlighting.HighlightFileFromRow(0, e)
+			e.CurrentBuffer.Dirty = 1
+			e.CurrentBuffer.ForgetSaved()
+			EditorSetStatusMessage(e, "Recovered from swap file, save to keep the changes")
+			return
+		case keys.Rune('d'), keys.Rune('D'):
+			diff := LineDiff(e.CurrentBuffer.Text.Bytes(), swap.content)
+			if len(diff) == 0 {
+				diff = []string{"swap file matches the file on disk"}
+			}
+			e.Modal = config.InitModal(config.MODAL_TYPE_GENERIC)
+			e.Modal.Title = "Swap file changes"
+			e.Modal.Data = diff
+			e.Modal.Results = diff
+			e.ModalOpen = true
+		case keys.Rune('x'), keys.Rune('X'):
+			RemoveSwapFile(e)
+			EditorSetStatusMessage(e, "Swap file discarded")
+			return
+		case keys.Rune('i'), keys.Rune('I'), keys.Escape:
+			EditorSetStatusMessage(e, "")
+			return
+		case keys.Up, keys.Down:
+			if e.ModalOpen {
+				ModalModeEventsHandler(c, e)
+			}
+		}
+	}
+}
+
+// LineDiff lists the lines removed from a ('-') and added in b ('+'), each
+// with its line number.
+func LineDiff(a, b []byte) []string {
+	aLines := strings.Split(strings.TrimSuffix(string(a), "\n"), "\n")
+	bLines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+
+	prefix := 0
+	for prefix < len(aLines) && prefix < len(bLines) && aLines[prefix] == bLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(aLines)-prefix && suffix < len(bLines)-prefix && aLines[len(aLines)-1-suffix] == bLines[len(bLines)-1-suffix] {
+		suffix++
+	}
+	aMid := aLines[prefix : len(aLines)-suffix]
+	bMid := bLines[prefix : len(bLines)-suffix]
+
+	removed := func(i int) string { return fmt.Sprintf("-%d: %s", prefix+i+1, aMid[i]) }
+	added := func(j int) string { return fmt.Sprintf("+%d: %s", prefix+j+1, bMid[j]) }
+
+	var diff []string
+	// Big changes are not worth a full comparison, show them as replaced
+	if len(aMid)*len(bMid) > 4_000_000 {
+		for i := range aMid {
+			diff = append(diff, removed(i))
+		}
+		for j := range bMid {
+			diff = append(diff, added(j))
+		}
+		return diff
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of aMid[i:]
+	// and bMid[j:]
+	lcs := make([][]int, len(aMid)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bMid)+1)
+	}
+	for i := len(aMid) - 1; i >= 0; i-- {
+		for j := len(bMid) - 1; j >= 0; j-- {
+			if aMid[i] == bMid[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(aMid) || j < len(bMid) {
+		switch {
+		case i < len(aMid) && j < len(bMid) && aMid[i] == bMid[j]:
+			i++
+			j++
+		case i < len(aMid) && (j == len(bMid) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, removed(i))
+			i++
+		default:
+			diff = append(diff, added(j))
+			j++
+		}
+	}
+	return diff
+}
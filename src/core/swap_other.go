@@ -0,0 +1,16 @@
+//go:build !unix
+
+package core
+
+import "os"
+
+// processExists reports whether a process with the given pid is running.
+// Finding a process only fails here when there is none to open.
+func processExists(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
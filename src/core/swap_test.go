@@ -0,0 +1,67 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/deanrtaylor1/go-editor/config"
+)
+
+func TestFlushSwapFileWritesEveryModifiedBuffer(t *testing.T) {
+	dir := t.TempDir()
+	newBuffer := func(name, text string, dirty int) config.Buffer {
+		b := config.NewBuffer()
+		b.Name = name
+		b.SetText([]byte(text))
+		b.SwapPath = SwapPath(filepath.Join(dir, name))
+		b.Dirty = dirty
+		return *b
+	}
+
+	e := config.NewEditor()
+	e.Buffers = []config.Buffer{
+		newBuffer("current.txt", "stale\n", 1),
+		newBuffer("background.txt", "background\n", 2),
+		newBuffer("saved.txt", "saved\n", 0),
+	}
+	current := newBuffer("current.txt", "current\n", 3)
+	e.CurrentBuffer = &current
+
+	if err := FlushSwapFile(e); err != nil {
+		t.Fatalf("FlushSwapFile() error = %v", err)
+	}
+	for name, want := range map[string]string{"current.txt": "current\n", "background.txt": "background\n"} {
+		swap, err := readSwapFile(SwapPath(filepath.Join(dir, name)))
+		if err != nil {
+			t.Fatalf("swap file for %s: %v", name, err)
+		}
+		if string(swap.content) != want {
+			t.Errorf("swap file for %s holds %q, want %q", name, swap.content, want)
+		}
+	}
+	if _, err := os.Stat(SwapPath(filepath.Join(dir, "saved.txt"))); !os.IsNotExist(err) {
+		t.Errorf("swap file written for a buffer without changes")
+	}
+	if temps, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(temps) > 0 {
+		t.Errorf("temporary files left behind: %v", temps)
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want []string
+	}{
+		{"a\nb\n", "a\nb\n", nil},
+		{"a\nb\n", "a\nc\n", []string{"-2: b", "+2: c"}},
+		{"a\nc\n", "a\nb\nc\n", []string{"+2: b"}},
+		{"a\nb\nc\n", "c\n", []string{"-1: a", "-2: b"}},
+	}
+	for _, tt := range tests {
+		if got := LineDiff([]byte(tt.a), []byte(tt.b)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LineDiff(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build unix
+
+package core
+
+import "syscall"
+
+// processExists reports whether a process with the given pid is running,
+// by sending it the null signal.
+func processExists(pid int) bool {
+	return syscall.Kill(pid, 0) == nil
+}
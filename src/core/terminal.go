@@ -0,0 +1,101 @@
+package core
+
+import (
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/term"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/constants"
+)
+
+var terminalActive bool
+
+// SetupTerminal puts the terminal into raw mode and switches to the alternate
+// screen, so whatever was on the screen before comes back on exit. Bracketed
+// paste is turned on so pasted text can be told apart from typing, and mouse
+// reporting when the mouse setting is on. The state
+// from the first call is what RestoreTerminal goes back to.
+func SetupTerminal(e *config.Editor) error {
+	state, err := term.MakeRaw(int(syscall.Stdin))
+	if err != nil {
+		return err
+	}
+	if e.TerminalState == nil {
+		e.TerminalState = state
+	}
+	terminalActive = true
+	os.Stdout.WriteString(constants.ESCAPE_ENTER_ALT_SCREEN + constants.ESCAPE_ENABLE_PASTE)
+	if e.Settings.Mouse {
+		os.Stdout.WriteString(constants.ESCAPE_ENABLE_MOUSE)
+	}
+	return nil
+}
+
+// ApplySettings brings the terminal and the key decoder in line with settings
+// changed while running.
+func ApplySettings(e *config.Editor) {
+	if e.Keys != nil {
+		e.Keys.SetEscapeTimeout(e.Settings.EscapeTimeout)
+	}
+	if !terminalActive {
+		return
+	}
+	if e.Settings.Mouse {
+		os.Stdout.WriteString(constants.ESCAPE_ENABLE_MOUSE)
+	} else {
+		os.Stdout.WriteString(constants.ESCAPE_DISABLE_MOUSE)
+	}
+}
+
+// RestoreTerminal undoes SetupTerminal: the cursor is put back, the alternate
+// screen is left and raw mode is switched off. Every exit path ends here, so
+// calling it more than once is harmless.
+func RestoreTerminal(e *config.Editor) {
+	if !terminalActive {
+		return
+	}
+	terminalActive = false
+
+	var out strings.Builder
+	out.WriteString(constants.ESCAPE_RESET_ATTRIBUTES)
+	out.WriteString(constants.ESCAPE_CURSOR_DEFAULT)
+	out.WriteString(constants.ESCAPE_SHOW_CURSOR)
+	out.WriteString(constants.ESCAPE_DISABLE_PASTE)
+	out.WriteString(constants.ESCAPE_DISABLE_MOUSE)
+	out.WriteString(constants.ESCAPE_LEAVE_ALT_SCREEN)
+	os.Stdout.WriteString(out.String())
+
+	if e.TerminalState != nil {
+		term.Restore(int(syscall.Stdin), e.TerminalState)
+	}
+}
+
+// SuspendEditor hands the terminal back to the shell and stops the process,
+// like Ctrl-Z does for any other program. It returns once the editor has been
+// resumed and the terminal is set up again.
+func SuspendEditor(e *config.Editor) {
+	if !canSuspend {
+		EditorSetStatusMessage(e, "suspending is not supported on this system")
+		return
+	}
+	UpdateSwapFile(e)
+	RestoreTerminal(e)
+	stopProcess()
+	ResumeEditor(e)
+}
+
+// ResumeEditor sets the terminal up again after the process was stopped and
+// picks up any size change made in the meantime.
+func ResumeEditor(e *config.Editor) {
+	// The shell may have reset the terminal while we were stopped, so this
+	// runs even if the terminal was never restored
+	terminalActive = false
+	if err := SetupTerminal(e); err != nil {
+		e.Quit(err)
+		return
+	}
+	EditorResize(e)
+}
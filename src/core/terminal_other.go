@@ -0,0 +1,8 @@
+//go:build !unix
+
+package core
+
+// Without job control there is no shell to hand the terminal back to.
+const canSuspend = false
+
+func stopProcess() {}
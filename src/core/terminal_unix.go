@@ -0,0 +1,14 @@
+//go:build unix
+
+package core
+
+import "syscall"
+
+const canSuspend = true
+
+// stopProcess stops the editor until the shell continues it. Raw mode turns
+// off the terminal's own Ctrl-Z handling, so the whole process group is
+// stopped the way the shell expects.
+func stopProcess() {
+	syscall.Kill(0, syscall.SIGSTOP)
+}
@@ -0,0 +1,349 @@
+package core
+
+import (
+	"regexp"
+
+	"github.com/deanrtaylor1/go-editor/config"
+	"github.com/deanrtaylor1/go-editor/utils"
+)
+
+// textObject finds the text around p that an operator acts on, as in diw or
+// ca(. It reports false when there's none, like i( outside any parentheses.
+type textObject func(e *config.Editor, p config.Point, count int) (textRange, bool)
+
+// textObjects hold the text objects by the keys that select them. The i forms
+// are the inside of a word, quotes, brackets, tag or paragraph and the a forms
+// take the surrounding space or delimiters too.
+var textObjects = map[string]textObject{
+	"iw": wordObject(false, false),
+	"aw": wordObject(false, true),
+	"iW": wordObject(true, false),
+	"aW": wordObject(true, true),
+	`i"`: quoteObject('"', false),
+	`a"`: quoteObject('"', true),
+	"i'": quoteObject('\'', false),
+	"a'": quoteObject('\'', true),
+	"i`": quoteObject('`', false),
+	"a`": quoteObject('`', true),
+	"i(": bracketObject('(', ')', false),
+	"a(": bracketObject('(', ')', true),
+	"i)": bracketObject('(', ')', false),
+	"a)": bracketObject('(', ')', true),
+	"ib": bracketObject('(', ')', false),
+	"ab": bracketObject('(', ')', true),
+	"i[": bracketObject('[', ']', false),
+	"a[": bracketObject('[', ']', true),
+	"i]": bracketObject('[', ']', false),
+	"a]": bracketObject('[', ']', true),
+	"i{": bracketObject('{', '}', false),
+	"a{": bracketObject('{', '}', true),
+	"i}": bracketObject('{', '}', false),
+	"a}": bracketObject('{', '}', true),
+	"iB": bracketObject('{', '}', false),
+	"aB": bracketObject('{', '}', true),
+	"i<": bracketObject('<', '>', false),
+	"a<": bracketObject('<', '>', true),
+	"i>": bracketObject('<', '>', false),
+	"a>": bracketObject('<', '>', true),
+	"it": tagObject(false),
+	"at": tagObject(true),
+	"ip": paragraphObject(false),
+	"ap": paragraphObject(true),
+}
+
+// runBounds returns the first and last character of the run of characters of
+// one class around p, within its row.
+func runBounds(e *config.Editor, p config.Point, bigWord bool) (int, int) {
+	chars := rowChars(e, p.Row)
+	class := charClass(e, p, bigWord)
+	first := p.Col
+	for first > 0 {
+		prev := utils.PrevGrapheme(chars, first)
+		if charClass(e, config.Point{Row: p.Row, Col: prev}, bigWord) != class {
+			break
+		}
+		first = prev
+	}
+	last := p.Col
+	for {
+		next := utils.NextGrapheme(chars, last)
+		if next >= len(chars) || charClass(e, config.Point{Row: p.Row, Col: next}, bigWord) != class {
+			break
+		}
+		last = next
+	}
+	return first, last
+}
+
+// wordObject selects count words, where iw counts the blanks between words as
+// words too and aw takes each word with the blanks after it.
+func wordObject(bigWord, around bool) textObject {
+	return func(e *config.Editor, p config.Point, count int) (textRange, bool) {
+		chars := rowChars(e, p.Row)
+		if len(chars) == 0 {
+			return textRange{}, false
+		}
+		p.Col = utils.Min(p.Col, lastChar(chars))
+		onBlank := charClass(e, p, bigWord) == classBlank
+		first, last := runBounds(e, p, bigWord)
+
+		runs := countOrOne(count)
+		if around {
+			runs *= 2
+		}
+		for i := 1; i < runs; i++ {
+			next := utils.NextGrapheme(chars, last)
+			if next >= len(chars) {
+				break
+			}
+			_, last = runBounds(e, config.Point{Row: p.Row, Col: next}, bigWord)
+		}
+
+		// Without blanks after the word, aw takes the ones before it
+		endsOnBlank := charClass(e, config.Point{Row: p.Row, Col: last}, bigWord) == classBlank
+		if around && !onBlank && !endsOnBlank && first > 0 {
+			prev := utils.PrevGrapheme(chars, first)
+			if charClass(e, config.Point{Row: p.Row, Col: prev}, bigWord) == classBlank {
+				first, _ = runBounds(e, config.Point{Row: p.Row, Col: prev}, bigWord)
+			}
+		}
+		return textRange{
+			Start: config.Point{Row: p.Row, Col: first},
+			End:   config.Point{Row: p.Row, Col: utils.NextGrapheme(chars, last)},
+		}, true
+	}
+}
+
+// quoteObject selects a quoted string on the cursor's row. Quotes pair up from
+// the start of the row, and a cursor before the string selects the next one.
+// a" takes the blanks after the closing quote too.
+func quoteObject(quote byte, around bool) textObject {
+	return func(e *config.Editor, p config.Point, count int) (textRange, bool) {
+		chars := rowChars(e, p.Row)
+		var quotes []int
+		for i := 0; i < len(chars); i++ {
+			switch chars[i] {
+			case '\\':
+				i++
+			case quote:
+				quotes = append(quotes, i)
+			}
+		}
+		for i := 0; i+1 < len(quotes); i += 2 {
+			open, close := quotes[i], quotes[i+1]
+			if p.Col > close {
+				continue
+			}
+			if !around {
+				return textRange{
+					Start: config.Point{Row: p.Row, Col: open + 1},
+					End:   config.Point{Row: p.Row, Col: close},
+				}, true
+			}
+			end := close + 1
+			for end < len(chars) && (chars[end] == ' ' || chars[end] == '\t') {
+				end++
+			}
+			return textRange{
+				Start: config.Point{Row: p.Row, Col: open},
+				End:   config.Point{Row: p.Row, Col: end},
+			}, true
+		}
+		return textRange{}, false
+	}
+}
+
+func charAt(e *config.Editor, p config.Point) byte {
+	chars := rowChars(e, p.Row)
+	if p.Col >= len(chars) {
+		return 0
+	}
+	return chars[p.Col]
+}
+
+// bracketObject selects the count-th pair of brackets around the cursor, which
+// may span rows. When the brackets sit at the end and start of their rows, the
+// inside is the whole rows between them.
+func bracketObject(open, close byte, around bool) textObject {
+	return func(e *config.Editor, p config.Point, count int) (textRange, bool) {
+		start := p
+		for i := 0; i < countOrOne(count); i++ {
+			if i > 0 {
+				prev, ok := prevChar(e, start)
+				if !ok {
+					return textRange{}, false
+				}
+				start = prev
+			}
+			found, ok := findOpenBracket(e, start, open, close)
+			if !ok {
+				return textRange{}, false
+			}
+			start = found
+		}
+		end, ok := findCloseBracket(e, start, open, close)
+		if !ok {
+			return textRange{}, false
+		}
+
+		if around {
+			end.Col++
+			return textRange{Start: start, End: end}, true
+		}
+		start.Col++
+		endRow := rowChars(e, end.Row)
+		if start.Col >= len(rowChars(e, start.Row)) && end.Row > start.Row && end.Col <= firstNonBlank(endRow) {
+			if end.Row == start.Row+1 {
+				return textRange{}, false
+			}
+			return textRange{
+				Start:    config.Point{Row: start.Row + 1},
+				End:      config.Point{Row: end.Row - 1},
+				Linewise: true,
+			}, true
+		}
+		return textRange{Start: start, End: end}, true
+	}
+}
+
+// findOpenBracket looks back from p for the open bracket that isn't closed
+// before p. A cursor on either bracket belongs to that pair.
+func findOpenBracket(e *config.Editor, p config.Point, open, close byte) (config.Point, bool) {
+	depth := 0
+	for q := p; ; {
+		switch charAt(e, q) {
+		case open:
+			if depth == 0 {
+				return q, true
+			}
+			depth--
+		case close:
+			if q != p {
+				depth++
+			}
+		}
+		prev, ok := prevChar(e, q)
+		if !ok {
+			return p, false
+		}
+		q = prev
+	}
+}
+
+// findCloseBracket looks forward from the open bracket at p for its match.
+func findCloseBracket(e *config.Editor, p config.Point, open, close byte) (config.Point, bool) {
+	depth := 0
+	for q := p; ; {
+		next, ok := nextChar(e, q)
+		if !ok {
+			return p, false
+		}
+		q = next
+		switch charAt(e, q) {
+		case open:
+			depth++
+		case close:
+			if depth == 0 {
+				return q, true
+			}
+			depth--
+		}
+	}
+}
+
+var tagPattern = regexp.MustCompile(`<(/?)([A-Za-z][\w:.-]*)[^<>]*?(/?)>`)
+
+type tagPair struct {
+	openStart, openEnd, closeStart, closeEnd int
+}
+
+// tagObject selects the count-th XML or HTML element around the cursor, its
+// content for it and the tags as well for at.
+func tagObject(around bool) textObject {
+	return func(e *config.Editor, p config.Point, count int) (textRange, bool) {
+		b := e.CurrentBuffer
+		text := b.Text.Slice(0, b.Text.Len())
+		offset := b.PointToOffset(p)
+
+		// Pair the tags up, closing tags without an open one are skipped
+		type openTag struct {
+			name       string
+			start, end int
+		}
+		var open []openTag
+		var enclosing []tagPair
+		for _, m := range tagPattern.FindAllSubmatchIndex(text, -1) {
+			if m[7] > m[6] {
+				continue
+			}
+			name := string(text[m[4]:m[5]])
+			if m[3] == m[2] {
+				open = append(open, openTag{name: name, start: m[0], end: m[1]})
+				continue
+			}
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i].name != name {
+					continue
+				}
+				pair := tagPair{open[i].start, open[i].end, m[0], m[1]}
+				if pair.openStart <= offset && offset < pair.closeEnd {
+					enclosing = append(enclosing, pair)
+				}
+				open = open[:i]
+				break
+			}
+		}
+
+		// Inner elements close first
+		n := countOrOne(count)
+		if n > len(enclosing) {
+			return textRange{}, false
+		}
+		pair := enclosing[n-1]
+		if around {
+			return textRange{Start: b.OffsetToPoint(pair.openStart), End: b.OffsetToPoint(pair.closeEnd)}, true
+		}
+		return textRange{Start: b.OffsetToPoint(pair.openEnd), End: b.OffsetToPoint(pair.closeStart)}, true
+	}
+}
+
+// paragraphObject selects count paragraphs, where ip counts the empty rows
+// between paragraphs as paragraphs too and ap takes each paragraph with the
+// empty rows after it.
+func paragraphObject(around bool) textObject {
+	return func(e *config.Editor, p config.Point, count int) (textRange, bool) {
+		if e.CurrentBuffer.NumRows == 0 {
+			return textRange{}, false
+		}
+		onEmpty := len(rowChars(e, p.Row)) == 0
+		first, last := blockBounds(e, p.Row)
+
+		blocks := countOrOne(count)
+		if around {
+			blocks *= 2
+		}
+		for i := 1; i < blocks && last < lastRow(e); i++ {
+			_, last = blockBounds(e, last+1)
+		}
+
+		// Without empty rows after the paragraph, ap takes the ones before it
+		if around && !onEmpty && len(rowChars(e, last)) > 0 && first > 0 {
+			first, _ = blockBounds(e, first-1)
+		}
+		return textRange{Start: config.Point{Row: first}, End: config.Point{Row: last}, Linewise: true}, true
+	}
+}
+
+// blockBounds returns the first and last row of the run of empty or non-empty
+// rows around row.
+func blockBounds(e *config.Editor, row int) (int, int) {
+	empty := len(rowChars(e, row)) == 0
+	first, last := row, row
+	for first > 0 && (len(rowChars(e, first-1)) == 0) == empty {
+		first--
+	}
+	for last < lastRow(e) && (len(rowChars(e, last+1)) == 0) == empty {
+		last++
+	}
+	return first, last
+}
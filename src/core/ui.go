@@ -3,13 +3,15 @@ package core
 import (
 	"bytes"
 	"fmt"
-	"log"
 	"math"
+	"strings"
 	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/deanrtaylor1/go-editor/config"
 	"github.com/deanrtaylor1/go-editor/constants"
+	"github.com/deanrtaylor1/go-editor/keys"
 	"github.com/deanrtaylor1/go-editor/utils"
 )
 
@@ -101,6 +103,7 @@ func EditorDrawRows(buffer *bytes.Buffer, e *config.Editor, startRow, endRow int
 
 	startPoint, endPoint := e.GetNormalizedSelection()
 	cColor := -1
+	options := e.CurrentBuffer.Options
 
 	for i := startRow; i <= endRow; i++ {
 		fileRow := i + e.RowOff
@@ -113,68 +116,80 @@ func EditorDrawRows(buffer *bytes.Buffer, e *config.Editor, startRow, endRow int
 		if fileRow >= e.CurrentBuffer.NumRows {
 			WriteWelcomeIfNoFile(buffer, screenCols, endRow-startRow+1, i, e)
 		} else {
-			rowLength := e.CurrentBuffer.Rows[fileRow].Length - e.ColOff
+			row := e.CurrentBuffer.Row(fileRow)
 			availableScreenCols := screenCols - e.LineNumberWidth
 
-			if rowLength < 0 {
-				rowLength = 0
-			}
-			if rowLength == 0 {
+			if row.Length == 0 {
 				withinSelection := (fileRow >= startPoint.Row && fileRow <= endPoint.Row)
 				if withinSelection {
 					// Insert a highlighted placeholder character (e.g., a space)
-					FormatSelectedTextHandler(buffer, ' ', &cColor, constants.HL_NORMAL)
+					FormatSelectedTextHandler(buffer, []byte{' '}, &cColor, constants.HL_NORMAL)
 				}
 
 			} else {
-				if rowLength > availableScreenCols {
-					rowLength = availableScreenCols
-				}
-				if e.ColOff < e.CurrentBuffer.Rows[fileRow].Length {
-					highlights := e.CurrentBuffer.Rows[fileRow].Highlighting
-					cColor := -1
-					for j := 0; j < rowLength; j++ {
-
-						c := e.CurrentBuffer.Rows[fileRow].Chars[e.ColOff+j]
-						hl := highlights[e.ColOff+j]
-						if c == ' ' {
-							spaceCount := CountSpaces(e, rowLength, j, fileRow)
-							if j > constants.TAB_STOP && spaceCount == constants.TAB_STOP {
-								AppendTabOrRowIndentBar(e, &j, buffer, fileRow, rowLength)
-								j += constants.TAB_STOP - 1
-								continue
-							}
+				highlights := row.Highlighting
+				cColor := -1
+				// col is the screen column of the character at idx, counted from the start of the row
+				col := 0
+				for idx := 0; idx < row.Length && col < e.ColOff+availableScreenCols; {
+					next := utils.NextGrapheme(row.Chars, idx)
+					c := row.Chars[idx:next]
+					width := utils.CellWidth(c, col, options.TabWidth)
+
+					if col < e.ColOff {
+						// A wide character cut by the left edge is replaced by spaces
+						if col+width > e.ColOff {
+							buffer.WriteString(strings.Repeat(" ", col+width-e.ColOff))
 						}
+						col += width
+						idx = next
+						continue
+					}
+					if col+width > e.ColOff+availableScreenCols {
+						break
+					}
 
-						withinSelection := e.IsWithinSelection(fileRow, j, startPoint, endPoint)
-
-						if fileRow == startPoint.Row && fileRow == endPoint.Row {
-							withinSelection = (e.ColOff+j >= startPoint.Col-e.LineNumberWidth && j <= endPoint.Col-e.LineNumberWidth)
-						} else if fileRow == startPoint.Row {
-							withinSelection = (e.ColOff+j >= startPoint.Col-e.LineNumberWidth)
-						} else if fileRow == endPoint.Row {
-							withinSelection = (e.ColOff+j <= endPoint.Col-e.LineNumberWidth)
-						} else if fileRow > startPoint.Row && fileRow < endPoint.Row {
-							withinSelection = true
+					j := col - e.ColOff
+					if c[0] == ' ' {
+						spaceCount := CountSpaces(row, idx, options.IndentSize)
+						if j > options.IndentSize && spaceCount == options.IndentSize {
+							AppendTabOrRowIndentBar(row, idx+options.IndentSize, options.IndentSize, buffer)
+							idx += options.IndentSize
+							col += options.IndentSize
+							continue
 						}
-
-						if withinSelection {
-							FormatSelectedTextHandler(buffer, c, &cColor, hl)
-						} else if unicode.IsControl(rune(c)) {
-							ControlCHandler(buffer, rune(c), cColor)
-						} else if hl == constants.HL_MATCH {
-							FormatFindResultHandler(buffer, c)
-						} else if hl == constants.HL_NORMAL {
-							NormalFormatHandler(buffer, c, cColor)
-						} else {
-							ColorFormatHandler(buffer, c, &cColor, hl)
+					}
+					if c[0] == '\t' {
+						if j > options.TabWidth && !e.IsWithinSelection(fileRow, idx, startPoint, endPoint) {
+							AppendTabOrRowIndentBar(row, next, width, buffer)
+							idx = next
+							col += width
+							continue
 						}
+						// Tabs are expanded here so the terminal never sees them
+						c = bytes.Repeat([]byte{' '}, width)
+					}
+
+					withinSelection := e.IsWithinSelection(fileRow, idx, startPoint, endPoint)
+					hl := highlights[idx]
+					r, _ := utf8.DecodeRune(c)
+
+					if withinSelection {
+						FormatSelectedTextHandler(buffer, c, &cColor, hl)
+					} else if unicode.IsControl(r) {
+						ControlCHandler(buffer, r, cColor)
+					} else if hl == constants.HL_MATCH {
+						FormatFindResultHandler(buffer, c)
+					} else if hl == constants.HL_NORMAL {
+						NormalFormatHandler(buffer, c, cColor)
+					} else {
+						ColorFormatHandler(buffer, c, &cColor, hl)
 					}
-					buffer.WriteString(constants.FOREGROUND_RESET)
-					cColor = -1
-				} else {
-					buffer.Write([]byte{})
+					col += width
+					idx = next
 				}
+				buffer.WriteString(constants.FOREGROUND_RESET)
+				cColor = -1
 			}
 		}
 
@@ -203,6 +218,9 @@ func EditorDrawStatusBar(buf *bytes.Buffer, e *config.Editor) {
 		modeBgColor = "\x1b[48;5;2m" // Subdued Green background
 
 		modeName = " INSERT "
+	case constants.EDITOR_MODE_COMMAND:
+		modeBgColor = "\x1b[48;5;3m" // Subdued Yellow background
+		modeName = " COMMAND"
 	default:
 		modeBgColor = "\x1b[48;5;236m" // Default dark gray background
 		modeName = "UNKNOWN"
@@ -223,13 +241,20 @@ func EditorDrawStatusBar(buf *bytes.Buffer, e *config.Editor) {
 	}
 
 	status := fmt.Sprintf(" \x1b[32m%.20s\x1b[39m - %d lines %s", e.CurrentBuffer.Name, e.CurrentBuffer.NumRows, dirty) // Green color for filename
+	if e.Recording != 0 {
+		status += fmt.Sprintf(" recording @%c", e.Recording)
+	}
 
 	// Right-aligned Status
-	rStatus := fmt.Sprintf("%s \x1b[34m|\x1b[39m %d/%d", e.CurrentBuffer.BufferSyntax.FileType, e.Cy+1, e.CurrentBuffer.NumRows) // Blue color for separator
+	format := LineEndingName(e.CurrentBuffer.Options.EndOfLine)
+	if !e.CurrentBuffer.Options.InsertFinalNewline {
+		format += " noeol"
+	}
+	rStatus := fmt.Sprintf("%s \x1b[34m|\x1b[39m %s \x1b[34m|\x1b[39m %d/%d", e.CurrentBuffer.BufferSyntax.FileType, format, e.Cy+1, e.CurrentBuffer.NumRows) // Blue color for separator
 
 	// Calculate the visible length of status and rStatus (ignoring ANSI codes)
-	visibleStatusLen := len(status) - 9   // 9 characters are for ANSI codes in 'status'
-	visibleRStatusLen := len(rStatus) - 9 // 9 characters are for ANSI codes in 'rStatus'
+	visibleStatusLen := utils.StringWidth(status) - 9    // 9 characters are for ANSI codes in 'status'
+	visibleRStatusLen := utils.StringWidth(rStatus) - 19 // 19 characters are for ANSI codes in 'rStatus'
 
 	// Calculate the number of spaces needed to fill the gap
 	spaceCount := e.ScreenCols - (visibleStatusLen + visibleRStatusLen + 7)
@@ -254,15 +279,16 @@ func EditorConfirmationPrompt(prompt string, e *config.Editor) bool {
 	for {
 		EditorSetStatusMessage(e, "%s (y/n)", prompt)
 		EditorRefreshScreen(e, constants.INITIAL_REFRESH)
-		c, err := ReadKey(e.Reader)
+		c, err := WaitForKey(e)
 		if err != nil {
-			log.Fatal(err)
+			return false
 		}
 
-		if c == 'y' || c == 'Y' {
+		switch c {
+		case keys.Rune('y'), keys.Rune('Y'):
 			EditorSetStatusMessage(e, "")
 			return true
-		} else if c == 'n' || c == 'N' || c == '\x1b' { // Escape key to cancel
+		case keys.Rune('n'), keys.Rune('N'), keys.Escape: // Escape key to cancel
 			EditorSetStatusMessage(e, "")
 			return false
 		}
@@ -270,30 +296,42 @@ func EditorConfirmationPrompt(prompt string, e *config.Editor) bool {
 	}
 }
 
-func EditorPrompt(prompt string, cb func([]rune, rune, *config.Editor, bool), e *config.Editor) []rune {
+// EditorKeyPrompt shows prompt in the message bar and returns the next key
+// pressed.
+func EditorKeyPrompt(prompt string, e *config.Editor) keys.Key {
+	EditorSetStatusMessage(e, "%s", prompt)
+	EditorRefreshScreen(e, constants.INITIAL_REFRESH)
+	c, err := WaitForKey(e)
+	if err != nil {
+		return keys.Escape
+	}
+	return c
+}
+
+func EditorPrompt(prompt string, cb func([]rune, keys.Key, *config.Editor, bool), e *config.Editor) []rune {
 	buf := []rune{}
 	for {
 		EditorSetStatusMessage(e, "%s", fmt.Sprintf("%s %s", prompt, string(buf)))
 		EditorRefreshScreen(e, constants.INITIAL_REFRESH)
-		c, err := ReadKey(e.Reader)
+		c, err := WaitForKey(e)
 		if err != nil {
-			log.Fatal(err)
+			return nil
 		}
 
-		if c == constants.DEL_KEY || c == utils.CTRL_KEY('h') || c == constants.BACKSPACE {
+		if c == keys.Delete || c == keys.Ctrl('h') || c == keys.Backspace {
 			if len(buf) != 0 {
 				buf = buf[:len(buf)-1]
 				if cb != nil {
 					cb(buf, c, e, false)
 				}
 			}
-		} else if c == '\x1b' {
+		} else if c == keys.Escape {
 			EditorSetStatusMessage(e, "")
 			if cb != nil {
 				cb(buf, c, e, false)
 			}
 			return nil
-		} else if c == '\r' {
+		} else if c == keys.Enter {
 			if len(buf) != 0 {
 				EditorSetStatusMessage(e, "")
 				if cb != nil {
@@ -301,8 +339,8 @@ func EditorPrompt(prompt string, cb func([]rune, rune, *config.Editor, bool), e
 				}
 				return buf
 			}
-		} else if c != utils.CTRL_KEY('c') && c < 128 {
-			buf = append(buf, c)
+		} else if c.Printable() {
+			buf = append(buf, c.Rune)
 		}
 
 		if cb != nil {
@@ -313,11 +351,13 @@ func EditorPrompt(prompt string, cb func([]rune, rune, *config.Editor, bool), e
 
 func EditorDrawMessageBar(buf *bytes.Buffer, e *config.Editor) {
 	buf.WriteString(constants.ESCAPE_CLEAR_TO_LINE_END) // Clear the line
-	msgLen := len(e.StatusMsg)
-	if msgLen > e.ScreenCols {
-		msgLen = e.ScreenCols
+	if e.EditorMode == constants.EDITOR_MODE_COMMAND {
+		line, _ := commandLineView(e)
+		buf.WriteString(line)
+		return
 	}
-	if msgLen > 0 && time.Since(e.StatusMsgTime).Seconds() < 5 {
-		buf.WriteString(e.StatusMsg[:msgLen]) // Write the message if within 5 seconds
+	msg := utils.TruncateToWidth(e.StatusMsg, e.ScreenCols)
+	if len(msg) > 0 && time.Since(e.StatusMsgTime) < constants.STATUS_MESSAGE_TIMEOUT {
+		buf.WriteString(msg)
 	}
 }
@@ -1,44 +1,85 @@
 package core
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/deanrtaylor1/go-editor/config"
-	"github.com/deanrtaylor1/go-editor/constants"
+	"github.com/deanrtaylor1/go-editor/highlighting"
 )
 
 func UndoAction(e *config.Editor) {
-	lastAction, success := e.CurrentBuffer.PopUndo()
+	from, cursor, success := e.CurrentBuffer.Undo(e.Settings.UndoLevels)
+	if !success {
+		return
+	}
+	afterUndo(e, from, cursor)
+}
+
+// UndoSteps goes count states forward or, for a negative count, back in the
+// order they were made, as g+ and g- do.
+func UndoSteps(e *config.Editor, count int) {
+	from, cursor, success := e.CurrentBuffer.UndoSteps(count, e.Settings.UndoLevels)
 	if !success {
 		return
 	}
+	afterUndo(e, from, cursor)
+}
 
-	e.CurrentBuffer.AppendRedo(lastAction, e.UndoHistory)
-
-	switch lastAction.ActionType {
-	case constants.ACTION_UPDATE_ROW:
-		e.CurrentBuffer.ReplaceRowAtIndex(lastAction.Index, lastAction.Row)
-		e.Cy = lastAction.Index
-		e.Cx = lastAction.Cx
-		e.CurrentBuffer.SliceIndex = e.Cx - e.LineNumberWidth
-	case constants.ACTION_APPEND_ROW_TO_PREVIOUS:
-		prevRow, ok := lastAction.PrevRow.(config.Row)
-		if !ok {
-			return
+// UndoEarlier goes back as :earlier does, by a count of states or a time like
+// 30s, 5m, 1h or 2d.
+func UndoEarlier(e *config.Editor, arg string) error {
+	return undoTravel(e, arg, -1)
+}
+
+// UndoLater goes forward as :later does, see UndoEarlier.
+func UndoLater(e *config.Editor, arg string) error {
+	return undoTravel(e, arg, 1)
+}
+
+func undoTravel(e *config.Editor, arg string, direction int) error {
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		arg = "1"
+	}
+	if count, err := strconv.Atoi(arg); err == nil {
+		UndoSteps(e, direction*count)
+		return nil
+	}
+
+	var d time.Duration
+	if days, ok := strings.CutSuffix(arg, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return fmt.Errorf("invalid time: %s", arg)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		parsed, err := time.ParseDuration(arg)
+		if err != nil {
+			return fmt.Errorf("invalid time: %s", arg)
 		}
-		e.CurrentBuffer.Rows[lastAction.Index-1] = prevRow
-		e.CurrentBuffer.InsertRowAtIndex(lastAction.Index, lastAction.Row)
-		e.Cx = lastAction.Cx
-		e.Cy = lastAction.Index
-		e.CurrentBuffer.SliceIndex = lastAction.Cx - e.LineNumberWidth
-	case constants.ACTION_INSERT_ROW:
-		e.CurrentBuffer.RemoveRowAtIndex(lastAction.Index)
-		e.CurrentBuffer.ReplaceRowAtIndex(lastAction.Index, lastAction.Row)
-		e.Cx = lastAction.Cx
-		e.Cy = lastAction.Index
-		e.CurrentBuffer.SliceIndex = e.Cx - e.LineNumberWidth
-	case constants.ACTION_INSERT_CHAR_AT_EOF:
-		e.CurrentBuffer.RemoveRowAtIndex(lastAction.Index)
-		e.Cx = lastAction.Cx
-		e.Cy = lastAction.Index
-		e.CurrentBuffer.SliceIndex = 0
+		d = parsed
+	}
+	from, cursor, success := e.CurrentBuffer.UndoTime(time.Duration(direction)*d, e.Settings.UndoLevels)
+	if success {
+		afterUndo(e, from, cursor)
+	}
+	return nil
+}
+
+// afterUndo brings the screen up to date with text changed from from on and
+// puts the cursor at cursor. Going back to the state that was saved leaves
+// the buffer unmodified again.
+func afterUndo(e *config.Editor, from, cursor config.Point) {
+	if e.CurrentBuffer.IsSaved() {
+		e.CurrentBuffer.Dirty = 0
+		RemoveSwapFile(e)
+	} else {
+		e.CurrentBuffer.Dirty++
 	}
+	highlighting.HighlightFileFromRow(from.Row, e)
+	moveCursorTo(e, cursor)
 }
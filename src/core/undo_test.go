@@ -0,0 +1,74 @@
+package core
+
+import "testing"
+
+func TestUndoBackToSavedState(t *testing.T) {
+	tests := []struct {
+		before string
+		after  string
+		dirty  bool
+	}{
+		{"", "dwu", false},
+		{"", "dwdwuu", false},
+		{"dw", "u", true},
+		{"dw", "u<C-r>", false},
+		{"dwdw", "uu<C-r><C-r>", false},
+		{"dw", "udw", true},
+		{"", "iab<Esc>u", false},
+		{"iab<Esc>", "ic<Esc>u", false},
+	}
+	for _, tt := range tests {
+		e := newTestEditor("a b c d\n")
+		typeKeys(e, tt.before)
+		e.CurrentBuffer.MarkSaved(e.Settings.UndoLevels)
+		typeKeys(e, tt.after)
+		if dirty := e.CurrentBuffer.Dirty > 0; dirty != tt.dirty {
+			t.Errorf("%q after saving on %q: dirty %v, want %v", tt.after, tt.before, dirty, tt.dirty)
+		}
+	}
+}
+
+// undoText is the text the undo tests start from
+const undoText = "one two\nthree four\n"
+
+func TestUndoGroups(t *testing.T) {
+	checkTyping(t, []typingTest{
+		// One insert session is one step however much was typed
+		{undoText, "iab<CR>cd<Esc>u", undoText},
+		{undoText, "iab<Esc>icd<Esc>u", "abone two\nthree four\n"},
+		{undoText, "ia<CR>b<Esc>u", undoText},
+		{undoText, "iab<BS>c<Esc>u", undoText},
+		{undoText, "i{<CR>x<Esc>u", undoText},
+		{undoText, "wcwx<CR>y<Esc>u", undoText},
+		// So is one command with its count
+		{undoText, "dwdwu", "two\nthree four\n"},
+		{undoText, "3dwu", undoText},
+		{undoText, "2ddu", undoText},
+		{undoText, "cwx<Esc>u", undoText},
+		{undoText, "dw.u", "two\nthree four\n"},
+		{undoText, "iab<CR>cd<Esc>u<C-r>", "ab\ncdone two\nthree four\n"},
+	})
+}
+
+func TestUndoRedoEditKinds(t *testing.T) {
+	checkTyping(t, []typingTest{
+		{undoText, ">>u", undoText},
+		{undoText, ">>u<C-r>", "  one two\nthree four\n"},
+		{undoText, "yypu", undoText},
+		{undoText, "yypu<C-r>", "one two\none two\nthree four\n"},
+		{undoText, "ywwpu", undoText},
+		{undoText, "ywwpu<C-r>", "one tone wo\nthree four\n"},
+		{undoText, "vlldu", undoText},
+		{undoText, "vlldu<C-r>", " two\nthree four\n"},
+		{undoText, "Vjdu", undoText},
+		{undoText, "Vjdu<C-r>", ""},
+		{undoText, "qadwq@au", "two\nthree four\n"},
+		{undoText, "dwdwuu<C-r>", "two\nthree four\n"},
+		{undoText, "dwdwuu<C-r><C-r>", "\nthree four\n"},
+		// g- and g+ go through the states in the order they were made
+		{undoText, "dwdwg-", "two\nthree four\n"},
+		{undoText, "dwdw2g-", undoText},
+		{undoText, "dwdw2g-g+", "two\nthree four\n"},
+		{undoText, "dwudwg-", "two\nthree four\n"},
+	})
+}
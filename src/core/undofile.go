@@ -0,0 +1,108 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/deanrtaylor1/go-editor/config"
+)
+
+const undoFileVersion = 1
+
+// undoFile is what an undo file holds: the undo tree of a file, with a hash of
+// the text its current state is so it isn't used on a file changed since.
+type undoFile struct {
+	Version int
+	Path    string
+	Hash    string
+	Tree    *config.UndoTree
+}
+
+// UndoFilePath returns where the undo history of the file at path is kept,
+// in the user's cache directory under a hash of the absolute path.
+func UndoFilePath(path string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(dir, "go-editor", "undo", hex.EncodeToString(sum[:])+".json")
+}
+
+func textHash(text []byte) string {
+	sum := sha256.Sum256(text)
+	return hex.EncodeToString(sum[:])
+}
+
+// WriteUndoFile keeps the undo tree of the current buffer, just saved to
+// path, for the next time the file is opened.
+func WriteUndoFile(e *config.Editor, path string) error {
+	target := UndoFilePath(path)
+	if !e.Settings.UndoFile || target == "" {
+		return nil
+	}
+	b := e.CurrentBuffer
+	b.EndUndoGroup(e.Settings.UndoLevels)
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	data, err := json.Marshal(undoFile{
+		Version: undoFileVersion,
+		Path:    path,
+		Hash:    textHash(b.Text.Bytes()),
+		Tree:    b.UndoTree,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return err
+	}
+	return writePrivateFile(target, data)
+}
+
+// LoadUndoFile gives the current buffer, just read from path, the undo tree
+// kept for the file. An undo file for other text, or one that can't be read,
+// is left alone and the buffer starts with no history.
+func LoadUndoFile(e *config.Editor, path string) {
+	target := UndoFilePath(path)
+	if !e.Settings.UndoFile || target == "" {
+		return
+	}
+	data, err := os.ReadFile(target)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			config.LogToFile(fmt.Sprintf("undo file %s: %s", target, err.Error()))
+		}
+		return
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	var file undoFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		config.LogToFile(fmt.Sprintf("undo file %s: %s", target, err.Error()))
+		return
+	}
+	text := e.CurrentBuffer.Text.Bytes()
+	if file.Version != undoFileVersion || file.Path != path || file.Tree == nil || file.Hash != textHash(text) {
+		return
+	}
+	if err := file.Tree.Check(text); err != nil {
+		config.LogToFile(fmt.Sprintf("undo file %s: %s", target, err.Error()))
+		return
+	}
+	e.CurrentBuffer.UndoTree = file.Tree
+	e.CurrentBuffer.SavedSeq = file.Tree.Current.Seq
+}
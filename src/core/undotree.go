@@ -0,0 +1,97 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/deanrtaylor1/go-editor/config"
+)
+
+// maxPreviewLength is how much of the edits ShowUndoTree shows for a state.
+const maxPreviewLength = 60
+
+// ShowUndoTree lists the undo states of the buffer in a modal, newest first,
+// with when each was made, the state it branched from and a preview of its
+// edits. Enter goes to the chosen state.
+func ShowUndoTree(e *config.Editor) {
+	t := e.CurrentBuffer.UndoTree
+	lines := make([]string, 0, len(t.States))
+	for i := len(t.States) - 1; i >= 0; i-- {
+		s := t.States[i]
+		marker := ' '
+		if s == t.Current {
+			marker = '>'
+		}
+		from := ""
+		if s.Parent != nil {
+			from = fmt.Sprintf("from %d", s.Parent.Seq)
+		}
+		lines = append(lines, fmt.Sprintf("%c %4d  %s  %-9s  %s", marker, s.Seq, s.Time.Format("15:04:05"), from, editPreview(s.Action)))
+	}
+
+	e.Modal = config.InitModal(config.MODAL_TYPE_UNDO_TREE)
+	e.Modal.Title = "Undo tree"
+	e.Modal.Data = lines
+	e.Modal.Results = lines
+	e.ModalOpen = true
+}
+
+// UndoToListed goes to the state at index in the list ShowUndoTree made.
+func UndoToListed(e *config.Editor, index int) {
+	t := e.CurrentBuffer.UndoTree
+	if index < 0 || index >= len(t.States) {
+		return
+	}
+	from, cursor, success := e.CurrentBuffer.UndoGoTo(t.States[len(t.States)-1-index])
+	if !success {
+		return
+	}
+	afterUndo(e, from, cursor)
+}
+
+// editPreview sums up the edits of a state as the text they took out and put
+// in. Typing and deleting in one go reads as a single edit.
+func editPreview(action config.EditorAction) string {
+	if len(action.Edits) == 0 {
+		return "original text"
+	}
+
+	var merged []config.Edit
+	for _, edit := range action.Edits {
+		if n := len(merged); n > 0 && len(edit.Removed) == 0 {
+			last := &merged[n-1]
+			if edit.Offset == last.Offset+len(last.Inserted) {
+				last.Inserted = append(last.Inserted, edit.Inserted...)
+				continue
+			}
+		}
+		if n := len(merged); n > 0 && len(edit.Inserted) == 0 {
+			last := &merged[n-1]
+			if edit.Offset+len(edit.Removed) == last.Offset+len(last.Inserted) && len(edit.Removed) <= len(last.Inserted) {
+				last.Inserted = last.Inserted[:len(last.Inserted)-len(edit.Removed)]
+				continue
+			}
+		}
+		merged = append(merged, config.Edit{
+			Offset:   edit.Offset,
+			Removed:  edit.Removed,
+			Inserted: append([]byte{}, edit.Inserted...),
+		})
+	}
+
+	var parts []string
+	for _, edit := range merged {
+		if len(edit.Removed) > 0 {
+			parts = append(parts, "-"+visibleText(string(edit.Removed)))
+		}
+		if len(edit.Inserted) > 0 {
+			parts = append(parts, "+"+visibleText(string(edit.Inserted)))
+		}
+	}
+	preview := strings.Join(parts, " ")
+	if utf8.RuneCountInString(preview) > maxPreviewLength {
+		preview = string([]rune(preview)[:maxPreviewLength-1]) + "…"
+	}
+	return preview
+}
@@ -12,54 +12,36 @@ func EditorUpdateRow(row *config.Row, e *config.Editor) {
 	if e.Cy < 0 {
 		return
 	}
-	currentRow := e.GetCurrentRow()
-
-	currentRow.Chars = row.Chars
-	currentRow.Length = row.Length
-	currentRow.Highlighting = make([]byte, row.Length)
-	highlighting.Fill(currentRow.Highlighting, constants.HL_NORMAL)
-	currentRow.Tabs = make([]byte, currentRow.Length)
+	row.Highlighting = make([]byte, row.Length)
+	highlighting.Fill(row.Highlighting, constants.HL_NORMAL)
+	row.Tabs = make([]byte, row.Length)
 	MapTabs(e)
 
-	highlighting.SyntaxHighlightStateMachine(&e.CurrentBuffer.Rows[e.Cy], e)
-}
-
-func ReplaceTabsWithSpaces(line []byte) []byte {
-	var result []byte
-	for _, b := range line {
-		if b == '\t' {
-			spacesNeeded := constants.TAB_STOP - (len(result) % constants.TAB_STOP)
-			for j := 0; j < spacesNeeded; j++ {
-				result = append(result, byte(constants.SPACE_RUNE))
-			}
-		} else {
-			result = append(result, b)
-		}
-	}
-	return result
+	highlighting.SyntaxHighlightStateMachine(e.Cy, e)
 }
 
 func MapTabs(e *config.Editor) {
-	currentRow := &e.CurrentBuffer.Rows[e.Cy]
+	currentRow := e.CurrentBuffer.Row(e.Cy)
 
 	if len(currentRow.Tabs) != len(currentRow.Chars) {
 		currentRow.Tabs = make([]byte, len(currentRow.Chars))
 	}
 
+	size := e.CurrentBuffer.Options.IndentSize
 	for i := 0; i < len(currentRow.Chars); {
-		if currentRow.Chars[i] == ' ' && i+constants.TAB_STOP <= len(currentRow.Chars) {
+		if currentRow.Chars[i] == ' ' && i+size <= len(currentRow.Chars) {
 			isTabs := true
-			for j := 1; j < constants.TAB_STOP; j++ {
+			for j := 1; j < size; j++ {
 				if currentRow.Chars[i+j] != ' ' {
 					isTabs = false
 					break
 				}
 			}
 			if isTabs {
-				for j := 0; j < constants.TAB_STOP; j++ {
+				for j := 0; j < size; j++ {
 					currentRow.Tabs[i+j] = constants.HL_TAB_KEY
 				}
-				i += constants.TAB_STOP
+				i += size
 				continue
 			}
 		}
@@ -0,0 +1,23 @@
+package core
+
+import "testing"
+
+func TestVisualDelete(t *testing.T) {
+	checkTyping(t, []typingTest{
+		{"one two\n", "vlld", " two\n"},
+		{"one two\n", "v$d", "\n"},
+		{"one\ntwo\nthree\n", "Vjd", "three\n"},
+		// Selections end after the whole character they end on
+		{"é世x\n", "vld", "x\n"},
+		{"世界x\n", "lvd", "世x\n"},
+		{"éx\n", "vd", "x\n"},
+		// An empty buffer has nothing to select, with or without rows
+		// deleted to get there
+		{"", "vd", ""},
+		{"", "v$d", ""},
+		{"", "Vd", ""},
+		{"", "Vy", ""},
+		{"a\nb\n", "Vjdvd", ""},
+		{"a\nb\n", "VjdVd", ""},
+	})
+}
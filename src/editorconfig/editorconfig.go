@@ -0,0 +1,267 @@
+package editorconfig
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/deanrtaylor1/go-editor/utils"
+)
+
+const FileName = ".editorconfig"
+
+type section struct {
+	glob       string
+	properties map[string]string
+}
+
+type file struct {
+	dir      string
+	root     bool
+	sections []section
+}
+
+// Properties returns the .editorconfig properties that apply to path. Files
+// closer to path override those further up the tree, and the search stops at
+// the first file that declares root = true.
+func Properties(path string) (map[string]string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []file
+	for dir := filepath.Dir(abs); ; {
+		f, err := parseFile(filepath.Join(dir, FileName))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			files = append(files, f)
+			if f.root {
+				break
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	properties := map[string]string{}
+	for i := len(files) - 1; i >= 0; i-- {
+		f := files[i]
+		rel, err := filepath.Rel(f.dir, abs)
+		if err != nil {
+			continue
+		}
+		rel = "/" + filepath.ToSlash(rel)
+		for _, s := range f.sections {
+			if !matchGlob(s.glob, rel) {
+				continue
+			}
+			for k, v := range s.properties {
+				properties[k] = v
+			}
+		}
+	}
+	return properties, nil
+}
+
+func parseFile(path string) (file, error) {
+	f := file{dir: filepath.Dir(path)}
+	handle, err := os.Open(path)
+	if err != nil {
+		return f, err
+	}
+	defer handle.Close()
+
+	var current *section
+	scanner := bufio.NewScanner(handle)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			f.sections = append(f.sections, section{glob: line[1 : len(line)-1], properties: map[string]string{}})
+			current = &f.sections[len(f.sections)-1]
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if current == nil {
+			// Only root is meaningful before the first section
+			if key == "root" {
+				f.root = strings.EqualFold(value, "true")
+			}
+			continue
+		}
+		current.properties[key] = strings.ToLower(value)
+	}
+	return f, scanner.Err()
+}
+
+var numericRange = regexp.MustCompile(`^([+-]?\d+)\.\.([+-]?\d+)$`)
+
+// matchGlob reports whether path, given relative to the .editorconfig with a
+// leading '/', matches an editorconfig section glob.
+func matchGlob(glob string, path string) bool {
+	if !strings.Contains(glob, "/") {
+		glob = "**/" + glob
+	} else if !strings.HasPrefix(glob, "/") {
+		glob = "/" + glob
+	}
+
+	var ranges [][2]int
+	pattern := translateGlob(glob, &ranges)
+	re, err := regexp.Compile("^" + pattern + "$")
+	if err != nil {
+		return false
+	}
+	match := re.FindStringSubmatch(path)
+	if match == nil {
+		return false
+	}
+	for i, r := range ranges {
+		n, err := strconv.Atoi(match[i+1])
+		if err != nil || n < r[0] || n > r[1] {
+			return false
+		}
+	}
+	return true
+}
+
+// translateGlob turns a glob into a regular expression. Numeric ranges become
+// capture groups whose bounds are appended to ranges in order.
+func translateGlob(glob string, ranges *[][2]int) string {
+	var out strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				out.WriteString(regexp.QuoteMeta(string(glob[i])))
+			} else {
+				out.WriteString(`\\`)
+			}
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					// "**/" also matches no directories at all
+					i++
+					out.WriteString(`(?:.*/)?`)
+				} else {
+					out.WriteString(`.*`)
+				}
+			} else {
+				out.WriteString(`[^/]*`)
+			}
+		case '?':
+			out.WriteString(`[^/]`)
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				out.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			i += end + 1
+			negate := strings.HasPrefix(class, "!") || strings.HasPrefix(class, "^")
+			if negate {
+				class = class[1:]
+			}
+			class = strings.ReplaceAll(class, `\`, `\\`)
+			class = strings.ReplaceAll(class, `[`, `\[`)
+			if negate {
+				out.WriteString(`[^/` + class + `]`)
+			} else {
+				out.WriteString(`[` + class + `]`)
+			}
+		case '{':
+			end := matchingBrace(glob, i)
+			if end < 0 {
+				out.WriteString(`\{`)
+				continue
+			}
+			inner := glob[i+1 : end]
+			i = end
+			if m := numericRange.FindStringSubmatch(inner); m != nil {
+				lo, _ := strconv.Atoi(m[1])
+				hi, _ := strconv.Atoi(m[2])
+				*ranges = append(*ranges, [2]int{utils.Min(lo, hi), utils.Max(lo, hi)})
+				out.WriteString(`([+-]?\d+)`)
+				continue
+			}
+			alternatives := splitAlternatives(inner)
+			if len(alternatives) < 2 {
+				out.WriteString(`\{` + translateGlob(inner, ranges) + `\}`)
+				continue
+			}
+			out.WriteString(`(?:`)
+			for j, alt := range alternatives {
+				if j > 0 {
+					out.WriteString(`|`)
+				}
+				out.WriteString(translateGlob(alt, ranges))
+			}
+			out.WriteString(`)`)
+		default:
+			out.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return out.String()
+}
+
+func matchingBrace(glob string, open int) int {
+	depth := 0
+	for i := open; i < len(glob); i++ {
+		switch glob[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitAlternatives splits the inside of a brace expression on the commas
+// that are not nested in another brace expression.
+func splitAlternatives(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
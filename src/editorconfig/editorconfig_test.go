@@ -0,0 +1,118 @@
+package editorconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		glob string
+		path string
+		want bool
+	}{
+		{"*", "/a.go", true},
+		{"*", "/dir/a.go", true},
+		{"*.go", "/a.go", true},
+		{"*.go", "/dir/sub/a.go", true},
+		{"*.go", "/a.go.txt", false},
+		{"a?.go", "/ab.go", true},
+		{"a?.go", "/a.go", false},
+		{"dir/*.go", "/dir/a.go", true},
+		{"dir/*.go", "/dir/sub/a.go", false},
+		{"dir/*.go", "/other/dir/a.go", false},
+		{"/dir/*.go", "/dir/a.go", true},
+		{"dir/**.go", "/dir/sub/a.go", true},
+		{"**/test/*.go", "/test/a.go", true},
+		{"**/test/*.go", "/x/y/test/a.go", true},
+		{"*.{js,ts}", "/a.ts", true},
+		{"*.{js,ts}", "/a.go", false},
+		{"{lib,src/{a,b}}/*.c", "/src/b/x.c", true},
+		{"{lib,src/{a,b}}/*.c", "/src/c/x.c", false},
+		{"*.{single}", "/a.{single}", true},
+		{"[abc].go", "/b.go", true},
+		{"[abc].go", "/d.go", false},
+		{"[!abc].go", "/d.go", true},
+		{"[!abc].go", "/a.go", false},
+		{"[a-c].go", "/b.go", true},
+		{"file{1..3}.txt", "/file2.txt", true},
+		{"file{1..3}.txt", "/file4.txt", false},
+		{"file{3..1}.txt", "/file1.txt", true},
+		{"file{-2..2}.txt", "/file-1.txt", true},
+		{"\\*.go", "/*.go", true},
+		{"\\*.go", "/a.go", false},
+		{"Makefile", "/sub/Makefile", true},
+		{"[unclosed", "/[unclosed", true},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.glob, tt.path); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.glob, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestProperties(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".editorconfig": `root = true
+# comments and lines without = are skipped
+; like this one
+nonsense
+
+[*]
+indent_style = space
+indent_size = 4
+end_of_line = LF
+
+[*.go]
+indent_style = tab
+
+[Makefile]
+indent_style = tab
+`,
+		"sub/.editorconfig": `[*.go]
+indent_size = 8
+
+[*.md]
+trim_trailing_whitespace = false
+`,
+		"nested/.editorconfig": `root = true
+
+[*]
+indent_size = 2
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		path string
+		want map[string]string
+	}{
+		{"a.txt", map[string]string{"indent_style": "space", "indent_size": "4", "end_of_line": "lf"}},
+		{"a.go", map[string]string{"indent_style": "tab", "indent_size": "4", "end_of_line": "lf"}},
+		{"sub/Makefile", map[string]string{"indent_style": "tab", "indent_size": "4", "end_of_line": "lf"}},
+		{"sub/a.go", map[string]string{"indent_style": "tab", "indent_size": "8", "end_of_line": "lf"}},
+		{"sub/deeper/a.md", map[string]string{"indent_style": "space", "indent_size": "4", "end_of_line": "lf", "trim_trailing_whitespace": "false"}},
+		{"nested/a.go", map[string]string{"indent_size": "2"}},
+	}
+	for _, tt := range tests {
+		got, err := Properties(filepath.Join(dir, tt.path))
+		if err != nil {
+			t.Errorf("Properties(%q): %s", tt.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Properties(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
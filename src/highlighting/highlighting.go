@@ -26,6 +26,7 @@ func isDelimiter(c byte) bool {
 
 func EditorSelectSyntaxHighlight(e *config.Editor) {
 	e.CurrentBuffer.BufferSyntax.FileType = "" // Reset to no filetype
+	e.CurrentBuffer.Options = config.NewBufferOptions()
 	if e.FileName == "" {
 		return
 	}
@@ -41,6 +42,7 @@ func EditorSelectSyntaxHighlight(e *config.Editor) {
 				e.CurrentBuffer.BufferSyntax.Keywords = syntax.Syntax
 				e.CurrentBuffer.BufferSyntax.MultiLineCommentStart = syntax.MultiLineCommentStart
 				e.CurrentBuffer.BufferSyntax.MultiLineCommentEnd = syntax.MultiLineCommentEnd
+				e.CurrentBuffer.Options.ApplySyntax(syntax)
 				return
 			}
 		}
@@ -48,25 +50,27 @@ func EditorSelectSyntaxHighlight(e *config.Editor) {
 }
 
 func HighlightFileFromRow(rowStart int, e *config.Editor) {
-	for i := rowStart; i < len(e.CurrentBuffer.Rows); i++ {
-		SyntaxHighlightStateMachine(&e.CurrentBuffer.Rows[i], e)
+	lineCount := e.CurrentBuffer.LineCount()
+	for i := rowStart; i < lineCount; i++ {
+		SyntaxHighlightStateMachine(i, e)
 	}
 
 	e.CurrentBuffer.NeedsFullHighlight = false
 }
 
-func SyntaxHighlightStateMachine(row *config.Row, e *config.Editor) {
+func SyntaxHighlightStateMachine(idx int, e *config.Editor) {
 	if e.CurrentBuffer.BufferSyntax == nil {
 		return
 	}
+	row := e.CurrentBuffer.Row(idx)
 	state := constants.STATE_NORMAL
 	scs := e.CurrentBuffer.BufferSyntax.SingleLineCommentStart
 	mcs := e.CurrentBuffer.BufferSyntax.MultiLineCommentStart
 	mce := e.CurrentBuffer.BufferSyntax.MultiLineCommentEnd
 	scsLen, mcsLen, mceLen := len(scs), len(mcs), len(mce)
 	inString := byte(0)
-	if row.Idx > 0 {
-		if e.CurrentBuffer.Rows[row.Idx-1].HlOpenComment {
+	if idx > 0 {
+		if e.CurrentBuffer.Row(idx - 1).HlOpenComment {
 			row.HlOpenComment = true
 			state = constants.STATE_MLCOMMENT
 		} else {
@@ -97,7 +101,7 @@ func SyntaxHighlightStateMachine(row *config.Row, e *config.Editor) {
 				i += mcsLen - 1
 				if !e.CurrentBuffer.NeedsFullHighlight {
 					e.CurrentBuffer.NeedsFullHighlight = true
-					HighlightFileFromRow(row.Idx, e)
+					HighlightFileFromRow(idx, e)
 					return
 				}
 			} else if c == '"' || c == '\'' {
@@ -147,7 +151,7 @@ func SyntaxHighlightStateMachine(row *config.Row, e *config.Editor) {
 				state = constants.STATE_NORMAL
 				if !e.CurrentBuffer.NeedsFullHighlight {
 					e.CurrentBuffer.NeedsFullHighlight = true
-					HighlightFileFromRow(row.Idx, e)
+					HighlightFileFromRow(idx, e)
 					return
 				}
 			} else {
@@ -199,7 +203,7 @@ func isSeparator(c rune) bool {
 }
 
 func ResetRowHighlights(offset int, e *config.Editor) {
-	currentRow := &e.CurrentBuffer.Rows[e.Cy+offset]
+	currentRow := e.CurrentBuffer.Row(e.Cy + offset)
 
 	currentRow.Highlighting = make([]byte, currentRow.Length)
 	Fill(currentRow.Highlighting, constants.HL_NORMAL)
@@ -0,0 +1,445 @@
+package keys
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	esc = 0x1b
+	// Anything longer is not a sequence we know, it is dropped instead of
+	// waiting for it to end
+	maxSequenceLength = 64
+)
+
+var (
+	pasteStart = []byte("\x1b[200~")
+	pasteEnd   = []byte("\x1b[201~")
+)
+
+// Decoder turns the bytes a terminal sends into keys. A lone ESC and the
+// start of an escape sequence look the same, so after an ESC the decoder
+// waits up to the escape timeout for the rest of the sequence.
+type Decoder struct {
+	chunks  chan []byte
+	err     error
+	closed  bool
+	buf     []byte
+	timeout atomic.Int64
+}
+
+// NewDecoder starts reading r in the background.
+func NewDecoder(r io.Reader, escapeTimeout time.Duration) *Decoder {
+	d := &Decoder{chunks: make(chan []byte, 16)}
+	d.SetEscapeTimeout(escapeTimeout)
+	go d.read(r)
+	return d
+}
+
+// SetEscapeTimeout changes how long to wait for the rest of an escape
+// sequence. It may be called while another goroutine is reading keys.
+func (d *Decoder) SetEscapeTimeout(timeout time.Duration) {
+	d.timeout.Store(int64(timeout))
+}
+
+func (d *Decoder) read(r io.Reader) {
+	for {
+		b := make([]byte, 1024)
+		n, err := r.Read(b)
+		if n > 0 {
+			d.chunks <- b[:n]
+		}
+		if err != nil {
+			d.err = err
+			close(d.chunks)
+			return
+		}
+	}
+}
+
+// fill waits for more input, at most timeout when it is not negative. It
+// reports whether anything arrived.
+func (d *Decoder) fill(timeout time.Duration) bool {
+	if d.closed {
+		return false
+	}
+	var expired <-chan time.Time
+	if timeout >= 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case chunk, ok := <-d.chunks:
+		if !ok {
+			d.closed = true
+			return false
+		}
+		d.buf = append(d.buf, chunk...)
+		return true
+	case <-expired:
+		return false
+	}
+}
+
+// ReadKey blocks until the next key press. It returns an error once the
+// input is closed and everything before it has been decoded.
+func (d *Decoder) ReadKey() (Key, error) {
+	for {
+		if len(d.buf) == 0 {
+			if !d.fill(-1) {
+				return Key{}, d.err
+			}
+			continue
+		}
+
+		if bytes.HasPrefix(d.buf, pasteStart) {
+			return d.readPaste(), nil
+		}
+
+		key, n := parse(d.buf)
+		if n == 0 && !d.fill(time.Duration(d.timeout.Load())) {
+			// The rest never came, so it was not a sequence after all
+			key, n = parsePartial(d.buf)
+		}
+		if n > 0 {
+			d.buf = d.buf[n:]
+			return key, nil
+		}
+	}
+}
+
+// readPaste collects everything up to the end of a bracketed paste. The
+// terminal always sends the end marker, so there is no timeout, a large
+// paste may take several reads to arrive.
+func (d *Decoder) readPaste() Key {
+	for {
+		text := d.buf[len(pasteStart):]
+		if end := bytes.Index(text, pasteEnd); end >= 0 {
+			d.buf = text[end+len(pasteEnd):]
+			return Paste(pasteText(text[:end]))
+		}
+		if !d.fill(-1) {
+			d.buf = nil
+			return Paste(pasteText(text))
+		}
+	}
+}
+
+// pasteText turns the carriage returns terminals send for line breaks into
+// newlines.
+func pasteText(b []byte) string {
+	b = bytes.ReplaceAll(b, []byte("\r\n"), []byte{'\n'})
+	return string(bytes.ReplaceAll(b, []byte{'\r'}, []byte{'\n'}))
+}
+
+// parse decodes the key at the start of b and returns how many bytes it
+// used, or 0 when b ends in the middle of a key.
+func parse(b []byte) (Key, int) {
+	if b[0] != esc {
+		return parseChar(b)
+	}
+	if len(b) == 1 {
+		return Key{}, 0
+	}
+	switch b[1] {
+	case '[':
+		return parseCSI(b)
+	case 'O':
+		return parseSS3(b)
+	case esc:
+		return Escape, 1
+	}
+
+	// Alt sends ESC in front of the key
+	key, n := parseChar(b[1:])
+	if n == 0 {
+		return Key{}, 0
+	}
+	key.Mod |= ModAlt
+	return key, n + 1
+}
+
+// parsePartial decodes b when no more input is coming.
+func parsePartial(b []byte) (Key, int) {
+	if b[0] == esc {
+		return Escape, 1
+	}
+	return Key{}, 1
+}
+
+func parseChar(b []byte) (Key, int) {
+	if !utf8.FullRune(b) {
+		return Key{}, 0
+	}
+	r, n := utf8.DecodeRune(b)
+	if r == utf8.RuneError && n <= 1 {
+		return Key{}, 1
+	}
+	return charKey(r), n
+}
+
+// charKey maps a character, including the control characters terminals send
+// for Ctrl combinations, to its key.
+func charKey(r rune) Key {
+	switch {
+	case r == '\r':
+		return Enter
+	case r == '\t':
+		return Tab
+	case r == 0x7f, r == 0x08:
+		return Backspace
+	case r == esc:
+		return Escape
+	case r == 0:
+		return Ctrl(' ')
+	case r < 0x1b:
+		return Ctrl('a' + r - 1)
+	case r < 0x20:
+		return Ctrl(r + 0x40)
+	}
+	return Rune(r)
+}
+
+var csiFinalKeys = map[byte]Key{
+	'A': Up,
+	'B': Down,
+	'C': Right,
+	'D': Left,
+	'H': Home,
+	'F': End,
+	'P': F(1),
+	'Q': F(2),
+	'R': F(3),
+	'S': F(4),
+}
+
+var csiTildeKeys = map[int]Key{
+	1:  Home,
+	2:  Insert,
+	3:  Delete,
+	4:  End,
+	5:  PageUp,
+	6:  PageDown,
+	7:  Home,
+	8:  End,
+	11: F(1),
+	12: F(2),
+	13: F(3),
+	14: F(4),
+	15: F(5),
+	17: F(6),
+	18: F(7),
+	19: F(8),
+	20: F(9),
+	21: F(10),
+	23: F(11),
+	24: F(12),
+}
+
+// parseCSI decodes ESC [ params final.
+func parseCSI(b []byte) (Key, int) {
+	// The Linux console sends ESC [ [ A to ESC [ [ E for F1 to F5
+	if len(b) > 2 && b[2] == '[' {
+		if len(b) < 4 {
+			return Key{}, 0
+		}
+		if b[3] >= 'A' && b[3] <= 'E' {
+			return F(int(b[3]-'A') + 1), 4
+		}
+		return Key{}, 4
+	}
+
+	end := 2
+	for {
+		if end >= len(b) {
+			if end >= maxSequenceLength {
+				return Key{}, end
+			}
+			return Key{}, 0
+		}
+		c := b[end]
+		if c >= 0x40 && c <= 0x7e {
+			break
+		}
+		if c < 0x20 || c > 0x3f {
+			// Not a valid sequence, drop what was read so far
+			return Key{}, end
+		}
+		end++
+	}
+	final := b[end]
+	n := end + 1
+	if b[2] == '<' && (final == 'M' || final == 'm') {
+		return parseSGRMouse(string(b[3:end]), final), n
+	}
+	params := parseParams(string(b[2:end]))
+
+	switch final {
+	case '~':
+		if param(params, 0, 0) == 27 {
+			// xterm modifyOtherKeys: ESC [ 27 ; mod ; code ~
+			return codepointKey(param(params, 2, 0), modifier(param(params, 1, 1))), n
+		}
+		key, ok := csiTildeKeys[param(params, 0, 0)]
+		if !ok {
+			return Key{}, n
+		}
+		key.Mod = modifier(param(params, 1, 1))
+		return key, n
+	case 'u':
+		// kitty and fixterms: ESC [ code[:shifted] ; mod u
+		code := param(params, 0, 0)
+		mod := modifier(param(params, 1, 1))
+		if mod&ModShift != 0 && len(params) > 0 && len(params[0]) > 1 && params[0][1] > 0 {
+			code = params[0][1]
+		}
+		return codepointKey(code, mod), n
+	case 'Z':
+		return Key{Code: CodeTab, Mod: ModShift}, n
+	}
+	key, ok := csiFinalKeys[final]
+	if !ok {
+		return Key{}, n
+	}
+	key.Mod = modifier(param(params, 1, 1))
+	return key, n
+}
+
+// parseSGRMouse decodes the parameters of an SGR mouse report,
+// ESC [ < button ; x ; y M, or m when a button is released.
+func parseSGRMouse(s string, final byte) Key {
+	params := parseParams(s)
+	code := param(params, 0, -1)
+	x := param(params, 1, 0)
+	y := param(params, 2, 0)
+	if code < 0 || x < 1 || y < 1 {
+		return Key{}
+	}
+
+	var mod Modifier
+	if code&4 != 0 {
+		mod |= ModShift
+	}
+	if code&8 != 0 {
+		mod |= ModAlt
+	}
+	if code&16 != 0 {
+		mod |= ModCtrl
+	}
+
+	m := Mouse{X: x - 1, Y: y - 1}
+	switch {
+	case code&64 != 0:
+		m.Button = MouseWheelUp + MouseButton(code&3)
+	case code&3 == 3:
+		m.Button = MouseNone
+	default:
+		m.Button = MouseLeft + MouseButton(code&3)
+	}
+	switch {
+	case final == 'm':
+		m.Action = MouseRelease
+	case code&32 != 0 && m.Button == MouseNone:
+		m.Action = MouseMove
+	case code&32 != 0:
+		m.Action = MouseDrag
+	}
+	return Key{Code: CodeMouse, Mod: mod, Mouse: m}
+}
+
+// parseSS3 decodes ESC O final, sent for arrows and F1 to F4 in application
+// mode. Some terminals put a modifier between the O and the final byte.
+func parseSS3(b []byte) (Key, int) {
+	if len(b) < 3 {
+		return Key{}, 0
+	}
+	mod := Modifier(0)
+	n := 2
+	if b[2] >= '0' && b[2] <= '9' {
+		if len(b) < 4 {
+			return Key{}, 0
+		}
+		mod = modifier(int(b[2] - '0'))
+		n++
+	}
+	final := b[n]
+	n++
+	if final == 'M' {
+		return Key{Code: CodeEnter, Mod: mod}, n
+	}
+	key, ok := csiFinalKeys[final]
+	if !ok {
+		return Key{}, n
+	}
+	key.Mod = mod
+	return key, n
+}
+
+// parseParams splits "1;5" or "97:65;2" into parameters and their
+// sub-parameters. Missing numbers are -1.
+func parseParams(s string) [][]int {
+	if s == "" {
+		return nil
+	}
+	var params [][]int
+	for _, field := range strings.Split(s, ";") {
+		var sub []int
+		for _, part := range strings.Split(field, ":") {
+			value, err := strconv.Atoi(part)
+			if err != nil {
+				value = -1
+			}
+			sub = append(sub, value)
+		}
+		params = append(params, sub)
+	}
+	return params
+}
+
+// param returns the first sub-parameter of parameter i, or def when it is
+// missing.
+func param(params [][]int, i int, def int) int {
+	if i >= len(params) || params[i][0] < 0 {
+		return def
+	}
+	return params[i][0]
+}
+
+// modifier decodes the xterm modifier parameter, 1 plus the modifier bits.
+// Caps and num lock are ignored.
+func modifier(p int) Modifier {
+	if p <= 1 {
+		return 0
+	}
+	return Modifier(p-1) & (ModShift | ModAlt | ModCtrl | ModMeta)
+}
+
+// codepointKey builds the key for a character sent with its modifiers, as
+// kitty and modifyOtherKeys do. The result matches what the plain terminal
+// encoding would have produced, so Ctrl+q is Ctrl('q') either way.
+func codepointKey(code int, mod Modifier) Key {
+	if code <= 0 || code > unicode.MaxRune {
+		return Key{}
+	}
+	key := charKey(rune(code))
+	key.Mod |= mod
+	if key.Code != CodeRune {
+		return key
+	}
+	// Shift is folded into the character, like typing it would
+	if key.Mod&ModShift != 0 {
+		key.Mod &^= ModShift
+		key.Rune = unicode.ToUpper(key.Rune)
+	}
+	if key.Mod&ModCtrl != 0 {
+		key.Rune = unicode.ToLower(key.Rune)
+	}
+	return key
+}
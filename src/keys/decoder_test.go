@@ -0,0 +1,105 @@
+package keys
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readAll decodes the input of r until it runs out.
+func readAll(r io.Reader, escapeTimeout time.Duration) []Key {
+	d := NewDecoder(r, escapeTimeout)
+	var got []Key
+	for {
+		key, err := d.ReadKey()
+		if err != nil {
+			return got
+		}
+		got = append(got, key)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	ctrlShiftUp := Up
+	ctrlShiftUp.Mod = ModCtrl | ModShift
+	shiftF5 := F(5)
+	shiftF5.Mod = ModShift
+
+	tests := []struct {
+		input string
+		want  []Key
+	}{
+		{"ab", []Key{Rune('a'), Rune('b')}},
+		{"é世", []Key{Rune('é'), Rune('世')}},
+		{"\r\t\x7f\x08", []Key{Enter, Tab, Backspace, Backspace}},
+		{"\x11\x00\x1c", []Key{Ctrl('q'), Ctrl(' '), Ctrl('\\')}},
+		{"\x1b", []Key{Escape}},
+		{"\x1b\x1b", []Key{Escape, Escape}},
+		{"\x1bx\x1bé", []Key{Alt('x'), Alt('é')}},
+		{"\x1b[A\x1b[B\x1b[C\x1b[D", []Key{Up, Down, Right, Left}},
+		{"\x1bOA\x1bOH\x1bOP", []Key{Up, Home, F(1)}},
+		{"\x1b[1;6A", []Key{ctrlShiftUp}},
+		{"\x1b[3~\x1b[5~\x1b[6~\x1b[2~", []Key{Delete, PageUp, PageDown, Insert}},
+		{"\x1b[1~\x1b[4~\x1b[7~\x1b[8~", []Key{Home, End, Home, End}},
+		{"\x1b[15;2~\x1b[24~", []Key{shiftF5, F(12)}},
+		{"\x1b[[A\x1b[[E", []Key{F(1), F(5)}},
+		{"\x1b[Z", []Key{{Code: CodeTab, Mod: ModShift}}},
+		{"\x1b[113;5u\x1b[97:65;2u", []Key{Ctrl('q'), Rune('A')}},
+		{"\x1b[27;5;113~", []Key{Ctrl('q')}},
+		{"\x1b[<0;3;4M", []Key{{Code: CodeMouse, Mouse: Mouse{Button: MouseLeft, X: 2, Y: 3}}}},
+		{"\x1b[<0;3;4m", []Key{{Code: CodeMouse, Mouse: Mouse{Button: MouseLeft, Action: MouseRelease, X: 2, Y: 3}}}},
+		{"\x1b[<32;1;1M", []Key{{Code: CodeMouse, Mouse: Mouse{Button: MouseLeft, Action: MouseDrag}}}},
+		{"\x1b[<65;1;1M", []Key{{Code: CodeMouse, Mouse: Mouse{Button: MouseWheelDown}}}},
+		{"\x1b[<16;1;1M", []Key{{Code: CodeMouse, Mod: ModCtrl, Mouse: Mouse{Button: MouseLeft}}}},
+		{"\x1b[200~a\r\nb\rc\x1b[201~d", []Key{Paste("a\nb\nc"), Rune('d')}},
+		{"\x1b[200~no end", []Key{Paste("no end")}},
+		{"\x1b[99Xa", []Key{{}, Rune('a')}},
+		{"\x1b[\x01a", []Key{{}, Ctrl('a'), Rune('a')}},
+	}
+	for _, tt := range tests {
+		got := readAll(strings.NewReader(tt.input), time.Second)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q decoded to %v, want %v", tt.input, Format(got), Format(tt.want))
+		}
+	}
+}
+
+// chunkReader hands out one chunk per read, waiting delay before each.
+type chunkReader struct {
+	chunks []string
+	delay  time.Duration
+}
+
+func (r *chunkReader) Read(b []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	time.Sleep(r.delay)
+	n := copy(b, r.chunks[0])
+	r.chunks = r.chunks[1:]
+	return n, nil
+}
+
+func TestDecoderEscapeTimeout(t *testing.T) {
+	tests := []struct {
+		chunks  []string
+		timeout time.Duration
+		want    []Key
+	}{
+		// The rest of the sequence comes in time
+		{[]string{"\x1b", "[A", "\x1b[", "3~"}, time.Second, []Key{Up, Delete}},
+		{[]string{"\x1b", "x"}, time.Second, []Key{Alt('x')}},
+		{[]string{"\x1b[200~ab", "c\x1b[2", "01~"}, time.Millisecond, []Key{Paste("abc")}},
+		// It doesn't, so ESC was pressed on its own
+		{[]string{"\x1b", "x"}, time.Millisecond, []Key{Escape, Rune('x')}},
+		{[]string{"\x1b", "[A"}, time.Millisecond, []Key{Escape, Rune('['), Rune('A')}},
+	}
+	for _, tt := range tests {
+		got := readAll(&chunkReader{chunks: tt.chunks, delay: 50 * time.Millisecond}, tt.timeout)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q with a %s timeout decoded to %v, want %v", tt.chunks, tt.timeout, Format(got), Format(tt.want))
+		}
+	}
+}
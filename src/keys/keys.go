@@ -0,0 +1,254 @@
+package keys
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Code says which key was pressed. Ordinary characters are CodeRune with the
+// character in Key.Rune, everything else has its own code.
+type Code int
+
+const (
+	CodeUnknown Code = iota
+	CodeRune
+	CodeEnter
+	CodeTab
+	CodeBackspace
+	CodeEscape
+	CodeInsert
+	CodeDelete
+	CodeUp
+	CodeDown
+	CodeLeft
+	CodeRight
+	CodeHome
+	CodeEnd
+	CodePageUp
+	CodePageDown
+	CodeF1
+	CodeF2
+	CodeF3
+	CodeF4
+	CodeF5
+	CodeF6
+	CodeF7
+	CodeF8
+	CodeF9
+	CodeF10
+	CodeF11
+	CodeF12
+	// CodePaste is a whole bracketed paste, the text is in Key.Text
+	CodePaste
+	// CodeMouse is a mouse event, the details are in Key.Mouse
+	CodeMouse
+)
+
+// Modifier is a set of modifier keys. The bits match the xterm and kitty
+// encoding of the modifier parameter minus one.
+type Modifier uint8
+
+const (
+	ModShift Modifier = 1 << iota
+	ModAlt
+	ModCtrl
+	ModMeta
+)
+
+// MouseButton says which button a mouse event is about. Each direction of
+// the wheel counts as a button.
+type MouseButton uint8
+
+const (
+	MouseNone MouseButton = iota
+	MouseLeft
+	MouseMiddle
+	MouseRight
+	MouseWheelUp
+	MouseWheelDown
+	MouseWheelLeft
+	MouseWheelRight
+)
+
+type MouseAction uint8
+
+const (
+	MousePress MouseAction = iota
+	MouseRelease
+	// MouseDrag is a move with the button held down
+	MouseDrag
+	// MouseMove is a move with no button held, only some terminals send it
+	MouseMove
+)
+
+// Mouse is a mouse event at cell X, Y of the screen, counted from 0.
+type Mouse struct {
+	Button MouseButton
+	Action MouseAction
+	X      int
+	Y      int
+}
+
+// Key is a single key press. Keys are comparable, so they can be used in
+// switch statements and as map keys.
+type Key struct {
+	Code  Code
+	Rune  rune
+	Mod   Modifier
+	Text  string
+	Mouse Mouse
+}
+
+var (
+	Enter     = Key{Code: CodeEnter}
+	Tab       = Key{Code: CodeTab}
+	Backspace = Key{Code: CodeBackspace}
+	Escape    = Key{Code: CodeEscape}
+	Insert    = Key{Code: CodeInsert}
+	Delete    = Key{Code: CodeDelete}
+	Up        = Key{Code: CodeUp}
+	Down      = Key{Code: CodeDown}
+	Left      = Key{Code: CodeLeft}
+	Right     = Key{Code: CodeRight}
+	Home      = Key{Code: CodeHome}
+	End       = Key{Code: CodeEnd}
+	PageUp    = Key{Code: CodePageUp}
+	PageDown  = Key{Code: CodePageDown}
+)
+
+// Rune is the key that types r.
+func Rune(r rune) Key {
+	return Key{Code: CodeRune, Rune: r}
+}
+
+// Ctrl is r pressed together with Ctrl. Letters are always lower case.
+func Ctrl(r rune) Key {
+	return Key{Code: CodeRune, Rune: unicode.ToLower(r), Mod: ModCtrl}
+}
+
+// Alt is r pressed together with Alt.
+func Alt(r rune) Key {
+	return Key{Code: CodeRune, Rune: r, Mod: ModAlt}
+}
+
+// F returns function key n, F1 to F12.
+func F(n int) Key {
+	return Key{Code: CodeF1 + Code(n-1)}
+}
+
+// Paste is text the terminal delivered as one bracketed paste.
+func Paste(text string) Key {
+	return Key{Code: CodePaste, Text: text}
+}
+
+// Printable reports whether the key types its rune, with no modifier held.
+func (k Key) Printable() bool {
+	return k.Code == CodeRune && k.Mod == 0 && unicode.IsPrint(k.Rune)
+}
+
+// IsArrow reports whether the key is one of the arrow keys, with or without
+// modifiers.
+func (k Key) IsArrow() bool {
+	return k.Code >= CodeUp && k.Code <= CodeRight
+}
+
+var codeNames = map[Code]string{
+	CodeEnter:     "CR",
+	CodeTab:       "Tab",
+	CodeBackspace: "BS",
+	CodeEscape:    "Esc",
+	CodeInsert:    "Insert",
+	CodeDelete:    "Del",
+	CodeUp:        "Up",
+	CodeDown:      "Down",
+	CodeLeft:      "Left",
+	CodeRight:     "Right",
+	CodeHome:      "Home",
+	CodeEnd:       "End",
+	CodePageUp:    "PageUp",
+	CodePageDown:  "PageDown",
+	CodeF1:        "F1",
+	CodeF2:        "F2",
+	CodeF3:        "F3",
+	CodeF4:        "F4",
+	CodeF5:        "F5",
+	CodeF6:        "F6",
+	CodeF7:        "F7",
+	CodeF8:        "F8",
+	CodeF9:        "F9",
+	CodeF10:       "F10",
+	CodeF11:       "F11",
+	CodeF12:       "F12",
+	CodePaste:     "Paste",
+}
+
+var mouseButtonNames = map[MouseButton]string{
+	MouseLeft:       "Left",
+	MouseMiddle:     "Middle",
+	MouseRight:      "Right",
+	MouseWheelUp:    "ScrollWheelUp",
+	MouseWheelDown:  "ScrollWheelDown",
+	MouseWheelLeft:  "ScrollWheelLeft",
+	MouseWheelRight: "ScrollWheelRight",
+}
+
+// mouseName names mouse events the way vim does, "LeftMouse", "LeftDrag",
+// "LeftRelease" and "ScrollWheelUp".
+func mouseName(m Mouse) string {
+	name := mouseButtonNames[m.Button]
+	if m.Button >= MouseWheelUp {
+		return name
+	}
+	switch m.Action {
+	case MouseRelease:
+		return name + "Release"
+	case MouseDrag:
+		return name + "Drag"
+	case MouseMove:
+		return "MouseMove"
+	}
+	return name + "Mouse"
+}
+
+// String writes the key in vim notation, "a", "<C-q>", "<S-Up>", "<F5>".
+func (k Key) String() string {
+	name := ""
+	switch k.Code {
+	case CodeUnknown:
+		return "<Unknown>"
+	case CodeRune:
+		switch k.Rune {
+		case '<':
+			name = "lt"
+		case ' ':
+			name = "Space"
+		default:
+			if k.Mod == 0 {
+				return string(k.Rune)
+			}
+			name = string(k.Rune)
+		}
+	case CodeMouse:
+		name = mouseName(k.Mouse)
+	default:
+		name = codeNames[k.Code]
+	}
+
+	var b strings.Builder
+	b.WriteString("<")
+	if k.Mod&ModCtrl != 0 {
+		b.WriteString("C-")
+	}
+	if k.Mod&ModAlt != 0 {
+		b.WriteString("M-")
+	}
+	if k.Mod&ModShift != 0 {
+		b.WriteString("S-")
+	}
+	if k.Mod&ModMeta != 0 {
+		b.WriteString("D-")
+	}
+	b.WriteString(name)
+	b.WriteString(">")
+	return b.String()
+}
@@ -0,0 +1,119 @@
+package keys
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const pasteEndName = "<PasteEnd>"
+
+// Format writes keys in the notation String uses, so they can be kept as text
+// and edited. A paste is written as its text between <Paste> and <PasteEnd>.
+// Mouse events depend on the screen and are not worth keeping, Parse doesn't
+// read them back.
+func Format(keys []Key) string {
+	var b strings.Builder
+	for _, key := range keys {
+		if key.Code == CodePaste {
+			b.WriteString("<Paste>")
+			b.WriteString(strings.ReplaceAll(key.Text, "<", "<lt>"))
+			b.WriteString(pasteEndName)
+			continue
+		}
+		b.WriteString(key.String())
+	}
+	return b.String()
+}
+
+// Parse reads keys written by Format. Anything in angle brackets that doesn't
+// name a key is taken as the characters it is made of, and line breaks, tabs
+// and escapes typed into the text are the keys they stand for.
+func Parse(s string) []Key {
+	var parsed []Key
+	for s != "" {
+		if s[0] == '<' {
+			if end := strings.IndexByte(s, '>'); end > 0 {
+				if key, ok := parseName(s[1:end]); ok {
+					s = s[end+1:]
+					if key.Code == CodePaste {
+						text := s
+						if i := strings.Index(s, pasteEndName); i >= 0 {
+							text, s = s[:i], s[i+len(pasteEndName):]
+						} else {
+							s = ""
+						}
+						key = Paste(strings.ReplaceAll(text, "<lt>", "<"))
+					}
+					parsed = append(parsed, key)
+					continue
+				}
+			}
+		}
+
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		switch r {
+		case '\r', '\n':
+			parsed = append(parsed, Enter)
+		case '\t':
+			parsed = append(parsed, Tab)
+		case '\x1b':
+			parsed = append(parsed, Escape)
+		default:
+			parsed = append(parsed, Rune(r))
+		}
+	}
+	return parsed
+}
+
+// parseName reads the name between angle brackets, with any modifiers in
+// front of it like C- or S-.
+func parseName(name string) (Key, bool) {
+	var mod Modifier
+	for len(name) > 2 && name[1] == '-' {
+		switch unicode.ToUpper(rune(name[0])) {
+		case 'C':
+			mod |= ModCtrl
+		case 'M', 'A':
+			mod |= ModAlt
+		case 'S':
+			mod |= ModShift
+		case 'D':
+			mod |= ModMeta
+		default:
+			return Key{}, false
+		}
+		name = name[2:]
+	}
+
+	var key Key
+	switch {
+	case strings.EqualFold(name, "lt"):
+		key = Rune('<')
+	case strings.EqualFold(name, "Space"):
+		key = Rune(' ')
+	case utf8.RuneCountInString(name) == 1:
+		if mod == 0 {
+			return Key{}, false
+		}
+		r, _ := utf8.DecodeRuneInString(name)
+		if mod&ModCtrl != 0 {
+			r = unicode.ToLower(r)
+		}
+		key = Rune(r)
+	default:
+		found := false
+		for code, codeName := range codeNames {
+			if strings.EqualFold(name, codeName) {
+				key, found = Key{Code: code}, true
+				break
+			}
+		}
+		if !found {
+			return Key{}, false
+		}
+	}
+	key.Mod = mod
+	return key, true
+}
@@ -0,0 +1,59 @@
+package keys
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormatParseRoundTrip(t *testing.T) {
+	shiftTab := Key{Code: CodeTab, Mod: ModShift}
+	ctrlAltLeft := Left
+	ctrlAltLeft.Mod = ModCtrl | ModAlt
+
+	tests := []struct {
+		keys []Key
+		text string
+	}{
+		{[]Key{Rune('d'), Rune('w'), Rune('é'), Rune('世')}, "dwé世"},
+		{[]Key{Rune('<'), Rune(' '), Rune('>')}, "<lt><Space>>"},
+		{[]Key{Rune('i'), Rune('<'), Rune('C'), Rune('R'), Rune('>'), Escape}, "i<lt>CR><Esc>"},
+		{[]Key{Enter, Tab, Backspace, Escape}, "<CR><Tab><BS><Esc>"},
+		{[]Key{Ctrl('r'), Alt('x'), shiftTab, ctrlAltLeft, F(5)}, "<C-r><M-x><S-Tab><C-M-Left><F5>"},
+		{[]Key{Paste("a{\n\tb<CR>}"), Rune('u')}, "<Paste>a{\n\tb<lt>CR>}<PasteEnd>u"},
+		{[]Key{Paste("<PasteEnd>"), Paste("")}, "<Paste><lt>PasteEnd><PasteEnd><Paste><PasteEnd>"},
+	}
+	for _, tt := range tests {
+		text := Format(tt.keys)
+		if text != tt.text {
+			t.Errorf("Format(%v) = %q, want %q", tt.keys, text, tt.text)
+		}
+		if got := Parse(text); !reflect.DeepEqual(got, tt.keys) {
+			t.Errorf("Parse(%q) = %v, want %v", text, got, tt.keys)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		want []Key
+	}{
+		{"", nil},
+		// Names are read whatever their case, and what isn't one is text
+		{"<esc><c-R><s-tab>", []Key{Escape, Ctrl('r'), {Code: CodeTab, Mod: ModShift}}},
+		{"<x>", []Key{Rune('<'), Rune('x'), Rune('>')}},
+		{"<X-a>", []Key{Rune('<'), Rune('X'), Rune('-'), Rune('a'), Rune('>')}},
+		{"<<Esc>", []Key{Rune('<'), Escape}},
+		{"a<", []Key{Rune('a'), Rune('<')}},
+		{"<>", []Key{Rune('<'), Rune('>')}},
+		// Control characters typed into the text
+		{"a\r\nb\t\x1b", []Key{Rune('a'), Enter, Enter, Rune('b'), Tab, Escape}},
+		// A paste with no end runs to the end of the text
+		{"<Paste>a<lt>b", []Key{Paste("a<b")}},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
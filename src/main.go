@@ -1,57 +1,87 @@
 package main
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
-	"syscall"
-
-	"golang.org/x/term"
+	"runtime/debug"
 
 	"github.com/deanrtaylor1/go-editor/config"
-	"github.com/deanrtaylor1/go-editor/constants"
 	"github.com/deanrtaylor1/go-editor/core"
 	"github.com/deanrtaylor1/go-editor/mappings"
 )
 
-func enableRawMode(e *config.Editor) error {
-	oldState, err := term.MakeRaw(int(syscall.Stdin))
+func initEditor(e *config.Editor) error {
+	err := config.GetWindowSize(e)
 	if err != nil {
 		return err
 	}
-	e.TerminalState = oldState
+	e.ScreenRows -= 2
 	return nil
 }
 
-func initEditor(e *config.Editor) {
-	err := config.GetWindowSize(e)
-	if err != nil {
-		log.Fatal(err)
-	}
-	e.ScreenRows -= 2
+func main() {
+	os.Exit(run())
 }
 
-func main() {
+// run is the editor from start to finish. Whichever way it ends, a normal
+// quit, an error or a panic, the deferred shutdown restores the terminal
+// before anything is printed.
+func run() (exitCode int) {
 	e := config.NewEditor()
 	motions := mappings.InitializeMotionMap(e)
 	e.MotionMap = motions
 
-	err := enableRawMode(e)
+	// Settings decide how the terminal is set up, so they come first
+	settingsErr := e.Settings.Load(config.SettingsPath())
+
+	err := core.SetupTerminal(e)
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		return 1
 	}
-	defer term.Restore(int(syscall.Stdin), e.TerminalState)
 
-	initEditor(e)
+	var runErr error
+	defer func() {
+		crash := recover()
+		if crash == nil {
+			core.RestoreTerminal(e)
+			if runErr != nil {
+				fmt.Fprintln(os.Stderr, runErr)
+				exitCode = 1
+			}
+			return
+		}
 
-	if len(os.Args) >= 2 {
-		core.ReadHandler(e, os.Args[1])
-	}
+		report, reportErr := core.WriteCrashReport(e, crash, debug.Stack())
+		core.RestoreTerminal(e)
+		fmt.Fprintf(os.Stderr, "go-editor crashed: %v\n", crash)
+		if reportErr != nil {
+			fmt.Fprintf(os.Stderr, "failed to write crash report: %s\n", reportErr.Error())
+		} else {
+			fmt.Fprintf(os.Stderr, "crash report written to %s\n", report)
+		}
+		exitCode = 2
+	}()
 
-	char := constants.INITIAL_REFRESH
+	if runErr = initEditor(e); runErr != nil {
+		return
+	}
 
-	for {
-		core.EditorRefreshScreen(e, char)
-		char = core.EventHandlerMain(e.Reader, e)
+	// Input has to be running before the file is read, opening it can ask
+	// about a swap file
+	core.StartInput(e)
 
+	if len(os.Args) >= 2 {
+		if runErr = core.ReadHandler(e, os.Args[1]); runErr != nil {
+			return
+		}
 	}
+	if settingsErr != nil && !errors.Is(settingsErr, fs.ErrNotExist) {
+		core.EditorSetStatusMessage(e, "failed to load settings: %s", settingsErr.Error())
+	}
+
+	runErr = core.RunEventLoop(e)
+	return
 }
@@ -8,58 +8,62 @@ import (
 )
 
 func InitializeMotionMap(e *config.Editor) map[string]func() {
-	return map[string]func(){
-		" pv": func() {
-			GoToFileBrowser(e)
-		},
-		"yy": func() {
-			yankLine(e)
-		},
-		" pf": func() {
-			OpenFuzzyModal(e)
-		},
-		" ps": func() {
-			OpenGrepModal(e)
-		},
+	motions := core.Sequences(e)
+	motions[" pv"] = func() {
+		GoToFileBrowser(e)
 	}
+	motions[" pf"] = func() {
+		OpenFuzzyModal(e)
+	}
+	motions[" ps"] = func() {
+		OpenGrepModal(e)
+	}
+	return motions
 }
 
 func GoToFileBrowser(e *config.Editor) {
 	e.EditorMode = constants.EDITOR_MODE_FILE_BROWSER
 	e.CacheCursorCoords()
 	e.ResetCursorCoords()
-	core.ReadHandler(e, e.RootDirectory)
-}
-
-func yankLine(e *config.Editor) {
-	e.EditorMode = constants.EDITOR_MODE_VISUAL
-	e.HighlightLine()
-	e.YankSelection()
-	e.Yank.Type = config.LineWise
-	e.ClearSelection()
-	e.EditorMode = constants.EDITOR_MODE_NORMAL
+	if err := core.ReadHandler(e, e.RootDirectory); err != nil {
+		core.EditorSetStatusMessage(e, "%s", err.Error())
+	}
 }
 
+// OpenFuzzyModal opens the file finder straight away and fills it once the
+// files under the root directory have been listed in the background.
 func OpenFuzzyModal(e *config.Editor) {
-	files, err := config.ListFiles(e.RootDirectory)
-	if err != nil {
-		config.LogToFile(err.Error())
-		return
-	}
 	e.ModalOpen = !e.ModalOpen
 
 	e.Modal = config.InitModal(config.MODAL_TYPE_FUZZY)
 
-	data := make(fuzzy.Matches, len(files))
-	for i, str := range files {
-		data[i] = fuzzy.Match{
-			Str:            str,
-			MatchedIndexes: []int{}, // Empty because no characters are matched
-		}
-	}
+	root := e.RootDirectory
+	go func() {
+		files, err := config.ListFiles(root)
+		e.Post(func(e *config.Editor) {
+			if err != nil {
+				core.EditorSetStatusMessage(e, "failed to list files: %s", err.Error())
+				return
+			}
+			if !e.ModalOpen || e.Modal.Type != config.MODAL_TYPE_FUZZY || e.RootDirectory != root {
+				return
+			}
+
+			data := make(fuzzy.Matches, len(files))
+			for i, str := range files {
+				data[i] = fuzzy.Match{
+					Str:            str,
+					MatchedIndexes: []int{}, // Empty because no characters are matched
+				}
+			}
 
-	e.Modal.Data = data
-	e.Modal.Results = data
+			e.Modal.Data = data
+			e.Modal.Results = data
+			if len(e.Modal.ModalInput) > 0 {
+				e.Modal.Results = fuzzy.FindFrom(string(e.Modal.ModalInput), &e.Modal)
+			}
+		})
+	}()
 }
 
 func OpenGrepModal(e *config.Editor) {
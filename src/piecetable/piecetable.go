@@ -0,0 +1,363 @@
+package piecetable
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/deanrtaylor1/go-editor/utils"
+)
+
+type source int
+
+const (
+	sourceOriginal source = iota
+	sourceAdd
+)
+
+// piece is a span of one of the two backing buffers. newlines holds the
+// absolute positions of the '\n' bytes inside the span, so splitting a piece
+// only needs to reslice it.
+type piece struct {
+	source   source
+	start    int
+	length   int
+	newlines []int
+}
+
+// node holds one piece in a treap ordered by document position, with the
+// byte and newline counts of its whole subtree so offsets and lines can be
+// found without walking every piece.
+type node struct {
+	piece    piece
+	priority uint32
+	left     *node
+	right    *node
+	length   int
+	newlines int
+}
+
+func newNode(p piece) *node {
+	n := &node{piece: p, priority: rand.Uint32()}
+	n.update()
+	return n
+}
+
+func (n *node) update() {
+	n.length = n.piece.length + n.left.size() + n.right.size()
+	n.newlines = len(n.piece.newlines) + n.left.lines() + n.right.lines()
+}
+
+func (n *node) size() int {
+	if n == nil {
+		return 0
+	}
+	return n.length
+}
+
+func (n *node) lines() int {
+	if n == nil {
+		return 0
+	}
+	return n.newlines
+}
+
+// splitNodes splits the tree into the pieces before offset and those from it
+// on, cutting the piece offset falls inside in two.
+func splitNodes(n *node, offset int) (*node, *node) {
+	if n == nil {
+		return nil, nil
+	}
+	leftLen := n.left.size()
+	switch {
+	case offset <= leftLen:
+		l, r := splitNodes(n.left, offset)
+		n.left = r
+		n.update()
+		return l, n
+	case offset >= leftLen+n.piece.length:
+		l, r := splitNodes(n.right, offset-leftLen-n.piece.length)
+		n.right = l
+		n.update()
+		return n, r
+	}
+	before, after := split(n.piece, offset-leftLen)
+	right := n.right
+	n.piece, n.right = before, nil
+	n.update()
+	return n, mergeNodes(newNode(after), right)
+}
+
+// mergeNodes joins two trees where every piece of a comes before those of b.
+func mergeNodes(a, b *node) *node {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if a.priority > b.priority {
+		a.right = mergeNodes(a.right, b)
+		a.update()
+		return a
+	}
+	b.left = mergeNodes(a, b.left)
+	b.update()
+	return b
+}
+
+func lastNode(n *node) *node {
+	for n != nil && n.right != nil {
+		n = n.right
+	}
+	return n
+}
+
+// growLast lengthens the last piece of the tree by the text appended to the
+// add buffer right after it.
+func growLast(n *node, grown piece) {
+	if n.right != nil {
+		growLast(n.right, grown)
+	} else {
+		p := n.piece
+		n.piece.length += grown.length
+		n.piece.newlines = append(p.newlines[:len(p.newlines):len(p.newlines)], grown.newlines...)
+	}
+	n.update()
+}
+
+// Table is a piece table. The original file contents are never modified,
+// inserted text is appended to the add buffer and the document is described
+// by a tree of pieces pointing into either buffer.
+type Table struct {
+	original []byte
+	add      []byte
+	root     *node
+}
+
+func New(content []byte) *Table {
+	t := &Table{original: content}
+	if len(content) > 0 {
+		t.root = newNode(piece{
+			source:   sourceOriginal,
+			start:    0,
+			length:   len(content),
+			newlines: indexNewlines(content, 0),
+		})
+	}
+	return t
+}
+
+func indexNewlines(b []byte, base int) []int {
+	var positions []int
+	for i, c := range b {
+		if c == '\n' {
+			positions = append(positions, base+i)
+		}
+	}
+	return positions
+}
+
+func (t *Table) buffer(s source) []byte {
+	if s == sourceAdd {
+		return t.add
+	}
+	return t.original
+}
+
+func (t *Table) pieceBytes(p piece) []byte {
+	return t.buffer(p.source)[p.start : p.start+p.length]
+}
+
+// split returns the parts of p before and after the relative offset at.
+func split(p piece, at int) (piece, piece) {
+	cut := sort.SearchInts(p.newlines, p.start+at)
+	left := piece{source: p.source, start: p.start, length: at, newlines: p.newlines[:cut:cut]}
+	right := piece{source: p.source, start: p.start + at, length: p.length - at, newlines: p.newlines[cut:]}
+	return left, right
+}
+
+// Len returns the number of bytes in the document.
+func (t *Table) Len() int {
+	return t.root.size()
+}
+
+// LineCount returns the number of lines, where a trailing newline does not
+// start a new line.
+func (t *Table) LineCount() int {
+	length := t.Len()
+	if length == 0 {
+		return 0
+	}
+	if t.ByteAt(length-1) == '\n' {
+		return t.root.lines()
+	}
+	return t.root.lines() + 1
+}
+
+func (t *Table) ByteAt(offset int) byte {
+	n := t.root
+	for n != nil {
+		leftLen := n.left.size()
+		switch {
+		case offset < leftLen:
+			n = n.left
+		case offset < leftLen+n.piece.length:
+			return t.buffer(n.piece.source)[n.piece.start+offset-leftLen]
+		default:
+			offset -= leftLen + n.piece.length
+			n = n.right
+		}
+	}
+	return 0
+}
+
+func (t *Table) Insert(offset int, text []byte) {
+	if len(text) == 0 {
+		return
+	}
+	offset = utils.Max(0, utils.Min(offset, t.Len()))
+
+	addStart := len(t.add)
+	t.add = append(t.add, text...)
+	newPiece := piece{
+		source:   sourceAdd,
+		start:    addStart,
+		length:   len(text),
+		newlines: indexNewlines(text, addStart),
+	}
+
+	left, right := splitNodes(t.root, offset)
+	if last := lastNode(left); last != nil && last.piece.source == sourceAdd && last.piece.start+last.piece.length == addStart {
+		// Typing usually continues the previous insert, so grow that piece
+		// rather than adding a new one for every keystroke.
+		growLast(left, newPiece)
+		t.root = mergeNodes(left, right)
+		return
+	}
+	t.root = mergeNodes(mergeNodes(left, newNode(newPiece)), right)
+}
+
+func (t *Table) Delete(offset, length int) {
+	if offset < 0 {
+		length += offset
+		offset = 0
+	}
+	if offset+length > t.Len() {
+		length = t.Len() - offset
+	}
+	if length <= 0 {
+		return
+	}
+	left, rest := splitNodes(t.root, offset)
+	_, right := splitNodes(rest, length)
+	t.root = mergeNodes(left, right)
+}
+
+// Bytes returns a copy of the whole document.
+func (t *Table) Bytes() []byte {
+	return t.Slice(0, t.Len())
+}
+
+// Slice returns a copy of the bytes in [start, end).
+func (t *Table) Slice(start, end int) []byte {
+	start = utils.Max(start, 0)
+	end = utils.Min(end, t.Len())
+	if start >= end {
+		return []byte{}
+	}
+	return t.appendRange(make([]byte, 0, end-start), t.root, 0, start, end)
+}
+
+// appendRange appends the bytes of the subtree n, which begins at document
+// offset base, that fall in [start, end).
+func (t *Table) appendRange(out []byte, n *node, base, start, end int) []byte {
+	if n == nil || base >= end || base+n.length <= start {
+		return out
+	}
+	out = t.appendRange(out, n.left, base, start, end)
+	pStart := base + n.left.size()
+	pEnd := pStart + n.piece.length
+	if pStart < end && pEnd > start {
+		b := t.pieceBytes(n.piece)
+		out = append(out, b[utils.Max(start, pStart)-pStart:utils.Min(end, pEnd)-pStart]...)
+	}
+	return t.appendRange(out, n.right, pEnd, start, end)
+}
+
+// LineStart returns the byte offset at which line n begins. Asking for the
+// line after the last one returns Len().
+func (t *Table) LineStart(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	if n > t.root.lines() {
+		return t.Len()
+	}
+	pos := 0
+	for nd := t.root; nd != nil; {
+		leftLines := nd.left.lines()
+		switch {
+		case n <= leftLines:
+			nd = nd.left
+		case n <= leftLines+len(nd.piece.newlines):
+			p := nd.piece
+			return pos + nd.left.size() + p.newlines[n-leftLines-1] - p.start + 1
+		default:
+			n -= leftLines + len(nd.piece.newlines)
+			pos += nd.left.size() + nd.piece.length
+			nd = nd.right
+		}
+	}
+	return t.Len()
+}
+
+// Line returns a copy of line n without its trailing newline.
+func (t *Table) Line(n int) []byte {
+	if n < 0 || n >= t.LineCount() {
+		return []byte{}
+	}
+	start := t.LineStart(n)
+	end := t.LineStart(n + 1)
+	if n < t.root.lines() {
+		end--
+	}
+	return t.Slice(start, end)
+}
+
+// PointToOffset converts a line and byte column into a document offset,
+// clamping the column to the length of the line.
+func (t *Table) PointToOffset(line, col int) int {
+	if line >= t.LineCount() {
+		return t.Len()
+	}
+	start := t.LineStart(line)
+	end := t.LineStart(line + 1)
+	if line < t.root.lines() {
+		end--
+	}
+	if col < 0 {
+		col = 0
+	}
+	return utils.Min(start+col, end)
+}
+
+// OffsetToPoint converts a document offset into a line and byte column.
+func (t *Table) OffsetToPoint(offset int) (int, int) {
+	offset = utils.Min(offset, t.Len())
+	line, pos := 0, offset
+	for n := t.root; n != nil; {
+		leftLen := n.left.size()
+		switch {
+		case pos < leftLen:
+			n = n.left
+		case pos < leftLen+n.piece.length:
+			line += n.left.lines() + sort.SearchInts(n.piece.newlines, n.piece.start+pos-leftLen)
+			n = nil
+		default:
+			line += n.left.lines() + len(n.piece.newlines)
+			pos -= leftLen + n.piece.length
+			n = n.right
+		}
+	}
+	return line, offset - t.LineStart(line)
+}
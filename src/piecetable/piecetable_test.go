@@ -0,0 +1,120 @@
+package piecetable
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// referencePoint works out what OffsetToPoint should return from a plain
+// byte slice.
+func referencePoint(doc []byte, offset int) (int, int) {
+	line := bytes.Count(doc[:offset], []byte{'\n'})
+	return line, offset - (bytes.LastIndexByte(doc[:offset], '\n') + 1)
+}
+
+func referenceLineCount(doc []byte) int {
+	n := bytes.Count(doc, []byte{'\n'})
+	if len(doc) > 0 && doc[len(doc)-1] != '\n' {
+		n++
+	}
+	return n
+}
+
+func TestInsertDeleteAgainstBytes(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []byte("ab\nc\n")
+	randomText := func(n int) []byte {
+		text := make([]byte, n)
+		for i := range text {
+			text[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return text
+	}
+
+	for round := 0; round < 20; round++ {
+		doc := randomText(rng.Intn(50))
+		table := New(append([]byte{}, doc...))
+		for step := 0; step < 300; step++ {
+			offset := rng.Intn(len(doc) + 1)
+			switch rng.Intn(3) {
+			case 0:
+				text := randomText(1 + rng.Intn(8))
+				table.Insert(offset, text)
+				doc = append(doc[:offset], append(text, doc[offset:]...)...)
+			case 1:
+				length := rng.Intn(10)
+				table.Delete(offset, length)
+				end := offset + length
+				if end > len(doc) {
+					end = len(doc)
+				}
+				doc = append(doc[:offset], doc[end:]...)
+			case 2:
+				// Typing keeps inserting where the last insert ended
+				if len(doc) > 0 {
+					table.Insert(len(doc), []byte("x"))
+					table.Insert(len(doc)+1, []byte("y"))
+					doc = append(doc, 'x', 'y')
+				}
+			}
+
+			if got := table.Bytes(); !bytes.Equal(got, doc) {
+				t.Fatalf("round %d step %d: Bytes() = %q, want %q", round, step, got, doc)
+			}
+			if table.Len() != len(doc) {
+				t.Fatalf("round %d step %d: Len() = %d, want %d", round, step, table.Len(), len(doc))
+			}
+			if got, want := table.LineCount(), referenceLineCount(doc); got != want {
+				t.Fatalf("round %d step %d: LineCount() = %d, want %d", round, step, got, want)
+			}
+
+			start := rng.Intn(len(doc) + 1)
+			end := start + rng.Intn(len(doc)-start+1)
+			if got := table.Slice(start, end); !bytes.Equal(got, doc[start:end]) {
+				t.Fatalf("round %d step %d: Slice(%d, %d) = %q, want %q", round, step, start, end, got, doc[start:end])
+			}
+			if len(doc) > 0 {
+				at := rng.Intn(len(doc))
+				if got := table.ByteAt(at); got != doc[at] {
+					t.Fatalf("round %d step %d: ByteAt(%d) = %q, want %q", round, step, at, got, doc[at])
+				}
+			}
+			line, col := table.OffsetToPoint(start)
+			wantLine, wantCol := referencePoint(doc, start)
+			if line != wantLine || col != wantCol {
+				t.Fatalf("round %d step %d: OffsetToPoint(%d) = %d,%d, want %d,%d", round, step, start, line, col, wantLine, wantCol)
+			}
+			if got := table.PointToOffset(line, col); got != start {
+				t.Fatalf("round %d step %d: PointToOffset(%d, %d) = %d, want %d", round, step, line, col, got, start)
+			}
+			lines := bytes.Split(doc, []byte{'\n'})
+			for n := 0; n < referenceLineCount(doc); n++ {
+				if got := table.Line(n); !bytes.Equal(got, lines[n]) {
+					t.Fatalf("round %d step %d: Line(%d) = %q, want %q", round, step, n, got, lines[n])
+				}
+			}
+		}
+	}
+}
+
+func TestLineStart(t *testing.T) {
+	table := New([]byte("one\ntwo\n"))
+	table.Insert(4, []byte("new\n"))
+	tests := []struct {
+		line int
+		want int
+	}{
+		{-1, 0},
+		{0, 0},
+		{1, 4},
+		{2, 8},
+		{3, 12},
+		{4, 12},
+	}
+	for _, tt := range tests {
+		if got := table.LineStart(tt.line); got != tt.want {
+			t.Errorf("LineStart(%d) = %d, want %d", tt.line, got, tt.want)
+		}
+	}
+}
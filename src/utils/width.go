@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+type runeRange struct {
+	lo, hi rune
+}
+
+// East Asian Wide and Fullwidth ranges, plus the emoji that terminals draw
+// two cells wide.
+var wideRanges = []runeRange{
+	{0x1100, 0x115F}, {0x231A, 0x231B}, {0x2329, 0x232A}, {0x23E9, 0x23EC},
+	{0x23F0, 0x23F0}, {0x23F3, 0x23F3}, {0x25FD, 0x25FE}, {0x2614, 0x2615},
+	{0x2648, 0x2653}, {0x267F, 0x267F}, {0x2693, 0x2693}, {0x26A1, 0x26A1},
+	{0x26AA, 0x26AB}, {0x26BD, 0x26BE}, {0x26C4, 0x26C5}, {0x26CE, 0x26CE},
+	{0x26D4, 0x26D4}, {0x26EA, 0x26EA}, {0x26F2, 0x26F3}, {0x26F5, 0x26F5},
+	{0x26FA, 0x26FA}, {0x26FD, 0x26FD}, {0x2705, 0x2705}, {0x270A, 0x270B},
+	{0x2728, 0x2728}, {0x274C, 0x274C}, {0x274E, 0x274E}, {0x2753, 0x2755},
+	{0x2757, 0x2757}, {0x2795, 0x2797}, {0x27B0, 0x27B0}, {0x27BF, 0x27BF},
+	{0x2B1B, 0x2B1C}, {0x2B50, 0x2B50}, {0x2B55, 0x2B55}, {0x2E80, 0x303E},
+	{0x3041, 0x33FF}, {0x3400, 0x4DBF}, {0x4E00, 0x9FFF}, {0xA000, 0xA4CF},
+	{0xA960, 0xA97F}, {0xAC00, 0xD7A3}, {0xF900, 0xFAFF}, {0xFE10, 0xFE19},
+	{0xFE30, 0xFE6F}, {0xFF00, 0xFF60}, {0xFFE0, 0xFFE6}, {0x16FE0, 0x16FE4},
+	{0x17000, 0x18CFF}, {0x1B000, 0x1B2FF}, {0x1F004, 0x1F004}, {0x1F0CF, 0x1F0CF},
+	{0x1F18E, 0x1F18E}, {0x1F191, 0x1F19A}, {0x1F200, 0x1F202}, {0x1F210, 0x1F23B},
+	{0x1F240, 0x1F248}, {0x1F250, 0x1F251}, {0x1F260, 0x1F265}, {0x1F300, 0x1F320},
+	{0x1F32D, 0x1F335}, {0x1F337, 0x1F37C}, {0x1F37E, 0x1F393}, {0x1F3A0, 0x1F3CA},
+	{0x1F3CF, 0x1F3D3}, {0x1F3E0, 0x1F3F0}, {0x1F3F4, 0x1F3F4}, {0x1F3F8, 0x1F43E},
+	{0x1F440, 0x1F440}, {0x1F442, 0x1F4FC}, {0x1F4FF, 0x1F53D}, {0x1F54B, 0x1F54E},
+	{0x1F550, 0x1F567}, {0x1F57A, 0x1F57A}, {0x1F595, 0x1F596}, {0x1F5A4, 0x1F5A4},
+	{0x1F5FB, 0x1F64F}, {0x1F680, 0x1F6C5}, {0x1F6CC, 0x1F6CC}, {0x1F6D0, 0x1F6D2},
+	{0x1F6D5, 0x1F6D7}, {0x1F6EB, 0x1F6EC}, {0x1F6F4, 0x1F6FC}, {0x1F7E0, 0x1F7EB},
+	{0x1F90C, 0x1F93A}, {0x1F93C, 0x1F945}, {0x1F947, 0x1F9FF}, {0x1FA70, 0x1FAFF},
+	{0x20000, 0x2FFFD}, {0x30000, 0x3FFFD},
+}
+
+const zeroWidthJoiner = 0x200D
+
+func isWide(r rune) bool {
+	lo, hi := 0, len(wideRanges)-1
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		switch {
+		case r < wideRanges[mid].lo:
+			hi = mid - 1
+		case r > wideRanges[mid].hi:
+			lo = mid + 1
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// isGraphemeExtend reports whether r attaches to the character before it
+// instead of starting a new one.
+func isGraphemeExtend(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		r == zeroWidthJoiner ||
+		(r >= 0xFE00 && r <= 0xFE0F) ||
+		(r >= 0x1F3FB && r <= 0x1F3FF) ||
+		(r >= 0xE0020 && r <= 0xE007F)
+}
+
+// RuneWidth returns the number of terminal cells r occupies on its own.
+// Control characters count as one cell because they are drawn as ^X.
+func RuneWidth(r rune) int {
+	switch {
+	case r == utf8.RuneError:
+		return 1
+	case isGraphemeExtend(r), unicode.Is(unicode.Cf, r):
+		return 0
+	case isWide(r), isRegionalIndicator(r):
+		return 2
+	}
+	return 1
+}
+
+// NextGrapheme returns the index of the first byte after the user perceived
+// character that starts at i.
+func NextGrapheme(b []byte, i int) int {
+	if i >= len(b) {
+		return len(b)
+	}
+	r, size := utf8.DecodeRune(b[i:])
+	j := i + size
+	if r == '\r' && j < len(b) && b[j] == '\n' {
+		return j + 1
+	}
+	if isRegionalIndicator(r) && j < len(b) {
+		if next, nextSize := utf8.DecodeRune(b[j:]); isRegionalIndicator(next) {
+			j += nextSize
+		}
+	}
+	for j < len(b) {
+		next, nextSize := utf8.DecodeRune(b[j:])
+		if !isGraphemeExtend(next) {
+			break
+		}
+		j += nextSize
+		if next == zeroWidthJoiner && j < len(b) {
+			_, joinedSize := utf8.DecodeRune(b[j:])
+			j += joinedSize
+		}
+	}
+	return j
+}
+
+// PrevGrapheme returns the index of the first byte of the character that
+// ends right before i.
+func PrevGrapheme(b []byte, i int) int {
+	if i > len(b) {
+		i = len(b)
+	}
+	// Step back to a character nothing before it can take in, then walk
+	// forwards from there the way NextGrapheme splits them
+	start := i
+	for start > 0 {
+		r, size := utf8.DecodeLastRune(b[:start])
+		start -= size
+		if startsGrapheme(b, start, r) {
+			break
+		}
+	}
+	prev := start
+	for j := start; j < i; {
+		prev = j
+		j = NextGrapheme(b, j)
+	}
+	return prev
+}
+
+// startsGrapheme reports whether r at index i always begins a character,
+// whatever comes before it.
+func startsGrapheme(b []byte, i int, r rune) bool {
+	if isGraphemeExtend(r) || isRegionalIndicator(r) {
+		return false
+	}
+	if r == '\n' && i > 0 && b[i-1] == '\r' {
+		return false
+	}
+	before, _ := utf8.DecodeLastRune(b[:i])
+	return i == 0 || before != zeroWidthJoiner
+}
+
+// GraphemeWidth returns the number of cells a single grapheme occupies.
+func GraphemeWidth(g []byte) int {
+	width := 0
+	for len(g) > 0 {
+		r, size := utf8.DecodeRune(g)
+		width = Max(width, RuneWidth(r))
+		g = g[size:]
+	}
+	return width
+}
+
+// CellWidth returns the number of cells g occupies when drawn at screen
+// column col. Tabs stretch to the next multiple of tabStop.
+func CellWidth(g []byte, col int, tabStop int) int {
+	if len(g) > 0 && g[0] == '\t' {
+		return tabStop - col%tabStop
+	}
+	return GraphemeWidth(g)
+}
+
+// StringWidth returns the number of cells s occupies when drawn.
+func StringWidth(s string) int {
+	b := []byte(s)
+	width := 0
+	for i := 0; i < len(b); {
+		next := NextGrapheme(b, i)
+		width += GraphemeWidth(b[i:next])
+		i = next
+	}
+	return width
+}
+
+// TruncateToWidth cuts s down to at most width cells without splitting a
+// character.
+func TruncateToWidth(s string, width int) string {
+	b := []byte(s)
+	used := 0
+	for i := 0; i < len(b); {
+		next := NextGrapheme(b, i)
+		w := GraphemeWidth(b[i:next])
+		if used+w > width {
+			return s[:i]
+		}
+		used += w
+		i = next
+	}
+	return s
+}
@@ -0,0 +1,63 @@
+package utils
+
+import "testing"
+
+// graphemeStarts splits b the way NextGrapheme walks it from the start.
+func graphemeStarts(b []byte) []int {
+	var starts []int
+	for i := 0; i < len(b); i = NextGrapheme(b, i) {
+		starts = append(starts, i)
+	}
+	return starts
+}
+
+func TestPrevGrapheme(t *testing.T) {
+	tests := []string{
+		"plain ascii",
+		"a\r\nb\n\r\n",
+		"héllo wörld",
+		"é́x",
+		"日本語のテキスト",
+		"👍🏽 ok",
+		"👨‍👩‍👧 family",
+		"‍x",
+		"🇬🇧🇫🇷🇩",
+		"a🇬🇧🇫🇷b",
+		"́start",
+		"x\xffy\xfe",
+	}
+	for _, tt := range tests {
+		b := []byte(tt)
+		starts := graphemeStarts(b)
+		for k, end := range append(starts[1:], len(b)) {
+			if got := PrevGrapheme(b, end); got != starts[k] {
+				t.Errorf("PrevGrapheme(%q, %d) = %d, want %d", tt, end, got, starts[k])
+			}
+		}
+		if got := PrevGrapheme(b, len(b)+5); got != starts[len(starts)-1] {
+			t.Errorf("PrevGrapheme(%q, past the end) = %d, want %d", tt, got, starts[len(starts)-1])
+		}
+	}
+	if got := PrevGrapheme(nil, 0); got != 0 {
+		t.Errorf("PrevGrapheme(nil, 0) = %d, want 0", got)
+	}
+}
+
+func TestStringWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"日本", 4},
+		{"é", 1},
+		{"👍🏽", 2},
+		{"🇬🇧", 2},
+	}
+	for _, tt := range tests {
+		if got := StringWidth(tt.s); got != tt.want {
+			t.Errorf("StringWidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}